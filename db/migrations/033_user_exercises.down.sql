@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS uq_exercises_owner_name;
+DROP INDEX IF EXISTS idx_exercises_owner;
+
+-- exercícios privados não existem no modelo anterior
+DELETE FROM public.exercises WHERE owner_user_id IS NOT NULL;
+
+ALTER TABLE public.exercises
+  DROP COLUMN IF EXISTS promoted_at,
+  DROP COLUMN IF EXISTS created_at,
+  DROP COLUMN IF EXISTS owner_user_id;
@@ -0,0 +1,21 @@
+-- 033: exercícios personalizados por usuário
+-- owner_user_id NULL = catálogo global; preenchido = exercício privado do dono
+ALTER TABLE public.exercises
+  ADD COLUMN IF NOT EXISTS owner_user_id TEXT,
+  ADD COLUMN IF NOT EXISTS created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN IF NOT EXISTS promoted_at   TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_exercises_owner
+  ON public.exercises (owner_user_id)
+  WHERE owner_user_id IS NOT NULL;
+
+-- evita nomes duplicados no catálogo privado de um mesmo usuário
+CREATE UNIQUE INDEX IF NOT EXISTS uq_exercises_owner_name
+  ON public.exercises (owner_user_id, lower(name))
+  WHERE owner_user_id IS NOT NULL;
+
+-- seeds 007/008 inseriram IDs explícitos; alinha a sequência antes de aceitar inserts da API
+SELECT setval(
+  pg_get_serial_sequence('public.exercises', 'id'),
+  GREATEST(COALESCE((SELECT MAX(id) FROM public.exercises), 1), 1)
+);
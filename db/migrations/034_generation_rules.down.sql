@@ -0,0 +1,2 @@
+ALTER TABLE public.treinos DROP COLUMN IF EXISTS rule_version;
+DROP TABLE IF EXISTS public.generation_rule_sets;
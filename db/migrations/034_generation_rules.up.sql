@@ -0,0 +1,13 @@
+-- 034: regras do gerador versionadas (splits, reps/descanso/séries por objetivo e nível)
+-- sem linhas => gerador usa o conjunto embutido (versão 0)
+CREATE TABLE IF NOT EXISTS public.generation_rule_sets (
+  version      INT PRIMARY KEY,
+  rules        JSONB NOT NULL,
+  notes        TEXT,
+  published_by TEXT,
+  published_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- versão das regras usada ao gerar o treino (0 = embutida; NULL = treino manual/legado)
+ALTER TABLE public.treinos
+  ADD COLUMN IF NOT EXISTS rule_version INT;
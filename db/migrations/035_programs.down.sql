@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.program_workouts;
+DROP TABLE IF EXISTS public.programs;
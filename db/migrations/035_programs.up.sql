@@ -0,0 +1,43 @@
+-- 035: programas periodizados (mesociclos de 4–16 semanas)
+CREATE TABLE IF NOT EXISTS public.programs (
+  id            BIGSERIAL PRIMARY KEY,
+  user_id       TEXT NOT NULL,
+  name          TEXT,
+  model         TEXT NOT NULL CHECK (model IN ('linear','dup','block')),
+  objetivo      TEXT NOT NULL,
+  nivel         TEXT NOT NULL,
+  divisao       TEXT NOT NULL,
+  weeks         INT  NOT NULL CHECK (weeks BETWEEN 4 AND 16),
+  days_per_week INT  NOT NULL CHECK (days_per_week BETWEEN 1 AND 7),
+  deload_every  INT  NOT NULL DEFAULT 4,
+  start_date    DATE NOT NULL,
+  rule_version  INT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_programs_user
+  ON public.programs (user_id, created_at DESC);
+
+-- um treino datado por (semana, dia); intensidade em % do e1RM
+CREATE TABLE IF NOT EXISTS public.program_workouts (
+  id             BIGSERIAL PRIMARY KEY,
+  program_id     BIGINT NOT NULL REFERENCES public.programs(id) ON DELETE CASCADE,
+  week           INT  NOT NULL,
+  day            INT  NOT NULL,
+  scheduled_date DATE NOT NULL,
+  divisao        TEXT NOT NULL,
+  treino_id      INT REFERENCES public.treinos(id) ON DELETE SET NULL,
+  phase          TEXT NOT NULL,
+  deload         BOOLEAN NOT NULL DEFAULT FALSE,
+  intensity_pct  NUMERIC(5,2) NOT NULL,
+  volume_factor  NUMERIC(4,2) NOT NULL,
+  rep_target     TEXT NOT NULL,
+  sets_target    INT  NOT NULL,
+  UNIQUE (program_id, week, day)
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_workouts_date
+  ON public.program_workouts (scheduled_date);
+
+CREATE INDEX IF NOT EXISTS idx_program_workouts_treino
+  ON public.program_workouts (treino_id);
@@ -0,0 +1,18 @@
+-- 036: períodos de deload (manual ou recomendado pela detecção de fadiga)
+CREATE TABLE IF NOT EXISTS public.deload_periods (
+  id            BIGSERIAL PRIMARY KEY,
+  user_id       TEXT NOT NULL,
+  starts_on     DATE NOT NULL,
+  ends_on       DATE NOT NULL,
+  reason        TEXT,
+  source        TEXT NOT NULL DEFAULT 'manual' CHECK (source IN ('manual','recommended')),
+  load_factor   NUMERIC(4,2) NOT NULL DEFAULT 0.85 CHECK (load_factor BETWEEN 0.5 AND 0.95),
+  volume_factor NUMERIC(4,2) NOT NULL DEFAULT 0.5,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  cancelled_at  TIMESTAMPTZ,
+  CHECK (ends_on >= starts_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deload_periods_user_active
+  ON public.deload_periods (user_id, starts_on DESC)
+  WHERE cancelled_at IS NULL;
@@ -0,0 +1,14 @@
+-- 037: check-in diário de prontidão (1 por usuário/dia)
+CREATE TABLE IF NOT EXISTS public.readiness_checkins (
+  user_id     TEXT NOT NULL,
+  day         DATE NOT NULL,
+  sleep_hours NUMERIC(3,1) NOT NULL CHECK (sleep_hours BETWEEN 0 AND 16),
+  soreness    SMALLINT NOT NULL CHECK (soreness BETWEEN 1 AND 5),
+  stress      SMALLINT NOT NULL CHECK (stress BETWEEN 1 AND 5),
+  energy      SMALLINT NOT NULL CHECK (energy BETWEEN 1 AND 5),
+  resting_hr  SMALLINT CHECK (resting_hr BETWEEN 25 AND 150),
+  notes       TEXT,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, day)
+);
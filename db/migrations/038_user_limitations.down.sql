@@ -0,0 +1,2 @@
+ALTER TABLE public.workout_sets DROP COLUMN IF EXISTS pain;
+DROP TABLE IF EXISTS public.user_limitations;
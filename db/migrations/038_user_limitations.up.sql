@@ -0,0 +1,21 @@
+-- 038: lesões/limitações do usuário + dor por série
+CREATE TABLE IF NOT EXISTS public.user_limitations (
+  id             BIGSERIAL PRIMARY KEY,
+  user_id        TEXT NOT NULL,
+  body_region    TEXT NOT NULL,
+  avoid_patterns TEXT[] NOT NULL DEFAULT '{}',
+  severity       TEXT NOT NULL DEFAULT 'moderate' CHECK (severity IN ('mild','moderate','severe')),
+  starts_on      DATE NOT NULL DEFAULT CURRENT_DATE,
+  ends_on        DATE,
+  notes          TEXT,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK (ends_on IS NULL OR ends_on >= starts_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_limitations_user
+  ON public.user_limitations (user_id, starts_on DESC);
+
+-- dor (0–10) registrada na série
+ALTER TABLE public.workout_sets
+  ADD COLUMN IF NOT EXISTS pain SMALLINT CHECK (pain BETWEEN 0 AND 10);
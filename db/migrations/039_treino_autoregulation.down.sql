@@ -0,0 +1,5 @@
+ALTER TABLE public.treino_exercicios
+  DROP COLUMN IF EXISTS backoff_sets,
+  DROP COLUMN IF EXISTS backoff_pct,
+  DROP COLUMN IF EXISTS top_set_rpe,
+  DROP COLUMN IF EXISTS target_rir;
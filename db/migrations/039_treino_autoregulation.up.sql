@@ -0,0 +1,6 @@
+-- 039: autorregulação por item do treino (RIR-alvo, top set por RPE + back-offs)
+ALTER TABLE public.treino_exercicios
+  ADD COLUMN IF NOT EXISTS target_rir   SMALLINT CHECK (target_rir BETWEEN 0 AND 5),
+  ADD COLUMN IF NOT EXISTS top_set_rpe  NUMERIC(3,1) CHECK (top_set_rpe BETWEEN 6 AND 10),
+  ADD COLUMN IF NOT EXISTS backoff_pct  NUMERIC(4,1) CHECK (backoff_pct BETWEEN 1 AND 30),
+  ADD COLUMN IF NOT EXISTS backoff_sets SMALLINT CHECK (backoff_sets BETWEEN 1 AND 6);
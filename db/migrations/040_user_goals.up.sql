@@ -0,0 +1,16 @@
+-- 040: metas do usuário (força por exercício ou peso corporal)
+CREATE TABLE IF NOT EXISTS public.user_goals (
+  id           BIGSERIAL PRIMARY KEY,
+  user_id      TEXT NOT NULL,
+  kind         TEXT NOT NULL CHECK (kind IN ('strength','bodyweight')),
+  exercicio_id INT REFERENCES public.exercises(id) ON DELETE CASCADE,
+  target_value NUMERIC(6,2) NOT NULL CHECK (target_value > 0),
+  start_value  NUMERIC(6,2),
+  deadline     DATE,
+  notes        TEXT,
+  achieved_at  TIMESTAMPTZ,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  CHECK ((kind = 'strength') = (exercicio_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_goals_user ON public.user_goals (user_id);
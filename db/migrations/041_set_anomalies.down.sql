@@ -0,0 +1,78 @@
+-- 041 (down): views de overload sem o filtro de flagged
+
+CREATE OR REPLACE VIEW workout_sets_recent12 AS
+WITH ranked AS (
+  SELECT
+    id,
+    session_id,
+    exercicio_id,
+    set_index,
+    reps,
+    weight_kg,
+    rir,
+    completed,
+    rest_sec,
+    created_at,
+    ROW_NUMBER() OVER (PARTITION BY exercicio_id ORDER BY created_at DESC, id DESC) AS rn
+  FROM workout_sets
+  WHERE completed = TRUE
+)
+SELECT
+  id,
+  session_id,
+  exercicio_id,
+  set_index,
+  reps,
+  weight_kg,
+  rir,
+  completed,
+  rest_sec,
+  created_at
+FROM ranked
+WHERE rn <= 12;
+
+CREATE OR REPLACE VIEW workout_sets_recent12_user AS
+WITH ranked AS (
+  SELECT
+    ws.id,
+    ws.session_id,
+    s.user_id,
+    ws.exercicio_id,
+    ws.set_index,
+    ws.reps,
+    ws.weight_kg,
+    ws.rir,
+    ws.completed,
+    ws.rest_sec,
+    ws.created_at,
+    ROW_NUMBER() OVER (
+      PARTITION BY s.user_id, ws.exercicio_id
+      ORDER BY ws.created_at DESC, ws.id DESC
+    ) AS rn
+  FROM workout_sets ws
+  JOIN workout_sessions s ON s.id = ws.session_id
+  WHERE ws.completed = TRUE
+)
+SELECT
+  id,
+  session_id,
+  user_id,
+  exercicio_id,
+  set_index,
+  reps,
+  weight_kg,
+  rir,
+  completed,
+  rest_sec,
+  created_at
+FROM ranked
+WHERE rn <= 12;
+
+REFRESH MATERIALIZED VIEW workout_overload_stats12_user_mv;
+
+DROP INDEX IF EXISTS public.idx_workout_sets_flagged;
+
+ALTER TABLE public.workout_sets
+  DROP COLUMN IF EXISTS confirmed_at,
+  DROP COLUMN IF EXISTS flag_reasons,
+  DROP COLUMN IF EXISTS flagged;
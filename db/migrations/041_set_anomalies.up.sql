@@ -0,0 +1,85 @@
+-- 041: anomalias em séries (flagged até confirmação)
+-- Séries suspeitas (ex.: 600 kg em vez de 60 kg) ficam fora das views de
+-- overload (e da MV por usuário) até o usuário confirmar.
+
+ALTER TABLE public.workout_sets
+  ADD COLUMN IF NOT EXISTS flagged      BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS flag_reasons TEXT[],
+  ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_workout_sets_flagged
+  ON public.workout_sets (session_id) WHERE flagged;
+
+-- ===== GLOBAL (por exercício) =====
+CREATE OR REPLACE VIEW workout_sets_recent12 AS
+WITH ranked AS (
+  SELECT
+    id,
+    session_id,
+    exercicio_id,
+    set_index,
+    reps,
+    weight_kg,
+    rir,
+    completed,
+    rest_sec,
+    created_at,
+    ROW_NUMBER() OVER (PARTITION BY exercicio_id ORDER BY created_at DESC, id DESC) AS rn
+  FROM workout_sets
+  WHERE completed = TRUE
+    AND NOT flagged
+)
+SELECT
+  id,
+  session_id,
+  exercicio_id,
+  set_index,
+  reps,
+  weight_kg,
+  rir,
+  completed,
+  rest_sec,
+  created_at
+FROM ranked
+WHERE rn <= 12;
+
+-- ===== POR USUÁRIO =====
+CREATE OR REPLACE VIEW workout_sets_recent12_user AS
+WITH ranked AS (
+  SELECT
+    ws.id,
+    ws.session_id,
+    s.user_id,
+    ws.exercicio_id,
+    ws.set_index,
+    ws.reps,
+    ws.weight_kg,
+    ws.rir,
+    ws.completed,
+    ws.rest_sec,
+    ws.created_at,
+    ROW_NUMBER() OVER (
+      PARTITION BY s.user_id, ws.exercicio_id
+      ORDER BY ws.created_at DESC, ws.id DESC
+    ) AS rn
+  FROM workout_sets ws
+  JOIN workout_sessions s ON s.id = ws.session_id
+  WHERE ws.completed = TRUE
+    AND NOT ws.flagged
+)
+SELECT
+  id,
+  session_id,
+  user_id,
+  exercicio_id,
+  set_index,
+  reps,
+  weight_kg,
+  rir,
+  completed,
+  rest_sec,
+  created_at
+FROM ranked
+WHERE rn <= 12;
+
+REFRESH MATERIALIZED VIEW workout_overload_stats12_user_mv;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS public.idx_user_metrics_user_weight;
+
+ALTER TABLE public.user_metrics
+  DROP COLUMN IF EXISTS calf_cm,
+  DROP COLUMN IF EXISTS thigh_cm,
+  DROP COLUMN IF EXISTS arm_cm,
+  DROP COLUMN IF EXISTS chest_cm;
@@ -0,0 +1,10 @@
+-- 042: medidas corporais adicionais em user_metrics (série temporal por dia)
+ALTER TABLE public.user_metrics
+  ADD COLUMN IF NOT EXISTS chest_cm numeric(6,2),
+  ADD COLUMN IF NOT EXISTS arm_cm   numeric(6,2),
+  ADD COLUMN IF NOT EXISTS thigh_cm numeric(6,2),
+  ADD COLUMN IF NOT EXISTS calf_cm  numeric(6,2);
+
+-- lookups da tendência de peso (últimas pesagens do usuário)
+CREATE INDEX IF NOT EXISTS idx_user_metrics_user_weight
+  ON public.user_metrics (user_id, measured_at DESC) WHERE weight_kg IS NOT NULL;
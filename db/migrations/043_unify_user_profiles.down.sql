@@ -0,0 +1,10 @@
+-- 043 (down): recria goal/level (027/028) a partir das colunas canônicas
+ALTER TABLE public.user_profiles
+  ADD COLUMN IF NOT EXISTS goal  TEXT,
+  ADD COLUMN IF NOT EXISTS level TEXT;
+
+UPDATE public.user_profiles
+  SET goal = training_goal, level = experience_level;
+
+ALTER TABLE public.user_profiles
+  ALTER COLUMN height_cm TYPE NUMERIC(5,2);
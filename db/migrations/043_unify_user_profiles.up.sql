@@ -0,0 +1,49 @@
+-- 043: perfil único (funde 015/017 e 027–030)
+-- Canônico: height_cm, weight_kg, birth_date (birth_year como fallback), gender,
+-- training_goal, experience_level, activity_level, use_ai, notes, updated_at.
+-- As colunas legadas goal/level são copiadas e removidas.
+
+ALTER TABLE public.user_profiles
+  ADD COLUMN IF NOT EXISTS height_cm        NUMERIC(5,1),
+  ADD COLUMN IF NOT EXISTS weight_kg        NUMERIC(6,2),
+  ADD COLUMN IF NOT EXISTS birth_date       DATE,
+  ADD COLUMN IF NOT EXISTS birth_year       INT,
+  ADD COLUMN IF NOT EXISTS gender           TEXT,
+  ADD COLUMN IF NOT EXISTS training_goal    TEXT,
+  ADD COLUMN IF NOT EXISTS experience_level TEXT,
+  ADD COLUMN IF NOT EXISTS activity_level   TEXT,
+  ADD COLUMN IF NOT EXISTS use_ai           BOOLEAN DEFAULT TRUE,
+  ADD COLUMN IF NOT EXISTS notes            TEXT,
+  ADD COLUMN IF NOT EXISTS updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- 015 criou height_cm INTEGER; 027 usava NUMERIC(5,2) (altura com decimal)
+ALTER TABLE public.user_profiles
+  ALTER COLUMN height_cm TYPE NUMERIC(5,1) USING height_cm::numeric;
+
+-- merge dos dados legados
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema = 'public' AND table_name = 'user_profiles' AND column_name = 'goal') THEN
+    UPDATE public.user_profiles
+      SET training_goal = COALESCE(NULLIF(training_goal, ''), NULLIF(goal, ''))
+      WHERE goal IS NOT NULL;
+    ALTER TABLE public.user_profiles DROP COLUMN goal;
+  END IF;
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema = 'public' AND table_name = 'user_profiles' AND column_name = 'level') THEN
+    UPDATE public.user_profiles
+      SET experience_level = COALESCE(NULLIF(experience_level, ''), NULLIF(level, ''))
+      WHERE level IS NOT NULL;
+    ALTER TABLE public.user_profiles DROP COLUMN level;
+  END IF;
+END $$;
+
+UPDATE public.user_profiles
+  SET birth_year = EXTRACT(YEAR FROM birth_date)::INT
+  WHERE birth_date IS NOT NULL
+    AND (birth_year IS NULL OR birth_year <> EXTRACT(YEAR FROM birth_date)::INT);
+
+-- índices de 015 (idempotentes) nas colunas canônicas
+CREATE INDEX IF NOT EXISTS idx_user_profiles_goal  ON public.user_profiles (training_goal);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_level ON public.user_profiles (experience_level);
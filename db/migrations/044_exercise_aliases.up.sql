@@ -0,0 +1,44 @@
+-- 044: apelidos de exercícios (PT/EN) para o registro por texto livre
+-- alias é comparado sem acento e em minúsculas.
+CREATE TABLE IF NOT EXISTS public.exercise_aliases (
+  id           BIGSERIAL PRIMARY KEY,
+  exercicio_id INT  NOT NULL REFERENCES public.exercises(id) ON DELETE CASCADE,
+  alias        TEXT NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_exercise_aliases_alias
+  ON public.exercise_aliases (lower(alias));
+
+CREATE INDEX IF NOT EXISTS idx_exercise_aliases_exercicio
+  ON public.exercise_aliases (exercicio_id);
+
+-- seeds para o catálogo base (008); ignorados se o id não existir
+INSERT INTO public.exercise_aliases (exercicio_id, alias)
+SELECT v.id, v.alias
+FROM (VALUES
+  (1,  'supino'),
+  (1,  'supino reto'),
+  (1,  'bench press'),
+  (1,  'bench'),
+  (2,  'supino inclinado'),
+  (2,  'incline dumbbell press'),
+  (2,  'incline press'),
+  (3,  'remada curvada'),
+  (3,  'barbell row'),
+  (3,  'bent over row'),
+  (4,  'puxada'),
+  (4,  'pulldown'),
+  (4,  'lat pulldown'),
+  (5,  'agachamento'),
+  (5,  'agacho'),
+  (5,  'squat'),
+  (5,  'back squat'),
+  (6,  'leg press'),
+  (6,  'leg 45'),
+  (10, 'abdominal'),
+  (10, 'crunch'),
+  (11, 'treadmill')
+) AS v(id, alias)
+WHERE EXISTS (SELECT 1 FROM public.exercises e WHERE e.id = v.id)
+ON CONFLICT DO NOTHING;
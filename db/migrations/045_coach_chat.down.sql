@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.coach_messages;
+DROP TABLE IF EXISTS public.coach_threads;
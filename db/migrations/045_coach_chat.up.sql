@@ -0,0 +1,31 @@
+-- 045: conversas com o coach (threads + mensagens); quotas contam coach_messages do dia
+CREATE TABLE IF NOT EXISTS public.coach_threads (
+  id          BIGSERIAL PRIMARY KEY,
+  user_id     TEXT        NOT NULL,
+  title       TEXT        NOT NULL DEFAULT '',
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_coach_threads_user
+  ON public.coach_threads (user_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS public.coach_messages (
+  id              BIGSERIAL PRIMARY KEY,
+  thread_id       BIGINT      NOT NULL REFERENCES public.coach_threads(id) ON DELETE CASCADE,
+  user_id         TEXT        NOT NULL,
+  role            TEXT        NOT NULL CHECK (role IN ('user','assistant')),
+  content         TEXT        NOT NULL,
+  tokens          INT         NOT NULL DEFAULT 0,   -- entrada (user) ou saída (assistant)
+  provider        TEXT,
+  model           TEXT,
+  prompt_version  TEXT,
+  fallback        BOOLEAN     NOT NULL DEFAULT FALSE,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_coach_messages_thread
+  ON public.coach_messages (thread_id, id);
+
+CREATE INDEX IF NOT EXISTS idx_coach_messages_user_day
+  ON public.coach_messages (user_id, created_at);
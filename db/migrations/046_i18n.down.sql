@@ -0,0 +1,3 @@
+ALTER TABLE public.exercises DROP COLUMN IF EXISTS name_en;
+ALTER TABLE public.user_profiles DROP CONSTRAINT IF EXISTS ck_user_profiles_language;
+ALTER TABLE public.user_profiles DROP COLUMN IF EXISTS language;
@@ -0,0 +1,30 @@
+-- 046: idioma preferido do usuário e nomes de exercícios em inglês
+ALTER TABLE public.user_profiles
+  ADD COLUMN IF NOT EXISTS language TEXT;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'ck_user_profiles_language') THEN
+    ALTER TABLE public.user_profiles
+      ADD CONSTRAINT ck_user_profiles_language CHECK (language IS NULL OR language IN ('pt-BR','en'));
+  END IF;
+END $$;
+
+-- name_en NULL = sem tradução (a API usa name)
+ALTER TABLE public.exercises
+  ADD COLUMN IF NOT EXISTS name_en TEXT;
+
+UPDATE public.exercises e SET name_en = t.name_en
+FROM (VALUES
+  ('supino reto com barra',         'Barbell bench press'),
+  ('supino inclinado com halteres', 'Incline dumbbell press'),
+  ('remada curvada com barra',      'Barbell bent-over row'),
+  ('puxada frontal na polia',       'Lat pulldown'),
+  ('puxada frontal',                'Lat pulldown'),
+  ('agachamento livre',             'Barbell back squat'),
+  ('leg press 45°',                 '45° leg press'),
+  ('abdominal supra',               'Crunch'),
+  ('esteira',                       'Treadmill'),
+  ('flexão de braços',              'Push-up')
+) AS t(name, name_en)
+WHERE lower(e.name) = t.name AND e.owner_user_id IS NULL AND e.name_en IS NULL;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS public.auth_tokens;
+ALTER TABLE public.users
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,18 @@
+-- 047: verificação de e-mail e reset de senha
+ALTER TABLE public.users
+  ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS updated_at        TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- tokens de uso único; guardamos só o sha256 (hex) do token enviado por e-mail
+CREATE TABLE IF NOT EXISTS public.auth_tokens (
+  id         BIGSERIAL PRIMARY KEY,
+  user_id    UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  purpose    TEXT NOT NULL CHECK (purpose IN ('verify_email','reset_password')),
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at    TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_tokens_user_purpose
+  ON public.auth_tokens (user_id, purpose);
@@ -0,0 +1,28 @@
+-- 048: sessões de login (uma por dispositivo) e refresh tokens rotativos.
+-- Cada sessão é uma "família": reuso de um refresh token já trocado revoga a sessão inteira.
+CREATE TABLE IF NOT EXISTS public.login_sessions (
+  id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id        UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  user_agent     TEXT,
+  ip             TEXT,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at     TIMESTAMPTZ,
+  revoked_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_sessions_user
+  ON public.login_sessions (user_id) WHERE revoked_at IS NULL;
+
+-- refresh tokens: só o sha256 (hex); rotated_at preenchido quando trocado por um novo
+CREATE TABLE IF NOT EXISTS public.refresh_tokens (
+  id         BIGSERIAL PRIMARY KEY,
+  session_id UUID NOT NULL REFERENCES public.login_sessions(id) ON DELETE CASCADE,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  rotated_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session
+  ON public.refresh_tokens (session_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS public.role_audit;
+DROP TABLE IF EXISTS public.user_roles;
@@ -0,0 +1,22 @@
+-- 049: papéis de usuário (athlete implícito para todos) e trilha de auditoria
+CREATE TABLE IF NOT EXISTS public.user_roles (
+  user_id    UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  role       TEXT NOT NULL CHECK (role IN ('athlete','coach','admin')),
+  granted_by UUID REFERENCES public.users(id) ON DELETE SET NULL,
+  granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, role)
+);
+
+-- actor_user_id NULL = sistema (BOOTSTRAP_ADMIN_EMAIL)
+CREATE TABLE IF NOT EXISTS public.role_audit (
+  id            BIGSERIAL PRIMARY KEY,
+  user_id       UUID NOT NULL,
+  role          TEXT NOT NULL,
+  action        TEXT NOT NULL CHECK (action IN ('grant','revoke')),
+  actor_user_id UUID,
+  reason        TEXT,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_audit_user
+  ON public.role_audit (user_id, created_at DESC);
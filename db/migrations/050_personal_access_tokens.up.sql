@@ -0,0 +1,17 @@
+-- 050: tokens de acesso pessoal (scripts/integrações); só o sha256 (hex) é guardado
+CREATE TABLE IF NOT EXISTS public.personal_access_tokens (
+  id           BIGSERIAL PRIMARY KEY,
+  user_id      UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  name         TEXT NOT NULL,
+  token_hash   TEXT NOT NULL UNIQUE,
+  token_prefix TEXT NOT NULL,          -- início do token, para o usuário reconhecer
+  scopes       TEXT[] NOT NULL,
+  expires_at   TIMESTAMPTZ NOT NULL,
+  last_used_at TIMESTAMPTZ,
+  last_used_ip TEXT,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_pat_user
+  ON public.personal_access_tokens (user_id) WHERE revoked_at IS NULL;
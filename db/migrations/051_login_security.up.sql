@@ -0,0 +1,26 @@
+-- 051: contenção de força bruta no login e eventos de segurança
+-- key: 'acct:<email>' (inclusive e-mails inexistentes) ou 'ip:<ip>'
+CREATE TABLE IF NOT EXISTS public.login_throttle (
+  key             TEXT PRIMARY KEY,
+  failures        INT NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  locked_until    TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS public.security_events (
+  id         BIGSERIAL PRIMARY KEY,
+  event      TEXT NOT NULL,
+  user_id    UUID REFERENCES public.users(id) ON DELETE SET NULL,
+  email      TEXT,
+  ip         TEXT,
+  user_agent TEXT,
+  detail     JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_created
+  ON public.security_events (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_security_events_user
+  ON public.security_events (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_security_events_event
+  ON public.security_events (event, created_at DESC);
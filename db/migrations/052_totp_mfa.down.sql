@@ -0,0 +1,8 @@
+DELETE FROM public.auth_tokens WHERE purpose = 'mfa_login';
+ALTER TABLE public.auth_tokens DROP CONSTRAINT IF EXISTS auth_tokens_purpose_check;
+ALTER TABLE public.auth_tokens ADD CONSTRAINT auth_tokens_purpose_check
+  CHECK (purpose IN ('verify_email','reset_password'));
+
+ALTER TABLE public.login_sessions DROP COLUMN IF EXISTS mfa;
+DROP TABLE IF EXISTS public.mfa_recovery_codes;
+DROP TABLE IF EXISTS public.user_totp;
@@ -0,0 +1,27 @@
+-- 052: autenticação em dois fatores (TOTP) e códigos de recuperação
+CREATE TABLE IF NOT EXISTS public.user_totp (
+  user_id      UUID PRIMARY KEY REFERENCES public.users(id) ON DELETE CASCADE,
+  secret       TEXT NOT NULL,              -- base32
+  confirmed_at TIMESTAMPTZ,                -- NULL = cadastro pendente (sem 2FA ativo)
+  last_step    BIGINT NOT NULL DEFAULT 0,  -- último passo aceito (anti-reuso)
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- só o sha256 (hex) de cada código; uso único
+CREATE TABLE IF NOT EXISTS public.mfa_recovery_codes (
+  id         BIGSERIAL PRIMARY KEY,
+  user_id    UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  code_hash  TEXT NOT NULL,
+  used_at    TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (user_id, code_hash)
+);
+
+-- sessão aberta com segundo fator
+ALTER TABLE public.login_sessions
+  ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT false;
+
+-- desafio do login em duas etapas
+ALTER TABLE public.auth_tokens DROP CONSTRAINT IF EXISTS auth_tokens_purpose_check;
+ALTER TABLE public.auth_tokens ADD CONSTRAINT auth_tokens_purpose_check
+  CHECK (purpose IN ('verify_email','reset_password','mfa_login'));
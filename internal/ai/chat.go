@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"anima/internal/i18n"
+)
+
+// ChatVersion vai para coach_messages.prompt_version nas respostas do chat.
+const ChatVersion = "coach-chat-v2"
+
+// Message: uma mensagem da conversa (role: user | assistant).
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SessionSummary: resumo de uma sessão recente.
+type SessionSummary struct {
+	Date      string   `json:"date"` // YYYY-MM-DD
+	Sets      int      `json:"sets"`
+	VolumeKg  float64  `json:"volume_kg"`
+	Exercises []string `json:"exercises,omitempty"`
+}
+
+// OverloadNote: última sugestão de progressão de um exercício.
+type OverloadNote struct {
+	Exercise   string   `json:"exercise"`
+	LastKg     *float64 `json:"last_kg,omitempty"`
+	SuggestKg  *float64 `json:"suggested_kg,omitempty"`
+	SuggestRep *int     `json:"suggested_reps,omitempty"`
+	Date       string   `json:"date"`
+}
+
+// ChatContext: o que o servidor sabe do aluno (montado pelos handlers).
+type ChatContext struct {
+	Profile    CoachInput       `json:"profile"`
+	Sessions   []SessionSummary `json:"sessions,omitempty"`
+	WeeklySets map[string]int   `json:"weekly_sets,omitempty"` // séries válidas nos últimos 7 dias por grupo
+	Overload   []OverloadNote   `json:"overload,omitempty"`
+}
+
+// ChatInput: contexto + histórico da thread + pergunta atual.
+type ChatInput struct {
+	Context ChatContext
+	History []Message
+	Message string
+}
+
+// ChatReply: resposta + metadados gravados em coach_messages.
+type ChatReply struct {
+	Content       string `json:"content"`
+	Provider      string `json:"provider"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version"`
+	Fallback      bool   `json:"fallback,omitempty"`
+	Error         string `json:"error,omitempty"`
+	TokensIn      int    `json:"tokens_in"`
+	TokensOut     int    `json:"tokens_out"`
+}
+
+// ChatResponder responde perguntas livres do aluno.
+type ChatResponder interface {
+	Name() string
+	Reply(ctx context.Context, in ChatInput) (ChatReply, error)
+}
+
+// EstimateTokens: ~4 caracteres por token (para quota quando o provider não informa).
+func EstimateTokens(s string) int {
+	return (len([]rune(s)) + 3) / 4
+}
+
+// ===== heurística (sem rede)
+
+func (Heuristic) Reply(_ context.Context, in ChatInput) (ChatReply, error) {
+	out := heuristicReply(in)
+	return ChatReply{
+		Content:       out,
+		Provider:      "heuristic",
+		PromptVersion: HeuristicVersion,
+		TokensIn:      EstimateTokens(in.Message),
+		TokensOut:     EstimateTokens(out),
+	}, nil
+}
+
+var foldChat = strings.NewReplacer("á", "a", "â", "a", "ã", "a", "é", "e", "ê", "e", "í", "i", "ó", "o", "ô", "o", "õ", "o", "ú", "u", "ç", "c")
+
+func hasAny(s string, words ...string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// heuristicReply responde por palavras-chave usando só o contexto.
+func heuristicReply(in ChatInput) string {
+	q := foldChat.Replace(strings.ToLower(in.Message))
+	c := in.Context
+	l := c.Profile.lang()
+
+	switch {
+	case hasAny(q, "dieta", "emagrec", "perder peso", "proteina", "caloria", "comer", "macro", "diet", "protein"):
+		txt := i18n.T(l, "chat.diet")
+		if c.Profile.WeightKg != nil {
+			txt += i18n.T(l, "chat.diet_protein", *c.Profile.WeightKg*1.6, *c.Profile.WeightKg*2.2, *c.Profile.WeightKg)
+		}
+		return txt
+
+	case hasAny(q, "volume", "series", "sets", "quantas"):
+		if len(c.WeeklySets) == 0 {
+			return i18n.T(l, "chat.volume_none")
+		}
+		groups := make([]string, 0, len(c.WeeklySets))
+		for g := range c.WeeklySets {
+			groups = append(groups, g)
+		}
+		sort.Strings(groups)
+		parts := make([]string, 0, len(groups))
+		var low []string
+		for _, g := range groups {
+			parts = append(parts, fmt.Sprintf("%s %d", g, c.WeeklySets[g]))
+			if c.WeeklySets[g] < 10 {
+				low = append(low, g)
+			}
+		}
+		txt := i18n.T(l, "chat.volume", strings.Join(parts, ", "))
+		if len(low) > 0 {
+			txt += i18n.T(l, "chat.volume_low", strings.Join(low, ", "))
+		}
+		return txt
+
+	case hasAny(q, "carga", "peso", "aumentar", "progre", "overload", "weight", "increase"):
+		if len(c.Overload) == 0 {
+			return i18n.T(l, "chat.overload_none")
+		}
+		parts := make([]string, 0, len(c.Overload))
+		for _, o := range c.Overload {
+			if o.SuggestKg == nil {
+				continue
+			}
+			s := fmt.Sprintf("%s: %.1f kg", o.Exercise, *o.SuggestKg)
+			if o.SuggestRep != nil {
+				s += fmt.Sprintf(" x %d", *o.SuggestRep)
+			}
+			parts = append(parts, s)
+		}
+		if len(parts) == 0 {
+			return i18n.T(l, "chat.overload_keep")
+		}
+		return i18n.T(l, "chat.overload", strings.Join(parts, "; "))
+
+	case hasAny(q, "descanso", "recupera", "dor", "cansa", "sono", "deload", "rest", "tired"):
+		txt := i18n.T(l, "chat.recovery")
+		if n := len(c.Sessions); n >= 5 {
+			txt += i18n.T(l, "chat.recovery_deload", n)
+		}
+		return txt + i18n.T(l, "chat.recovery_pain")
+	}
+
+	// padrão: resumo do que sabemos
+	txt := heuristicNotes(c.Profile)
+	if n := len(c.Sessions); n > 0 {
+		last := c.Sessions[0]
+		txt += i18n.T(l, "chat.last_session", last.Date, last.Sets, last.VolumeKg)
+	}
+	return txt + i18n.T(l, "chat.ask_more")
+}
+
+// ===== provider de chat
+
+func (p *ChatProvider) Reply(ctx context.Context, in ChatInput) (ChatReply, error) {
+	msgs, err := chatMessages(in)
+	if err == nil {
+		var (
+			out   string
+			usage chatUsage
+		)
+		if out, usage, err = p.send(ctx, msgs, 0.5, 500); err == nil {
+			if usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+				for _, m := range msgs {
+					usage.PromptTokens += EstimateTokens(m.Content)
+				}
+				usage.CompletionTokens = EstimateTokens(out)
+			}
+			return ChatReply{
+				Content:       out,
+				Provider:      p.Name(),
+				Model:         p.Model,
+				PromptVersion: ChatVersion,
+				TokensIn:      usage.PromptTokens,
+				TokensOut:     usage.CompletionTokens,
+			}, nil
+		}
+	}
+	fb, ok := p.Fallback.(ChatResponder)
+	if !ok {
+		return ChatReply{}, err
+	}
+	res, ferr := fb.Reply(ctx, in)
+	if ferr != nil {
+		return ChatReply{}, ferr
+	}
+	res.Fallback = true
+	res.Error = err.Error()
+	return res, nil
+}
@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChatProvider: endpoint de chat compatível com OpenAI (POST {BaseURL}/chat/completions).
+// Em qualquer erro (timeout, HTTP != 2xx, resposta vazia) cai para Fallback.
+type ChatProvider struct {
+	BaseURL  string
+	APIKey   string
+	Model    string
+	Timeout  time.Duration
+	Client   *http.Client
+	Fallback CoachProvider
+}
+
+func (p *ChatProvider) Name() string { return "chat" }
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens"`
+}
+
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Usage chatUsage `json:"usage"`
+}
+
+func (p *ChatProvider) CoachNotes(ctx context.Context, in CoachInput) (CoachResult, error) {
+	notes, err := p.complete(ctx, in)
+	if err == nil {
+		return CoachResult{Notes: notes, Provider: p.Name(), Model: p.Model, PromptVersion: PromptVersion}, nil
+	}
+	if p.Fallback == nil {
+		return CoachResult{}, err
+	}
+	res, ferr := p.Fallback.CoachNotes(ctx, in)
+	if ferr != nil {
+		return CoachResult{}, ferr
+	}
+	res.Fallback = true
+	res.Error = err.Error()
+	return res, nil
+}
+
+func (p *ChatProvider) complete(ctx context.Context, in CoachInput) (string, error) {
+	sys, user, err := renderCoachPrompt(in)
+	if err != nil {
+		return "", err
+	}
+	out, _, err := p.send(ctx, []chatMessage{
+		{Role: "system", Content: sys},
+		{Role: "user", Content: user},
+	}, 0.4, 300)
+	return out, err
+}
+
+// send faz a chamada em si; devolve o texto e o uso de tokens informado pelo provider.
+func (p *ChatProvider) send(ctx context.Context, msgs []chatMessage, temperature float64, maxTokens int) (string, chatUsage, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:       p.Model,
+		Messages:    msgs,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+	})
+	if err != nil {
+		return "", chatUsage{}, err
+	}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimRight(p.BaseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", chatUsage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", chatUsage{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", chatUsage{}, fmt.Errorf("chat provider: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out chatResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return "", chatUsage{}, fmt.Errorf("chat provider: %w", err)
+	}
+	if len(out.Choices) == 0 || strings.TrimSpace(out.Choices[0].Message.Content) == "" {
+		return "", chatUsage{}, errors.New("chat provider: empty completion")
+	}
+	return strings.TrimSpace(out.Choices[0].Message.Content), out.Usage, nil
+}
+
+// ProviderFromEnv escolhe o provider:
+//
+//	COACH_PROVIDER=chat  -> ChatProvider (COACH_API_URL, COACH_API_KEY, COACH_MODEL, COACH_TIMEOUT_MS)
+//	default              -> Heuristic
+func ProviderFromEnv() CoachProvider {
+	if !strings.EqualFold(strings.TrimSpace(os.Getenv("COACH_PROVIDER")), "chat") {
+		return Heuristic{}
+	}
+	p := &ChatProvider{
+		BaseURL:  envOr("COACH_API_URL", "https://api.openai.com/v1"),
+		APIKey:   os.Getenv("COACH_API_KEY"),
+		Model:    envOr("COACH_MODEL", "gpt-4o-mini"),
+		Timeout:  8 * time.Second,
+		Fallback: Heuristic{},
+	}
+	if ms, err := strconv.Atoi(os.Getenv("COACH_TIMEOUT_MS")); err == nil && ms > 0 {
+		p.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	return p
+}
+
+func envOr(k, def string) string {
+	if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"anima/internal/i18n"
+)
+
+// PromptVersion vai para generations.prompt_version; mude ao alterar os templates.
+const PromptVersion = "coach-v2"
+
+const coachSystemPrompt = `Você é um treinador de musculação experiente. Escreva notas curtas (no máximo 4 frases) para o plano de treino do aluno: foco da sessão, progressão, recuperação e cuidados. Não invente dados que não foram informados e não dê conselhos médicos.`
+
+var coachUserTmpl = template.Must(template.New("coach_user").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"f":    func(v *float64) float64 { return *v },
+}).Parse(`Objetivo: {{or .Goal "geral"}}
+Nível: {{or .Level "indefinido"}}
+Divisão: {{or .Split "indefinida"}}
+{{- with .HeightCm}}
+Altura: {{printf "%.0f" (f .)}} cm{{end}}
+{{- with .WeightKg}}
+Peso: {{printf "%.1f" (f .)}} kg{{end}}
+{{- with .WeeklyRateKg}}
+Variação do peso: {{printf "%+.2f" (f .)}} kg/semana{{end}}
+{{- with .Age}}
+Idade: {{.}} anos{{end}}
+{{- if .Exercises}}
+Exercícios: {{join .Exercises ", "}}{{end}}`))
+
+// renderCoachPrompt devolve (system, user).
+func renderCoachPrompt(in CoachInput) (string, string, error) {
+	var b bytes.Buffer
+	if err := coachUserTmpl.Execute(&b, in); err != nil {
+		return "", "", err
+	}
+	return coachSystemPrompt + " " + i18n.T(in.lang(), "chat.respond_in"), b.String(), nil
+}
+
+const chatSystemPrompt = `Você é o coach de musculação do aplicativo Anima. Responda de forma curta e prática, usando apenas os dados do aluno abaixo. Se faltar informação, diga o que o aluno deve registrar. Não dê diagnósticos nem conselhos médicos.`
+
+var chatContextTmpl = template.Must(template.New("coach_chat").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"f":    func(v *float64) float64 { return *v },
+}).Parse(`{{- if .Sessions}}
+Sessões recentes:
+{{- range .Sessions}}
+- {{.Date}}: {{.Sets}} séries, {{printf "%.0f" .VolumeKg}} kg de volume{{if .Exercises}} ({{join .Exercises ", "}}){{end}}
+{{- end}}{{end}}
+{{- if .WeeklySets}}
+Séries nos últimos 7 dias por grupo:{{range $g, $n := .WeeklySets}} {{$g}}={{$n}}{{end}}{{end}}
+{{- if .Overload}}
+Sugestões de progressão:
+{{- range .Overload}}
+- {{.Exercise}} ({{.Date}}):{{with .LastKg}} média {{printf "%.1f" (f .)}} kg{{end}}{{with .SuggestKg}} -> {{printf "%.1f" (f .)}} kg{{end}}{{with .SuggestRep}} x {{.}}{{end}}
+{{- end}}{{end}}`))
+
+// chatMessages monta system (instruções + dados do aluno), histórico e a pergunta.
+func chatMessages(in ChatInput) ([]chatMessage, error) {
+	var b bytes.Buffer
+	if err := coachUserTmpl.Execute(&b, in.Context.Profile); err != nil {
+		return nil, err
+	}
+	if err := chatContextTmpl.Execute(&b, in.Context); err != nil {
+		return nil, err
+	}
+	sys := chatSystemPrompt + " " + i18n.T(in.Context.Profile.lang(), "chat.respond_in")
+	msgs := []chatMessage{{Role: "system", Content: sys + "\n\nDados do aluno:\n" + b.String()}}
+	for _, m := range in.History {
+		msgs = append(msgs, chatMessage{Role: m.Role, Content: m.Content})
+	}
+	return append(msgs, chatMessage{Role: "user", Content: in.Message}), nil
+}
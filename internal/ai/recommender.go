@@ -1,97 +1,119 @@
 package ai
 
 import (
-	"fmt"
+	"context"
 	"math"
-	"time"
+	"strings"
+
+	"anima/internal/i18n"
 )
 
-type Profile struct {
-	HeightCm      int
-	WeightKg      float64
-	Birthdate     *time.Time
-	Goal          string
-	ActivityLevel string
-	UseAI         bool
+// CoachInput: dados do perfil/plano usados para as notas do coach.
+type CoachInput struct {
+	Goal         string   `json:"goal"`
+	Level        string   `json:"level"`
+	Split        string   `json:"split"`
+	HeightCm     *float64 `json:"height_cm,omitempty"`
+	WeightKg     *float64 `json:"weight_kg,omitempty"`
+	WeeklyRateKg *float64 `json:"weekly_rate_kg,omitempty"` // tendência de peso (kg/semana)
+	Age          *int     `json:"age,omitempty"`
+	Exercises    []string `json:"exercises,omitempty"`
+	Lang         string   `json:"lang,omitempty"` // pt-BR (padrão) | en
 }
 
-// Idade aproximada em anos
-func age(bd *time.Time) int {
-	if bd == nil {
-		return 0
-	}
-	now := time.Now()
-	y := now.Year() - bd.Year()
-	// Ajuste se ainda não fez aniversário no ano
-	if now.YearDay() < bd.YearDay() {
-		y--
-	}
-	if y < 0 {
-		return 0
+func (in CoachInput) lang() i18n.Lang {
+	if l, ok := i18n.Parse(in.Lang); ok {
+		return l
 	}
-	return y
+	return i18n.Default
 }
 
-// IMC simples
-func bmi(heightCm int, weightKg float64) float64 {
-	if heightCm <= 0 || weightKg <= 0 {
-		return 0
-	}
-	h := float64(heightCm) / 100.0
-	return weightKg / (h * h)
+// CoachResult: notas + metadados gravados em generations.
+type CoachResult struct {
+	Notes         string `json:"notes"`
+	Provider      string `json:"provider"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version"`
+	Fallback      bool   `json:"fallback,omitempty"` // heurística após erro do provider
+	Error         string `json:"error,omitempty"`
+}
+
+// CoachProvider gera as notas do coach para um plano.
+type CoachProvider interface {
+	Name() string
+	CoachNotes(ctx context.Context, in CoachInput) (CoachResult, error)
 }
 
-// Heurística simples para volume e foco com base em objetivo/IMC
-func coachNotes(p Profile) string {
-	imc := bmi(p.HeightCm, p.WeightKg)
-	a := age(p.Birthdate)
+// HeuristicVersion identifica o template fixo da heurística.
+const HeuristicVersion = "heuristic-v1"
 
-	var foco string
-	switch p.Goal {
-	case "hipertrofia":
-		foco = "priorize progressão de carga e boa técnica em compostos"
-	case "emagrecimento":
-		foco = "mantenha maior densidade do treino e descanso controlado"
-	case "resistência":
-		foco = "ênfase em volume moderado e menor descanso"
-	default:
-		foco = "mantenha técnica e regularidade"
-	}
+// Heuristic: notas por template (sem rede). Default e fallback dos demais providers.
+type Heuristic struct{}
 
-	var extra string
-	switch {
-	case imc == 0:
-		extra = "registre peso/altura para recomendações melhores"
-	case imc < 18.5:
-		extra = "reforço calórico leve pode ajudar no ganho de massa"
-	case imc < 25:
-		extra = "mantenha leve superávit calórico e sono adequado"
-	case imc < 30:
-		extra = "combine treino com leve déficit calórico e alta consistência"
-	default:
-		extra = "priorize aderência e monitoramento semanal de medidas"
+func (Heuristic) Name() string { return "heuristic" }
+
+func (Heuristic) CoachNotes(_ context.Context, in CoachInput) (CoachResult, error) {
+	return CoachResult{
+		Notes:         heuristicNotes(in),
+		Provider:      "heuristic",
+		PromptVersion: HeuristicVersion,
+	}, nil
+}
+
+// IMC simples (0 sem dados)
+func bmi(heightCm, weightKg *float64) float64 {
+	if heightCm == nil || weightKg == nil || *heightCm <= 0 || *weightKg <= 0 {
+		return 0
 	}
+	h := *heightCm / 100.0
+	return math.Round(*weightKg/(h*h)*100) / 100
+}
 
-	// Toques contextuais (totalmente heurísticos)
-	hint := ""
-	if a > 0 {
-		if a >= 40 {
-			hint = "; aqueça bem ombros/quadril e respeite cadências seguras"
-		} else if a <= 20 {
-			hint = "; foque em técnica antes de buscar cargas muito altas"
-		}
+func valOr(def, v string) string {
+	if strings.TrimSpace(v) == "" {
+		return def
 	}
+	return v
+}
 
-	// Arredonda IMC para duas casas para exibir
-	roundIMC := math.Round(imc*100) / 100
+func heuristicNotes(in CoachInput) string {
+	l := in.lang()
+	txt := i18n.T(l, "coach.plan",
+		i18n.Label(l, "goal", valOr(i18n.T(l, "coach.goal_general"), in.Goal)),
+		i18n.Label(l, "level", valOr(i18n.T(l, "coach.level_unset"), in.Level)),
+		i18n.Label(l, "division", valOr(i18n.T(l, "coach.split_unset"), in.Split)))
 
-	return fmt.Sprintf("%s. IMC=%.2f — %s%s.", foco, roundIMC, extra, hint)
-}
+	if in.HeightCm != nil {
+		txt += i18n.T(l, "coach.height", *in.HeightCm)
+	}
+	if in.WeightKg != nil {
+		if in.WeeklyRateKg != nil {
+			txt += i18n.T(l, "coach.weight_trend", *in.WeightKg, *in.WeeklyRateKg)
+		} else {
+			txt += i18n.T(l, "coach.weight", *in.WeightKg)
+		}
+	}
+	if in.Age != nil {
+		txt += i18n.T(l, "coach.age", *in.Age)
+	}
+	if imc := bmi(in.HeightCm, in.WeightKg); imc > 0 {
+		txt += i18n.T(l, "coach.bmi", imc)
+	}
 
-// ComputeCoachNotes cria uma nota final, ou string vazia se UseAI=false.
-func ComputeCoachNotes(p Profile) string {
-	if !p.UseAI {
-		return ""
+	switch in.Goal {
+	case "hipertrofia":
+		txt += i18n.T(l, "coach.tip.hyper")
+	case "emagrecimento":
+		txt += i18n.T(l, "coach.tip.fatloss")
+	case "forca", "força":
+		txt += i18n.T(l, "coach.tip.strength")
+	case "resistencia", "resistência":
+		txt += i18n.T(l, "coach.tip.endurance")
+	default:
+		txt += i18n.T(l, "coach.tip.default")
+	}
+	if in.Age != nil && *in.Age >= 40 {
+		txt += i18n.T(l, "coach.tip.age40")
 	}
-	return coachNotes(p)
+	return txt
 }
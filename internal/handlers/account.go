@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"anima/internal/i18n"
+	"anima/internal/mail"
+)
+
+// Ciclo de vida da conta (Postgres + pgcrypto):
+//   POST /api/auth/register             {name?, email, password}
+//   POST /api/auth/verify-email         {token}
+//   POST /api/auth/verify-email/resend  {email}
+//   POST /api/auth/password/forgot      {email}
+//   POST /api/auth/password/reset       {token, password}
+// Tokens de e-mail são de uso único, expiram e só o sha256 fica no banco.
+// resend/forgot respondem 202 sempre (não revelam se o e-mail existe).
+
+const (
+	purposeVerifyEmail   = "verify_email"
+	purposeResetPassword = "reset_password"
+
+	minPasswordLen = 8
+)
+
+var errTokenInvalid = errors.New("invalid or expired token")
+
+// Sender dos e-mails de conta (log por padrão; ver mail.FromEnv).
+var mailer mail.Sender = mail.LogSender{}
+
+func SetMailer(s mail.Sender) {
+	if s == nil {
+		s = mail.LogSender{}
+	}
+	mailer = s
+}
+
+type registerRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type accountUser struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name,omitempty"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func normalizeEmail(s string) (string, bool) {
+	e := strings.ToLower(strings.TrimSpace(s))
+	a, err := netmail.ParseAddress(e)
+	if err != nil || a.Address != e || !strings.Contains(e[strings.LastIndex(e, "@"):], ".") {
+		return "", false
+	}
+	return e, true
+}
+
+// newAuthToken: 32 bytes aleatórios (base64url) e o hash que vai para o banco.
+func newAuthToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashAuthToken(token), nil
+}
+
+func hashAuthToken(t string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(t)))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueAuthToken invalida tokens pendentes do mesmo propósito e cria um novo.
+func issueAuthToken(ctx context.Context, db *sql.DB, userID, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := newAuthToken()
+	if err != nil {
+		return "", err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE auth_tokens SET used_at = now()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO auth_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, now() + $4 * interval '1 second')`,
+		userID, purpose, hash, int64(ttl/time.Second)); err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// consumeAuthToken marca o token como usado (atômico) e devolve o dono.
+func consumeAuthToken(ctx context.Context, tx *sql.Tx, token, purpose string) (string, error) {
+	var uid string
+	err := tx.QueryRowContext(ctx, `
+		UPDATE auth_tokens SET used_at = now()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+		RETURNING user_id::text`, hashAuthToken(token), purpose).Scan(&uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errTokenInvalid
+	}
+	return uid, err
+}
+
+func verifyTokenTTL() time.Duration {
+	return time.Duration(atoiEnvInt("VERIFY_TOKEN_TTL_HOURS", 48)) * time.Hour
+}
+
+func resetTokenTTL() time.Duration {
+	return time.Duration(atoiEnvInt("RESET_TOKEN_TTL_MIN", 60)) * time.Minute
+}
+
+func appLink(path, token string) string {
+	base := strings.TrimRight(strings.TrimSpace(os.Getenv("APP_BASE_URL")), "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return base + path + "?token=" + token
+}
+
+// mailLang: idioma do perfil do destinatário; senão o da requisição.
+func mailLang(ctx context.Context, db *sql.DB, userID string) i18n.Lang {
+	var pref sql.NullString
+	_ = db.QueryRowContext(ctx, `SELECT language FROM user_profiles WHERE user_id = $1`, userID).Scan(&pref)
+	if l, ok := i18n.Parse(pref.String); ok {
+		return l
+	}
+	return langOf(ctx)
+}
+
+// sendAccountMail envia fora da requisição: SMTP lento não segura a resposta
+// nem denuncia (pelo tempo) se o e-mail existe.
+func sendAccountMail(m mail.Message) {
+	s := mailer
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.Send(ctx, m); err != nil {
+			log.Printf("[mail] %s: falha ao enviar %q: %v", s.Name(), m.Subject, err)
+		}
+	}()
+}
+
+func greeting(name string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return " " + name
+	}
+	return ""
+}
+
+func sendVerifyEmail(ctx context.Context, db *sql.DB, userID, name, email string) error {
+	ttl := verifyTokenTTL()
+	token, err := issueAuthToken(ctx, db, userID, purposeVerifyEmail, ttl)
+	if err != nil {
+		return err
+	}
+	l := mailLang(ctx, db, userID)
+	sendAccountMail(mail.Message{
+		To:      email,
+		Subject: i18n.T(l, "mail.verify_subject"),
+		Text:    i18n.T(l, "mail.verify_body", greeting(name), int(ttl/time.Hour), appLink("/verify-email", token)),
+	})
+	return nil
+}
+
+func sendResetEmail(ctx context.Context, db *sql.DB, userID, name, email string) error {
+	ttl := resetTokenTTL()
+	token, err := issueAuthToken(ctx, db, userID, purposeResetPassword, ttl)
+	if err != nil {
+		return err
+	}
+	l := mailLang(ctx, db, userID)
+	sendAccountMail(mail.Message{
+		To:      email,
+		Subject: i18n.T(l, "mail.reset_subject"),
+		Text:    i18n.T(l, "mail.reset_body", greeting(name), int(ttl/time.Minute), appLink("/reset-password", token)),
+	})
+	return nil
+}
+
+// AuthRegister: POST /api/auth/register
+func AuthRegister(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var in registerRequest
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			badRequest(w, "invalid json")
+			return
+		}
+		email, ok := normalizeEmail(in.Email)
+		if !ok {
+			badRequest(w, "invalid email")
+			return
+		}
+		if len([]rune(in.Password)) < minPasswordLen {
+			badRequest(w, "password must be at least 8 characters")
+			return
+		}
+		name := strings.TrimSpace(in.Name)
+
+		var u accountUser
+		err := db.QueryRowContext(r.Context(), `
+			INSERT INTO users (name, email, password_hash)
+			VALUES (NULLIF($1, ''), $2, crypt($3, gen_salt('bf')))
+			RETURNING id::text, COALESCE(name, ''), email, created_at`,
+			name, email, in.Password).Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": "email already registered"})
+				return
+			}
+			internalErr(w, err)
+			return
+		}
+		if err := sendVerifyEmail(r.Context(), db, u.ID, u.Name, u.Email); err != nil {
+			// conta criada; o usuário pode pedir reenvio
+			log.Printf("[auth] register %s: token de verificação: %v", u.ID, err)
+		}
+		jsonWrite(w, http.StatusCreated, u)
+	}
+}
+
+// AuthVerifyEmail: POST /api/auth/verify-email {token}
+func AuthVerifyEmail(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var in struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Token) == "" {
+			badRequest(w, "invalid json")
+			return
+		}
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer tx.Rollback()
+		uid, err := consumeAuthToken(r.Context(), tx, in.Token, purposeVerifyEmail)
+		if errors.Is(err, errTokenInvalid) {
+			badRequest(w, err.Error())
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		var u accountUser
+		err = tx.QueryRowContext(r.Context(), `
+			UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+			WHERE id = $1
+			RETURNING id::text, COALESCE(name, ''), email, created_at`, uid).Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			internalErr(w, err)
+			return
+		}
+		u.EmailVerified = true
+		jsonWrite(w, http.StatusOK, u)
+	}
+}
+
+// lookupAccount resolve o usuário pelo e-mail (ok=false se não existe).
+func lookupAccount(ctx context.Context, db *sql.DB, email string) (id, name string, verified, ok bool, err error) {
+	var vat sql.NullTime
+	err = db.QueryRowContext(ctx, `
+		SELECT id::text, COALESCE(name, ''), email_verified_at FROM users WHERE email = $1`, email).Scan(&id, &name, &vat)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", false, false, nil
+	}
+	if err != nil {
+		return "", "", false, false, err
+	}
+	return id, name, vat.Valid, true, nil
+}
+
+func decodeEmail(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return "", false
+	}
+	var in struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return "", false
+	}
+	email, ok := normalizeEmail(in.Email)
+	if !ok {
+		badRequest(w, "invalid email")
+		return "", false
+	}
+	return email, true
+}
+
+func accepted(w http.ResponseWriter) {
+	jsonWrite(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+}
+
+// AuthResendVerification: POST /api/auth/verify-email/resend {email}
+func AuthResendVerification(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, ok := decodeEmail(w, r)
+		if !ok {
+			return
+		}
+		if !reserveAccountMail(w, r, db, email) {
+			return
+		}
+		uid, name, verified, found, err := lookupAccount(r.Context(), db, email)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if found && !verified {
+			if err := sendVerifyEmail(r.Context(), db, uid, name, email); err != nil {
+				internalErr(w, err)
+				return
+			}
+		}
+		accepted(w)
+	}
+}
+
+// AuthForgotPassword: POST /api/auth/password/forgot {email}
+func AuthForgotPassword(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, ok := decodeEmail(w, r)
+		if !ok {
+			return
+		}
+		if !reserveAccountMail(w, r, db, email) {
+			return
+		}
+		uid, name, _, found, err := lookupAccount(r.Context(), db, email)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if found {
+			if err := sendResetEmail(r.Context(), db, uid, name, email); err != nil {
+				internalErr(w, err)
+				return
+			}
+		}
+		accepted(w)
+	}
+}
+
+// AuthResetPassword: POST /api/auth/password/reset {token, password}
+// Troca a senha, invalida os demais tokens de reset, encerra as sessões de
+// login e confirma o e-mail (quem recebeu o link é dono da caixa).
+func AuthResetPassword(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var in struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Token) == "" {
+			badRequest(w, "invalid json")
+			return
+		}
+		if len([]rune(in.Password)) < minPasswordLen {
+			badRequest(w, "password must be at least 8 characters")
+			return
+		}
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer tx.Rollback()
+		uid, err := consumeAuthToken(r.Context(), tx, in.Token, purposeResetPassword)
+		if errors.Is(err, errTokenInvalid) {
+			badRequest(w, err.Error())
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		var email string
+		if err := tx.QueryRowContext(r.Context(), `
+			UPDATE users
+			SET password_hash = crypt($2, gen_salt('bf')),
+			    email_verified_at = COALESCE(email_verified_at, now()),
+			    updated_at = now()
+			WHERE id = $1
+			RETURNING email`, uid, in.Password).Scan(&email); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if _, err := tx.ExecContext(r.Context(), `
+			UPDATE auth_tokens SET used_at = now()
+			WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, uid, purposeResetPassword); err != nil {
+			internalErr(w, err)
+			return
+		}
+		// senha nova: derruba os dispositivos logados
+		if err := revokeLoginSessions(r.Context(), tx, `user_id = $2`, "password_reset", uid); err != nil {
+			internalErr(w, err)
+			return
+		}
+		// e destrava a conta (bloqueio por tentativas de login)
+		if err := clearLoginThrottle(r.Context(), tx, acctThrottleKey(email)); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			internalErr(w, err)
+			return
+		}
+		recordSecurityEvent(r.Context(), db, r, "password_reset", uid, email, nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
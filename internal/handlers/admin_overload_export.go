@@ -4,23 +4,15 @@ import (
 	"database/sql"
 	"encoding/csv"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 )
 
 // AdminOverloadExportCSV exporta logs como CSV (streaming).
+// Auth: papel admin (RequireRole em main.go).
 // GET /api/admin/overload/export.csv?exercicio_id=10&user_id=abc&from=RFC3339&to=RFC3339&limit=10000
 func AdminOverloadExportCSV(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// auth
-		want := os.Getenv("ADMIN_TOKEN")
-		got := r.Header.Get("X-Admin-Token")
-		if want != "" && got != want {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
-
 		q := r.URL.Query()
 		var where = "WHERE 1=1"
 		var args []any
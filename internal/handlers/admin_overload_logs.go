@@ -3,25 +3,16 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
 // AdminOverloadLogs lista logs de overload com filtros e paginação.
-// Segurança: exige header X-Admin-Token == ADMIN_TOKEN (se setado no ambiente).
+// Auth: papel admin (RequireRole em main.go).
 // GET /api/admin/overload/logs?exercicio_id=10&user_id=abc&from=2025-08-01T00:00:00Z&to=2025-08-31T23:59:59Z&page=1&page_size=50
 func AdminOverloadLogs(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Auth simples
-		want := os.Getenv("ADMIN_TOKEN")
-		got := r.Header.Get("X-Admin-Token")
-		if want != "" && got != want {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
-
 		q := r.URL.Query()
 
 		// filtros
@@ -11,6 +11,9 @@ type refreshResp struct {
 	Error  string `json:"error,omitempty"`
 }
 
+// OverloadRefreshMV recria/atualiza as MVs de overload.
+// Auth: papel admin (RequireRole em main.go).
+// POST /api/admin/overload/refresh
 func OverloadRefreshMV(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -20,6 +23,7 @@ func OverloadRefreshMV(db *sql.DB) http.Handler {
 		}
 
 		// Garante MV por usuário e índice único
+		// (workout_sets_recent12_user já exclui séries flagged — migração 041)
 		if _, err := db.Exec(`
 DO $$
 BEGIN
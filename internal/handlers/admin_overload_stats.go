@@ -3,24 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 )
 
 // AdminOverloadStats retorna agregados dos logs de overload.
-// Auth: X-Admin-Token (se ADMIN_TOKEN setado).
+// Auth: papel admin (RequireRole em main.go).
 // GET /api/admin/overload/stats?group=exercicio|day|hour&exercicio_id=10&from=RFC3339&to=RFC3339&limit=30
 func AdminOverloadStats(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// auth
-		want := os.Getenv("ADMIN_TOKEN")
-		got := r.Header.Get("X-Admin-Token")
-		if want != "" && got != want {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
-
 		q := r.URL.Query()
 		group := q.Get("group")
 		if group == "" {
@@ -1,70 +1,140 @@
 package handlers
 
 import (
-    "crypto/rand"
+	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
 	"time"
+
+	"anima/internal/jwtkeys"
 )
 
+// JWT de acesso.
+// Com keyset (JWT_KEYS_DIR) os tokens saem em RS256/EdDSA com "kid" e o
+// JWKS público fica em /.well-known/jwks.json; HS256 (JWT_SECRET) só é aceito
+// enquanto JWT_ACCEPT_HS256=true (migração). Sem keyset, HS256 é o modo legado.
+// iss (JWT_ISSUER), aud (JWT_AUDIENCE) e exp são obrigatórios; folga de 30s no nbf.
+
 type jwtClaims struct {
-	Sub string `json:"sub"`
-	Exp *int64 `json:"exp,omitempty"`
-	Iat *int64 `json:"iat,omitempty"`
-	// adicione campos se precisar
+	Sub   string          `json:"sub"`
+	Sid   string          `json:"sid,omitempty"` // sessão de login (refresh token) que emitiu o token
+	Roles []string        `json:"roles,omitempty"`
+	Amr   []string        `json:"amr,omitempty"` // "mfa" = sessão com segundo fator
+	Iss   string          `json:"iss,omitempty"`
+	Aud   json.RawMessage `json:"aud,omitempty"` // string ou lista
+	Exp   *int64          `json:"exp,omitempty"`
+	Nbf   *int64          `json:"nbf,omitempty"`
+	Iat   *int64          `json:"iat,omitempty"`
 }
 
 var errInvalidToken = errors.New("invalid token")
 
-// OptionalAuth: se houver Bearer JWT válido, popula user_id; senão segue.
+const (
+	ctxKeyLoginSession ctxKey = "login_session"
+	ctxKeyMFA          ctxKey = "mfa"
+)
+
+const jwtLeeway = 30 // segundos
+
+// Keyset das chaves assimétricas (nil = só HS256).
+var jwtKeys *jwtkeys.Set
+
+func SetJWTKeys(ks *jwtkeys.Set) { jwtKeys = ks }
+
+func jwtIssuer() string   { return envDefault("JWT_ISSUER", "anima") }
+func jwtAudience() string { return envDefault("JWT_AUDIENCE", "anima-api") }
+
+func envDefault(k, def string) string {
+	if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+		return v
+	}
+	return def
+}
+
+// acceptHS256: sem keyset é o único modo; com keyset só sob a flag de migração.
+func acceptHS256() bool {
+	if os.Getenv("JWT_SECRET") == "" {
+		return false
+	}
+	return jwtKeys == nil || strings.EqualFold(os.Getenv("JWT_ACCEPT_HS256"), "true")
+}
+
+// OptionalAuth: se houver Bearer válido (JWT ou PAT), popula user_id; senão segue.
 func OptionalAuth(next http.Handler) http.Handler {
-	secret := os.Getenv("JWT_SECRET")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userID, _ := extractUserIDFromJWT(r, secret)
-		if userID != "" {
-			r = SetUserID(r, userID)
+		if ar, err := authenticate(r); err == nil && ar != nil {
+			r = ar
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// RequireAuth: exige JWT válido; senão 401.
+// RequireAuth: exige Bearer válido (JWT ou PAT); senão 401.
 func RequireAuth(next http.Handler) http.Handler {
-	secret := os.Getenv("JWT_SECRET")
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		userID, err := extractUserIDFromJWT(r, secret)
-		if err != nil || userID == "" {
+		if AuthKind(r) != "" { // já autenticado por OptionalAuth
+			next.ServeHTTP(w, r)
+			return
+		}
+		ar, err := authenticate(r)
+		if err != nil || ar == nil {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, SetUserID(r, userID))
+		next.ServeHTTP(w, ar)
 	})
 }
 
-func extractUserIDFromJWT(r *http.Request, secret string) (string, error) {
+// authenticate resolve o Bearer; (nil, nil) sem Authorization.
+func authenticate(r *http.Request) (*http.Request, error) {
 	auth := r.Header.Get("Authorization")
 	if auth == "" || !strings.HasPrefix(auth, "Bearer ") {
-		return "", nil
+		return nil, nil
 	}
 	token := strings.TrimPrefix(auth, "Bearer ")
-	if secret == "" {
-		return "", errInvalidToken
+	if strings.HasPrefix(token, patPrefix) {
+		return resolvePAT(r, token)
 	}
-	claims, err := verifyJWTHS256(token, secret)
+	claims, err := verifyJWT(token)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return setAuthClaims(r, claims), nil
+}
+
+func setAuthClaims(r *http.Request, c *jwtClaims) *http.Request {
+	r = SetUserID(r, c.Sub)
+	ctx := context.WithValue(r.Context(), ctxKeyAuthKind, "jwt")
+	ctx = context.WithValue(ctx, ctxKeyRoles, c.Roles)
+	ctx = context.WithValue(ctx, ctxKeyMFA, slices.Contains(c.Amr, "mfa"))
+	if c.Sid != "" {
+		ctx = context.WithValue(ctx, ctxKeyLoginSession, c.Sid)
 	}
-	return claims.Sub, nil
+	return r.WithContext(ctx)
+}
+
+// GetLoginSessionID: sessão de login do access token ("" para tokens antigos).
+func GetLoginSessionID(r *http.Request) string {
+	s, _ := r.Context().Value(ctxKeyLoginSession).(string)
+	return s
+}
+
+// mfaVerified: o access token veio de uma sessão aberta com segundo fator.
+func mfaVerified(r *http.Request) bool {
+	v, _ := r.Context().Value(ctxKeyMFA).(bool)
+	return v
 }
 
-// verifyJWTHS256 valida assinatura e exp (se presente).
-func verifyJWTHS256(token, secret string) (*jwtClaims, error) {
+// verifyJWT valida assinatura (pelo alg/kid do header) e iss, aud, nbf, exp.
+func verifyJWT(token string) (*jwtClaims, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
 		return nil, errInvalidToken
@@ -76,11 +146,35 @@ func verifyJWTHS256(token, secret string) (*jwtClaims, error) {
 	if err != nil {
 		return nil, errInvalidToken
 	}
-	var header map[string]any
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
 	if err := json.Unmarshal(hb, &header); err != nil {
 		return nil, errInvalidToken
 	}
-	if alg, _ := header["alg"].(string); alg != "HS256" {
+
+	// signature
+	sig, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	signingInput := parts[0] + "." + parts[1]
+	switch header.Alg {
+	case "HS256":
+		if !acceptHS256() {
+			return nil, errInvalidToken
+		}
+		mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, errInvalidToken
+		}
+	case jwtkeys.RS256, jwtkeys.EdDSA:
+		if jwtKeys == nil || jwtKeys.Verify(header.Kid, header.Alg, signingInput, sig) != nil {
+			return nil, errInvalidToken
+		}
+	default:
 		return nil, errInvalidToken
 	}
 
@@ -93,62 +187,130 @@ func verifyJWTHS256(token, secret string) (*jwtClaims, error) {
 	if err := json.Unmarshal(pb, &claims); err != nil {
 		return nil, errInvalidToken
 	}
-	if claims.Sub == "" {
+	if claims.Sub == "" || claims.Iss != jwtIssuer() || !audienceHas(claims.Aud, jwtAudience()) {
 		return nil, errInvalidToken
 	}
-
-	// signature
-	sig, err := enc.DecodeString(parts[2])
-	if err != nil {
+	now := time.Now().Unix()
+	if claims.Nbf != nil && now+jwtLeeway < *claims.Nbf {
 		return nil, errInvalidToken
 	}
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(parts[0]))
-	mac.Write([]byte("."))
-	mac.Write([]byte(parts[1]))
-	if !hmac.Equal(sig, mac.Sum(nil)) {
+	// sem exp o token valeria para sempre: recusado
+	if claims.Exp == nil || now > *claims.Exp {
 		return nil, errInvalidToken
 	}
+	return &claims, nil
+}
 
-	// exp
-	if claims.Exp != nil {
-		now := time.Now().Unix()
-		if now > *claims.Exp {
-			return nil, errInvalidToken
+func audienceHas(raw json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return one == want
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, a := range many {
+			if a == want {
+				return true
+			}
 		}
 	}
-	return &claims, nil
+	return false
 }
 
-// IssueJWTHS256 emite um JWT assinado (HS256) com sub, iat e exp.
+// IssueJWTHS256 emite um JWT HS256 com o segredo dado (modo legado/migração).
 // Retorna token e expiresIn (segundos) calculado a partir de ttlHours.
 func IssueJWTHS256(sub string, ttlHours int, secret string) (string, int64, error) {
-    if sub == "" || secret == "" || ttlHours <= 0 {
-        return "", 0, errInvalidToken
-    }
-    enc := base64.RawURLEncoding
-
-    header := map[string]any{"alg": "HS256", "typ": "JWT"}
-    now := time.Now().Unix()
-    exp := time.Now().Add(time.Duration(ttlHours) * time.Hour).Unix()
-    // random jti to ensure refreshed tokens differ even within same second
-    jti := make([]byte, 12)
-    _, _ = rand.Read(jti)
-    payload := map[string]any{
-        "sub": sub,
-        "iat": now,
-        "exp": exp,
-        "jti": base64.RawURLEncoding.EncodeToString(jti),
-    }
-
-    hb, _ := json.Marshal(header)
-    pb, _ := json.Marshal(payload)
-
-    seg := enc.EncodeToString(hb) + "." + enc.EncodeToString(pb)
-    mac := hmac.New(sha256.New, []byte(secret))
-    mac.Write([]byte(seg))
-    sig := mac.Sum(nil)
-
-    token := seg + "." + enc.EncodeToString(sig)
-    return token, exp - now, nil
+	if secret == "" || ttlHours <= 0 {
+		return "", 0, errInvalidToken
+	}
+	return issueJWT(sub, "", time.Duration(ttlHours)*time.Hour, nil, nil, nil, secret)
+}
+
+// IssueAccessToken emite o access token com a chave de assinatura do keyset
+// (ou HS256/JWT_SECRET sem keyset); sid liga o token à sessão de login.
+func IssueAccessToken(sub, sid string, ttl time.Duration, roles ...string) (string, int64, error) {
+	return issueAccessToken(sub, sid, ttl, roles, nil)
+}
+
+// issueAccessToken: como IssueAccessToken, com os métodos de autenticação (amr).
+func issueAccessToken(sub, sid string, ttl time.Duration, roles, amr []string) (string, int64, error) {
+	if jwtKeys != nil {
+		return issueJWT(sub, sid, ttl, roles, amr, jwtKeys.SigningKey(), "")
+	}
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", 0, errInvalidToken
+	}
+	return issueJWT(sub, sid, ttl, roles, amr, nil, secret)
+}
+
+// issueJWT assina com key (RS256/EdDSA) ou, se nil, HS256 com secret.
+func issueJWT(sub, sid string, ttl time.Duration, roles, amr []string, key *jwtkeys.Key, secret string) (string, int64, error) {
+	if sub == "" || ttl <= 0 {
+		return "", 0, errInvalidToken
+	}
+	enc := base64.RawURLEncoding
+
+	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+	if key != nil {
+		header["alg"], header["kid"] = key.Alg, key.ID
+	}
+	now := time.Now().Unix()
+	exp := time.Now().Add(ttl).Unix()
+	// random jti to ensure refreshed tokens differ even within same second
+	jti := make([]byte, 12)
+	_, _ = rand.Read(jti)
+	payload := map[string]any{
+		"sub": sub,
+		"iss": jwtIssuer(),
+		"aud": jwtAudience(),
+		"iat": now,
+		"nbf": now,
+		"exp": exp,
+		"jti": enc.EncodeToString(jti),
+	}
+	if sid != "" {
+		payload["sid"] = sid
+	}
+	if len(roles) > 0 {
+		payload["roles"] = roles
+	}
+	if len(amr) > 0 {
+		payload["amr"] = amr
+	}
+
+	hb, _ := json.Marshal(header)
+	pb, _ := json.Marshal(payload)
+	seg := enc.EncodeToString(hb) + "." + enc.EncodeToString(pb)
+
+	var sig []byte
+	if key != nil {
+		var err error
+		if sig, err = key.Sign(seg); err != nil {
+			return "", 0, err
+		}
+	} else {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(seg))
+		sig = mac.Sum(nil)
+	}
+	return seg + "." + enc.EncodeToString(sig), exp - now, nil
+}
+
+// JWKS: GET /.well-known/jwks.json (chaves públicas do keyset; vazio sem keyset).
+func JWKS() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		b, err := jwtKeys.JWKS()
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		_, _ = w.Write(b)
+	})
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+    "crypto/subtle"
     "database/sql"
     "encoding/json"
     "net/http"
@@ -13,14 +14,9 @@ type loginRequest struct {
     Password string `json:"password"`
 }
 
-type loginResponse struct {
-    AccessToken string `json:"access_token"`
-    ExpiresIn   int64  `json:"expires_in"`
-    TokenType   string `json:"token_type"`
-}
-
 // AuthLogin: POST /api/auth/login
-// Valida email/senha no Postgres (pgcrypto) e retorna JWT.
+// Valida email/senha no Postgres (pgcrypto) e abre uma sessão de login
+// (ou devolve o desafio de 2FA, se ativo).
 func AuthLogin(db *sql.DB) http.HandlerFunc {
     return func(w http.ResponseWriter, r *http.Request) {
         if r.Method != http.MethodPost {
@@ -39,38 +35,84 @@ func AuthLogin(db *sql.DB) http.HandlerFunc {
             return
         }
 
-        // Verifica credenciais usando pgcrypto (crypt) no PostgreSQL
-        var userID string
-        err := db.QueryRow(`
-            SELECT id::text
-            FROM users
-            WHERE email = $1 AND password_hash IS NOT NULL AND password_hash = crypt($2, password_hash)
-        `, email, in.Password).Scan(&userID)
-        if err == sql.ErrNoRows {
-            http.Error(w, "invalid credentials", http.StatusUnauthorized)
+        // Backoff/bloqueio por conta e por IP (ver login_security.go): a tentativa
+        // é contada antes de conferir a senha e devolvida se der certo
+        ctx := r.Context()
+        acctKey, ipKey := acctThrottleKey(email), ipThrottleKey(clientIP(r))
+        hits, wait, err := reserveAttempt(ctx, db,
+            throttleKey{acctKey, LoginThrottleFromEnv()}, throttleKey{ipKey, loginIPThrottle()})
+        if err != nil {
+            internalErr(w, err)
+            return
+        }
+        if wait > 0 {
+            recordSecurityEvent(ctx, db, r, "login_blocked", "", email,
+                map[string]any{"retry_after_sec": int(wait.Seconds())})
+            writeTooManyAttempts(w, wait)
             return
         }
+
+        // Verifica credenciais usando pgcrypto (crypt) no PostgreSQL.
+        // E-mail inexistente (ou sem senha) roda crypt com um salt fixo de mesmo
+        // custo: o tempo de resposta não revela se a conta existe.
+        var userID, pwHash, computed string
+        var verifiedAt sql.NullTime
+        err = db.QueryRowContext(ctx, `
+            SELECT COALESCE(u.id::text, ''), u.email_verified_at, COALESCE(u.password_hash, ''),
+                   crypt($2, COALESCE(u.password_hash, $3))
+            FROM (SELECT 1) AS one
+            LEFT JOIN users u ON u.email = $1
+        `, email, in.Password, dummyBcryptSalt).Scan(&userID, &verifiedAt, &pwHash, &computed)
         if err != nil {
             internalErr(w, err)
             return
         }
+        if pwHash == "" || subtle.ConstantTimeCompare([]byte(pwHash), []byte(computed)) != 1 {
+            reason := "bad_password"
+            if userID == "" {
+                reason = "unknown_email"
+            }
+            loginFailed(ctx, db, r, userID, email, reason, hits[0], hits[1])
+            http.Error(w, "invalid credentials", http.StatusUnauthorized)
+            return
+        }
+        if err := clearLoginThrottle(ctx, db, acctKey); err != nil {
+            internalErr(w, err)
+            return
+        }
+        if err := refundAttempt(ctx, db, ipKey, hits[1]); err != nil {
+            internalErr(w, err)
+            return
+        }
 
-        secret := os.Getenv("JWT_SECRET")
-        if secret == "" {
-            internalErr(w, errInvalidToken)
+        // AUTH_REQUIRE_VERIFIED=true: só entra quem confirmou o e-mail
+        if !verifiedAt.Valid && strings.EqualFold(os.Getenv("AUTH_REQUIRE_VERIFIED"), "true") {
+            jsonWrite(w, http.StatusForbidden, map[string]string{"error": "email not verified"})
             return
         }
-        ttl := atoiEnvInt("JWT_EXP_HOURS", 24)
-        token, expIn, err := IssueJWTHS256(userID, ttl, secret)
+
+        // 2FA ativo: devolve só o desafio; o par sai em /api/auth/mfa (ver mfa.go)
+        enabled, err := totpEnabled(ctx, db, userID)
         if err != nil {
             internalErr(w, err)
             return
         }
+        if enabled {
+            ch, err := issueMFAChallenge(ctx, db, userID)
+            if err != nil {
+                internalErr(w, err)
+                return
+            }
+            jsonWrite(w, http.StatusOK, ch)
+            return
+        }
 
-        jsonWrite(w, http.StatusOK, loginResponse{
-            AccessToken: token,
-            ExpiresIn:   expIn,
-            TokenType:   "Bearer",
-        })
+        // access token curto + refresh token rotativo (ver auth_sessions.go)
+        pair, err := startLoginSession(ctx, db, r, userID, false)
+        if err != nil {
+            internalErr(w, err)
+            return
+        }
+        jsonWrite(w, http.StatusOK, pair)
     }
 }
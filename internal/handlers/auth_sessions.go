@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sessões de login e refresh tokens.
+// Login cria uma sessão (dispositivo) com um refresh token opaco; cada
+// /api/auth/refresh troca o token por um novo (rotação). Apresentar um token
+// já trocado é sinal de vazamento: a sessão inteira é revogada.
+// Access tokens são curtos (ACCESS_TOKEN_TTL_MIN) e levam o id da sessão em "sid".
+
+var errRefreshInvalid = errors.New("invalid refresh token")
+
+func accessTokenTTL() time.Duration {
+	return time.Duration(atoiEnvInt("ACCESS_TOKEN_TTL_MIN", 15)) * time.Minute
+}
+
+func refreshTokenTTL() time.Duration {
+	return time.Duration(atoiEnvInt("REFRESH_TOKEN_TTL_DAYS", 30)) * 24 * time.Hour
+}
+
+type tokenPair struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
+	// papéis retidos até a sessão ter segundo fator (MFA_REQUIRED_ROLES)
+	RestrictedRoles []string `json:"restricted_roles,omitempty"`
+}
+
+// insertRefreshToken grava um novo refresh token na sessão (dentro de tx).
+func insertRefreshToken(ctx context.Context, tx *sql.Tx, sessionID string) (string, error) {
+	token, hash, err := newAuthToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, now() + $3 * interval '1 second')`,
+		sessionID, hash, int64(refreshTokenTTL()/time.Second))
+	return token, err
+}
+
+func userAgent(r *http.Request) string {
+	ua := strings.TrimSpace(r.UserAgent())
+	if len(ua) > 300 {
+		ua = ua[:300]
+	}
+	return ua
+}
+
+// startLoginSession cria a sessão de login e devolve o par de tokens;
+// mfa marca a sessão como aberta com segundo fator.
+func startLoginSession(ctx context.Context, db *sql.DB, r *http.Request, userID string, mfa bool) (*tokenPair, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var sid string
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO login_sessions (user_id, user_agent, ip, mfa)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		RETURNING id::text`, userID, userAgent(r), clientIP(r), mfa).Scan(&sid); err != nil {
+		return nil, err
+	}
+	refresh, err := insertRefreshToken(ctx, tx, sid)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return issuePair(ctx, db, userID, sid, refresh)
+}
+
+func issuePair(ctx context.Context, db *sql.DB, userID, sid, refresh string) (*tokenPair, error) {
+	roles, err := loadUserRoles(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	var mfa bool
+	if err := db.QueryRowContext(ctx, `SELECT mfa FROM login_sessions WHERE id = $1`, sid).Scan(&mfa); err != nil {
+		return nil, err
+	}
+	amr := []string{"pwd"}
+	if mfa {
+		amr = append(amr, "mfa")
+	}
+	roles, withheld := effectiveRoles(roles, mfa)
+	access, expIn, err := issueAccessToken(userID, sid, accessTokenTTL(), roles, amr)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenPair{
+		AccessToken:      access,
+		ExpiresIn:        expIn,
+		TokenType:        "Bearer",
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int64(refreshTokenTTL() / time.Second),
+		RestrictedRoles:  withheld,
+	}, nil
+}
+
+// rotateRefreshToken troca o token apresentado por um novo na mesma sessão.
+func rotateRefreshToken(ctx context.Context, db *sql.DB, r *http.Request, token string) (*tokenPair, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		tokID     int64
+		sid, uid  string
+		rotated   sql.NullTime
+		revoked   sql.NullTime
+		expiresAt time.Time
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT t.id, t.session_id::text, t.rotated_at, t.expires_at, s.user_id::text, s.revoked_at
+		FROM refresh_tokens t
+		JOIN login_sessions s ON s.id = t.session_id
+		WHERE t.token_hash = $1
+		FOR UPDATE OF t, s`, hashAuthToken(token)).Scan(&tokID, &sid, &rotated, &expiresAt, &uid, &revoked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errRefreshInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if revoked.Valid {
+		return nil, errRefreshInvalid
+	}
+	if rotated.Valid {
+		// reuso: alguém guardou um token antigo; derruba a família inteira
+		if err := revokeLoginSessions(ctx, tx, `id = $2`, "refresh_reuse", sid); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		recordSecurityEvent(ctx, db, r, "refresh_reuse", uid, "", map[string]any{"session_id": sid})
+		return nil, errRefreshInvalid
+	}
+	if time.Now().After(expiresAt) {
+		return nil, errRefreshInvalid
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET rotated_at = now() WHERE id = $1`, tokID); err != nil {
+		return nil, err
+	}
+	refresh, err := insertRefreshToken(ctx, tx, sid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE login_sessions
+		SET last_used_at = now(), ip = COALESCE(NULLIF($2, ''), ip), user_agent = COALESCE(NULLIF($3, ''), user_agent)
+		WHERE id = $1`, sid, clientIP(r), userAgent(r)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return issuePair(ctx, db, uid, sid, refresh)
+}
+
+// execer: *sql.DB ou *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// revokeLoginSessions revoga as sessões ativas que casam com where
+// ($1 = motivo; $2.. = args).
+func revokeLoginSessions(ctx context.Context, ex execer, where, reason string, args ...any) error {
+	_, err := ex.ExecContext(ctx, `
+		UPDATE login_sessions SET revoked_at = now(), revoked_reason = $1
+		WHERE revoked_at IS NULL AND `+where, append([]any{reason}, args...)...)
+	return err
+}
+
+// AuthRefresh: POST /api/auth/refresh {refresh_token}
+func AuthRefresh(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var in struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.RefreshToken) == "" {
+			badRequest(w, "invalid json or refresh_token missing")
+			return
+		}
+		pair, err := rotateRefreshToken(r.Context(), db, r, in.RefreshToken)
+		if errors.Is(err, errRefreshInvalid) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		jsonWrite(w, http.StatusOK, pair)
+	}
+}
+
+// AuthLogout: POST /api/auth/logout {refresh_token, all?}
+// Revoga a sessão do token (all=true: todas as sessões do usuário). Idempotente.
+func AuthLogout(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var in struct {
+			RefreshToken string `json:"refresh_token"`
+			All          bool   `json:"all"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.RefreshToken) == "" {
+			badRequest(w, "invalid json or refresh_token missing")
+			return
+		}
+		var sid, uid string
+		err := db.QueryRowContext(r.Context(), `
+			SELECT s.id::text, s.user_id::text
+			FROM refresh_tokens t JOIN login_sessions s ON s.id = t.session_id
+			WHERE t.token_hash = $1`, hashAuthToken(in.RefreshToken)).Scan(&sid, &uid)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if in.All {
+			err = revokeLoginSessions(r.Context(), db, `user_id = $2`, "logout_all", uid)
+		} else {
+			err = revokeLoginSessions(r.Context(), db, `id = $2`, "logout", sid)
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type loginSession struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+// MeSessions:
+//
+//	GET    /api/me/sessions       -> sessões ativas (dispositivos logados)
+//	DELETE /api/me/sessions       -> revoga todas menos a atual
+//	DELETE /api/me/sessions/{id}  -> revoga uma sessão
+func MeSessions(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := GetUserID(r)
+		if uid == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		current := GetLoginSessionID(r)
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/sessions"), "/")
+
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			rows, err := db.QueryContext(r.Context(), `
+				SELECT s.id::text, COALESCE(s.user_agent, ''), COALESCE(s.ip, ''), s.created_at, s.last_used_at
+				FROM login_sessions s
+				WHERE s.user_id = $1 AND s.revoked_at IS NULL
+				  AND EXISTS (SELECT 1 FROM refresh_tokens t
+				              WHERE t.session_id = s.id AND t.rotated_at IS NULL AND t.expires_at > now())
+				ORDER BY s.last_used_at DESC`, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			defer rows.Close()
+			out := []loginSession{}
+			for rows.Next() {
+				var s loginSession
+				if err := rows.Scan(&s.ID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt); err != nil {
+					internalErr(w, err)
+					return
+				}
+				s.Current = s.ID == current
+				out = append(out, s)
+			}
+			if err := rows.Err(); err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, map[string]any{"sessions": out})
+
+		case r.Method == http.MethodDelete && id == "":
+			// sem sid (token antigo) não há "atual": revoga todas
+			err := revokeLoginSessions(r.Context(), db, `user_id = $2 AND id::text <> $3`, "revoked_by_user", uid, current)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case r.Method == http.MethodDelete:
+			res, err := db.ExecContext(r.Context(), `
+				UPDATE login_sessions SET revoked_at = now(), revoked_reason = 'revoked_by_user'
+				WHERE id::text = $1 AND user_id = $2 AND revoked_at IS NULL`, id, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				notFound(w)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
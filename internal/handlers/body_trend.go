@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+)
+
+// Peso de tendência: média móvel exponencial diária sobre user_metrics.weight_kg
+// (α = 0.1/dia, corrigido pelo intervalo entre pesagens). Filtra a oscilação
+// de água/sal e é o peso usado pelo gerador e nas notas do coach.
+
+const (
+	weightEMAAlpha  = 0.1
+	weightRateDays  = 28 // janela da taxa semanal
+	weightRateMinPt = 3
+)
+
+type weightTrendPoint struct {
+	Day      string  `json:"day"`
+	WeightKG float64 `json:"weight_kg"`
+	TrendKG  float64 `json:"trend_kg"`
+}
+
+type weightTrend struct {
+	TrendKG       float64            `json:"trend_kg"`
+	LastKG        float64            `json:"last_kg"`
+	LastAt        string             `json:"last_at"`
+	WeeklyRateKG  *float64           `json:"weekly_rate_kg,omitempty"`
+	WeeklyRatePct *float64           `json:"weekly_rate_pct,omitempty"`
+	Points        int                `json:"points"`
+	Series        []weightTrendPoint `json:"series,omitempty"`
+}
+
+// emaSeries aplica a EMA com α ajustado ao número de dias entre pontos
+// (ordenados por dia).
+func emaSeries(days []time.Time, ws []float64) []float64 {
+	out := make([]float64, len(ws))
+	for i := range ws {
+		if i == 0 {
+			out[i] = ws[i]
+			continue
+		}
+		gap := math.Max(days[i].Sub(days[i-1]).Hours()/24, 1)
+		a := 1 - math.Pow(1-weightEMAAlpha, gap)
+		out[i] = out[i-1] + a*(ws[i]-out[i-1])
+	}
+	return out
+}
+
+// computeWeightTrend: nil sem pesagens; taxa semanal = inclinação da tendência
+// nos últimos 28 dias (precisa de ≥3 pontos cobrindo ≥7 dias).
+func computeWeightTrend(days []time.Time, ws []float64) *weightTrend {
+	if len(ws) == 0 {
+		return nil
+	}
+	ema := emaSeries(days, ws)
+	last := len(ws) - 1
+	t := &weightTrend{
+		TrendKG: round2(ema[last]),
+		LastKG:  ws[last],
+		LastAt:  days[last].Format("2006-01-02"),
+		Points:  len(ws),
+		Series:  make([]weightTrendPoint, len(ws)),
+	}
+	for i := range ws {
+		t.Series[i] = weightTrendPoint{Day: days[i].Format("2006-01-02"), WeightKG: ws[i], TrendKG: round2(ema[i])}
+	}
+
+	cut := days[last].AddDate(0, 0, -weightRateDays)
+	var xs, ys []float64
+	for i := range ws {
+		if days[i].Before(cut) {
+			continue
+		}
+		xs = append(xs, days[i].Sub(cut).Hours()/24)
+		ys = append(ys, ema[i])
+	}
+	if len(xs) >= weightRateMinPt && xs[len(xs)-1]-xs[0] >= 7 {
+		kg := round2(linreg(xs, ys) * 7)
+		pct := round2(kg / t.TrendKG * 100)
+		t.WeeklyRateKG, t.WeeklyRatePct = &kg, &pct
+	}
+	return t
+}
+
+// loadWeightTrend lê as pesagens dos últimos `days` dias (+30 de aquecimento da EMA).
+func loadWeightTrend(ctx context.Context, db *sql.DB, uid string, days int) (*weightTrend, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT measured_at, weight_kg::float8
+		FROM user_metrics
+		WHERE user_id = $1
+		  AND weight_kg IS NOT NULL
+		  AND measured_at >= CURRENT_DATE - $2::int
+		ORDER BY measured_at
+	`, uid, days+30)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var (
+		ds []time.Time
+		ws []float64
+	)
+	for rows.Next() {
+		var (
+			d time.Time
+			v float64
+		)
+		if err := rows.Scan(&d, &v); err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+		ws = append(ws, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	t := computeWeightTrend(ds, ws)
+	if t != nil {
+		// recorta o aquecimento da série exibida
+		from := time.Now().UTC().AddDate(0, 0, -days).Format("2006-01-02")
+		i := 0
+		for i < len(t.Series) && t.Series[i].Day < from {
+			i++
+		}
+		t.Series = t.Series[i:]
+	}
+	return t, nil
+}
+
+// applyWeightTrend troca o peso do perfil pelo peso de tendência (quando há pesagens).
+func applyWeightTrend(ctx context.Context, db *sql.DB, uid string, p *userProfile) {
+	t, err := loadWeightTrend(ctx, db, uid, 90)
+	if err != nil || t == nil {
+		return
+	}
+	p.WeightKG = &t.TrendKG
+	p.WeeklyRateKG = t.WeeklyRateKG
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// IP do cliente: o par da conexão (RemoteAddr). X-Forwarded-For só é usado
+// quando a conexão vem de um proxy listado em TRUSTED_PROXIES (IPs ou CIDRs,
+// separados por vírgula); aí vale o último endereço da cadeia que não é proxy.
+// Sem TRUSTED_PROXIES o cabeçalho é ignorado (qualquer cliente pode forjá-lo).
+func clientIP(r *http.Request) string {
+	peer := remoteHost(r.RemoteAddr)
+	trusted := trustedProxies()
+	if len(trusted) == 0 || !ipIn(peer, trusted) {
+		return peer
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		h := strings.TrimSpace(hops[i])
+		if net.ParseIP(h) == nil {
+			break // entrada inválida: não dá para confiar no resto da cadeia
+		}
+		if !ipIn(h, trusted) {
+			return h
+		}
+	}
+	return peer
+}
+
+// ClientIP exportado pros testes
+func ClientIP(r *http.Request) string { return clientIP(r) }
+
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func trustedProxies() []*net.IPNet {
+	var out []*net.IPNet
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			if strings.Contains(p, ":") {
+				p += "/128"
+			} else {
+				p += "/32"
+			}
+		}
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func ipIn(s string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"anima/internal/ai"
+)
+
+// Provider das notas do coach (heurística por padrão; ver ai.ProviderFromEnv).
+var coachProvider ai.CoachProvider = ai.Heuristic{}
+
+func SetCoachProvider(p ai.CoachProvider) {
+	if p == nil {
+		p = ai.Heuristic{}
+	}
+	coachProvider = p
+}
+
+func coachInput(req GenerateReq, p userProfile, exs []GeneratedExercise) ai.CoachInput {
+	in := ai.CoachInput{
+		Goal:         req.Objetivo,
+		Level:        req.Nivel,
+		Split:        req.Divisao,
+		HeightCm:     p.HeightCM,
+		WeightKg:     p.WeightKG,
+		WeeklyRateKg: p.WeeklyRateKG,
+		Age:          p.age(time.Now()),
+	}
+	if in.Goal == "" && p.TrainingGoal != nil {
+		in.Goal = *p.TrainingGoal
+	}
+	if in.Level == "" && p.ExperienceLevel != nil {
+		in.Level = *p.ExperienceLevel
+	}
+	for _, e := range exs {
+		in.Exercises = append(in.Exercises, e.Nome)
+	}
+	return in
+}
+
+// buildCoachNotes: "" se o usuário desligou use_ai; cada chamada fica em generations.
+func buildCoachNotes(ctx context.Context, db *sql.DB, uid string, req GenerateReq, p userProfile, exs []GeneratedExercise) string {
+	if p.UseAI != nil && !*p.UseAI {
+		return ""
+	}
+	in := coachInput(req, p, exs)
+	in.Lang = string(langOf(ctx))
+	start := time.Now()
+	res, err := coachProvider.CoachNotes(ctx, in)
+	if err != nil {
+		// provider sem fallback: heurística direto
+		res, _ = ai.Heuristic{}.CoachNotes(ctx, in)
+		res.Fallback, res.Error = true, err.Error()
+	}
+	recordGeneration(ctx, db, uid, in, res, time.Since(start))
+	return res.Notes
+}
+
+// recordGeneration grava a chamada em generations (defensivo: só loga em erro).
+func recordGeneration(ctx context.Context, db *sql.DB, uid string, in ai.CoachInput, res ai.CoachResult, took time.Duration) {
+	input, _ := json.Marshal(in)
+	output, _ := json.Marshal(map[string]any{
+		"notes":      res.Notes,
+		"provider":   res.Provider,
+		"fallback":   res.Fallback,
+		"error":      res.Error,
+		"latency_ms": took.Milliseconds(),
+	})
+	model := res.Model
+	if model == "" {
+		model = res.Provider
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO generations (user_id, input_json, output_json, prompt_version, model)
+		VALUES (CASE WHEN $1 ~* '^[0-9a-f-]{36}$' THEN $1::uuid END, $2, $3, $4, $5)
+	`, uid, input, output, res.PromptVersion, model); err != nil {
+		log.Printf("[coach] record generation failed: %v", err)
+	}
+}
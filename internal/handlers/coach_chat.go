@@ -0,0 +1,556 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"anima/internal/ai"
+
+	"github.com/lib/pq"
+)
+
+// Chat com o coach. Threads/mensagens em coach_threads/coach_messages; o contexto
+// (perfil, sessões recentes, volume semanal, sugestões de overload) é montado a cada
+// pergunta. Quotas diárias (UTC) por usuário:
+//
+//	COACH_DAILY_MESSAGES (padrão 50)     — perguntas por dia
+//	COACH_DAILY_TOKENS   (padrão 20000)  — tokens de entrada+saída por dia
+//
+// 0 desliga o limite. A pergunta é gravada (com tokens reservados) antes de
+// chamar o provider, numa transação serializada por usuário; pedidos paralelos
+// já enxergam a reserva e não furam a quota.
+
+const (
+	coachMaxMessageLen = 2000 // caracteres por pergunta
+	coachHistoryLimit  = 20   // mensagens anteriores enviadas ao provider
+	coachReplyReserve  = 500  // teto de tokens da resposta (max_tokens do provider)
+)
+
+// coachTokenReserve: estimativa (~4 caracteres/token) + teto da resposta;
+// corrigida pelo uso real depois da resposta.
+func coachTokenReserve(msg string) int {
+	return utf8.RuneCountInString(msg)/4 + 1 + coachReplyReserve
+}
+
+func coachQuota(key string, def int) int {
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key))); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+// coachResponder: o provider configurado se souber conversar; senão a heurística.
+func coachResponder() ai.ChatResponder {
+	if r, ok := coachProvider.(ai.ChatResponder); ok {
+		return r
+	}
+	return ai.Heuristic{}
+}
+
+type coachUsage struct {
+	MessagesToday int `json:"messages_today"`
+	TokensToday   int `json:"tokens_today"`
+	MessagesLimit int `json:"messages_limit"`
+	TokensLimit   int `json:"tokens_limit"`
+}
+
+// queryRower: *sql.DB ou *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func loadCoachUsage(ctx context.Context, db queryRower, uid string) (coachUsage, error) {
+	u := coachUsage{
+		MessagesLimit: coachQuota("COACH_DAILY_MESSAGES", 50),
+		TokensLimit:   coachQuota("COACH_DAILY_TOKENS", 20000),
+	}
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FILTER (WHERE role = 'user'), COALESCE(SUM(tokens), 0)
+		FROM coach_messages
+		WHERE user_id = $1 AND created_at >= date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+	`, uid).Scan(&u.MessagesToday, &u.TokensToday)
+	return u, err
+}
+
+func (u coachUsage) exceeded() string {
+	switch {
+	case u.MessagesLimit > 0 && u.MessagesToday >= u.MessagesLimit:
+		return "daily message quota exceeded"
+	case u.TokensLimit > 0 && u.TokensToday >= u.TokensLimit:
+		return "daily token quota exceeded"
+	}
+	return ""
+}
+
+// coachTurn: passos da admissão de uma pergunta. lock abre a seção crítica do
+// usuário (release(true) efetiva a reserva); load e reserve rodam dentro dela.
+type coachTurn struct {
+	lock    func() (release func(commit bool) error, err error)
+	load    func() (coachUsage, error)
+	reserve func() error
+}
+
+// admit: contagem e reserva na mesma seção crítica; msg != "" = quota esgotada.
+func (t coachTurn) admit() (coachUsage, string, error) {
+	release, err := t.lock()
+	if err != nil {
+		return coachUsage{}, "", err
+	}
+	u, err := t.load()
+	if err != nil {
+		_ = release(false)
+		return u, "", err
+	}
+	if msg := u.exceeded(); msg != "" {
+		return u, msg, release(false)
+	}
+	if err := t.reserve(); err != nil {
+		_ = release(false)
+		return u, "", err
+	}
+	return u, "", release(true)
+}
+
+// CoachUsage / AdmitCoachTurn exportados pros testes
+type CoachUsage = coachUsage
+
+func AdmitCoachTurn(lock func() (func(bool) error, error), load func() (CoachUsage, error), reserve func() error) (CoachUsage, string, error) {
+	return coachTurn{lock: lock, load: load, reserve: reserve}.admit()
+}
+
+// ===== contexto
+
+func loadChatContext(ctx context.Context, db *sql.DB, uid string) (ai.ChatContext, error) {
+	var c ai.ChatContext
+
+	p, err := loadUserProfile(ctx, db, uid)
+	if err != nil {
+		return c, err
+	}
+	applyWeightTrend(ctx, db, uid, &p)
+	c.Profile = coachInput(GenerateReq{}, p, nil)
+	c.Profile.Lang = string(langOf(ctx))
+
+	// últimas 5 sessões
+	rows, err := db.QueryContext(ctx, `
+		SELECT ws.started_at,
+		       COUNT(s.id) FILTER (WHERE s.completed AND NOT s.flagged),
+		       COALESCE(SUM(s.weight_kg * s.reps) FILTER (WHERE s.completed AND NOT s.flagged), 0)::float8,
+		       COALESCE(array_agg(DISTINCT e.name) FILTER (WHERE e.name IS NOT NULL), '{}')
+		FROM workout_sessions ws
+		LEFT JOIN workout_sets s ON s.session_id = ws.id
+		LEFT JOIN exercises e ON e.id = s.exercicio_id
+		WHERE ws.user_id = $1
+		GROUP BY ws.id, ws.started_at
+		ORDER BY ws.started_at DESC
+		LIMIT 5
+	`, uid)
+	if err != nil {
+		return c, err
+	}
+	for rows.Next() {
+		var (
+			at time.Time
+			s  ai.SessionSummary
+		)
+		if err := rows.Scan(&at, &s.Sets, &s.VolumeKg, pq.Array(&s.Exercises)); err != nil {
+			rows.Close()
+			return c, err
+		}
+		s.Date = utcDay(at)
+		s.VolumeKg = roundTo(s.VolumeKg, 1)
+		c.Sessions = append(c.Sessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return c, err
+	}
+
+	// séries válidas por grupo nos últimos 7 dias
+	rows, err = db.QueryContext(ctx, `
+		SELECT e.muscle_group, COUNT(*)
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		JOIN exercises e ON e.id = s.exercicio_id
+		WHERE ws.user_id = $1 AND s.completed AND NOT s.flagged
+		  AND ws.started_at >= NOW() - INTERVAL '7 days'
+		GROUP BY e.muscle_group
+	`, uid)
+	if err != nil {
+		return c, err
+	}
+	for rows.Next() {
+		var (
+			g string
+			n int
+		)
+		if err := rows.Scan(&g, &n); err != nil {
+			rows.Close()
+			return c, err
+		}
+		if c.WeeklySets == nil {
+			c.WeeklySets = map[string]int{}
+		}
+		c.WeeklySets[g] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return c, err
+	}
+
+	// última sugestão de overload por exercício (30 dias)
+	rows, err = db.QueryContext(ctx, `
+		SELECT DISTINCT ON (l.exercicio_id)
+		       COALESCE(e.name, '#' || l.exercicio_id::text), l.requested_at,
+		       l.avg_carga_kg::float8, l.suggested_carga_kg::float8, l.suggested_repeticoes
+		FROM overload_suggestions_log l
+		LEFT JOIN exercises e ON e.id = l.exercicio_id
+		WHERE l.user_id = $1 AND l.requested_at >= NOW() - INTERVAL '30 days'
+		ORDER BY l.exercicio_id, l.requested_at DESC
+		LIMIT 10
+	`, uid)
+	if err != nil {
+		return c, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			o       ai.OverloadNote
+			at      time.Time
+			last    sql.NullFloat64
+			suggest sql.NullFloat64
+			reps    sql.NullInt64
+		)
+		if err := rows.Scan(&o.Exercise, &at, &last, &suggest, &reps); err != nil {
+			return c, err
+		}
+		o.Date = utcDay(at)
+		if last.Valid {
+			o.LastKg = &last.Float64
+		}
+		if suggest.Valid {
+			o.SuggestKg = &suggest.Float64
+		}
+		if reps.Valid {
+			n := int(reps.Int64)
+			o.SuggestRep = &n
+		}
+		c.Overload = append(c.Overload, o)
+	}
+	return c, rows.Err()
+}
+
+// ===== threads
+
+type coachMessage struct {
+	ID        int64     `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Tokens    int       `json:"tokens"`
+	Provider  *string   `json:"provider,omitempty"`
+	Fallback  bool      `json:"fallback,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type coachThread struct {
+	ID        int64          `json:"id"`
+	Title     string         `json:"title"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Messages  []coachMessage `json:"messages,omitempty"`
+}
+
+func loadThreadMessages(ctx context.Context, db *sql.DB, threadID int64, limit int) ([]coachMessage, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, role, content, tokens, provider, fallback, created_at FROM (
+		  SELECT * FROM coach_messages WHERE thread_id = $1 ORDER BY id DESC LIMIT $2
+		) m ORDER BY id
+	`, threadID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []coachMessage{}
+	for rows.Next() {
+		var m coachMessage
+		if err := rows.Scan(&m.ID, &m.Role, &m.Content, &m.Tokens, &m.Provider, &m.Fallback, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// threadTitle: começo da primeira pergunta.
+func threadTitle(msg string) string {
+	t := strings.Join(strings.Fields(msg), " ")
+	if utf8.RuneCountInString(t) <= 60 {
+		return t
+	}
+	return string([]rune(t)[:57]) + "..."
+}
+
+type coachMessageReq struct {
+	ThreadID *int64 `json:"thread_id"`
+	Message  string `json:"message"`
+}
+
+// POST /api/coach/messages  {"thread_id": 12?, "message": "..."}
+func CoachMessages(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		uid := strings.TrimSpace(GetUserID(r))
+		if uid == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+		var in coachMessageReq
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			badRequest(w, "invalid JSON")
+			return
+		}
+		in.Message = strings.TrimSpace(in.Message)
+		if in.Message == "" {
+			badRequest(w, "message is required")
+			return
+		}
+		if utf8.RuneCountInString(in.Message) > coachMaxMessageLen {
+			badRequest(w, "message too long (max "+strconv.Itoa(coachMaxMessageLen)+" chars)")
+			return
+		}
+		ctx := r.Context()
+
+		// thread existente (do próprio usuário) ou nova
+		var (
+			threadID int64
+			history  []ai.Message
+		)
+		if in.ThreadID != nil {
+			threadID = *in.ThreadID
+			var ok bool
+			if err := db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM coach_threads WHERE id=$1 AND user_id=$2)`, threadID, uid).Scan(&ok); err != nil {
+				internalErr(w, err)
+				return
+			}
+			if !ok {
+				notFound(w)
+				return
+			}
+			prev, err := loadThreadMessages(ctx, db, threadID, coachHistoryLimit)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			for _, m := range prev {
+				history = append(history, ai.Message{Role: m.Role, Content: m.Content})
+			}
+		}
+
+		// quota: conta e grava a pergunta sob lock do usuário antes do provider
+		userMsg := coachMessage{Role: "user", Content: in.Message, Tokens: coachTokenReserve(in.Message)}
+		var tx *sql.Tx
+		usage, msg, err := coachTurn{
+			lock: func() (func(bool) error, error) {
+				var err error
+				if tx, err = db.BeginTx(ctx, nil); err != nil {
+					return nil, err
+				}
+				if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('coach-quota:' || $1))`, uid); err != nil {
+					_ = tx.Rollback()
+					return nil, err
+				}
+				return func(commit bool) error {
+					if commit {
+						return tx.Commit()
+					}
+					return tx.Rollback()
+				}, nil
+			},
+			load: func() (coachUsage, error) { return loadCoachUsage(ctx, tx, uid) },
+			reserve: func() error {
+				if threadID == 0 {
+					if err := tx.QueryRowContext(ctx, `
+						INSERT INTO coach_threads (user_id, title) VALUES ($1, $2) RETURNING id
+					`, uid, threadTitle(in.Message)).Scan(&threadID); err != nil {
+						return err
+					}
+				}
+				return tx.QueryRowContext(ctx, `
+					INSERT INTO coach_messages (thread_id, user_id, role, content, tokens)
+					VALUES ($1, $2, 'user', $3, $4) RETURNING id, created_at
+				`, threadID, uid, userMsg.Content, userMsg.Tokens).Scan(&userMsg.ID, &userMsg.CreatedAt)
+			},
+		}.admit()
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if msg != "" {
+			now := time.Now().UTC()
+			reset := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			w.Header().Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds())+1))
+			jsonWrite(w, http.StatusTooManyRequests, map[string]any{"error": msg, "usage": usage})
+			return
+		}
+
+		cctx, err := loadChatContext(ctx, db, uid)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		chatIn := ai.ChatInput{Context: cctx, History: history, Message: in.Message}
+		resp := coachResponder()
+		reply, err := resp.Reply(ctx, chatIn)
+		if err != nil {
+			// provider sem fallback: heurística direto
+			reply, _ = ai.Heuristic{}.Reply(ctx, chatIn)
+			reply.Fallback, reply.Error = true, err.Error()
+		}
+
+		// troca a reserva pelo uso real e grava a resposta
+		tx, err = db.BeginTx(ctx, nil)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer tx.Rollback()
+		userMsg.Tokens = reply.TokensIn
+		if _, err := tx.ExecContext(ctx, `UPDATE coach_messages SET tokens = $2 WHERE id = $1`, userMsg.ID, userMsg.Tokens); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE coach_threads SET updated_at = NOW() WHERE id = $1`, threadID); err != nil {
+			internalErr(w, err)
+			return
+		}
+		model := reply.Model
+		if model == "" {
+			model = reply.Provider
+		}
+		botMsg := coachMessage{Role: "assistant", Content: reply.Content, Tokens: reply.TokensOut, Provider: &reply.Provider, Fallback: reply.Fallback}
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO coach_messages (thread_id, user_id, role, content, tokens, provider, model, prompt_version, fallback)
+			VALUES ($1, $2, 'assistant', $3, $4, $5, $6, $7, $8) RETURNING id, created_at
+		`, threadID, uid, botMsg.Content, botMsg.Tokens, reply.Provider, model, reply.PromptVersion, reply.Fallback).Scan(&botMsg.ID, &botMsg.CreatedAt); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			internalErr(w, err)
+			return
+		}
+
+		usage.MessagesToday++
+		usage.TokensToday += reply.TokensIn + reply.TokensOut
+		jsonWrite(w, http.StatusCreated, map[string]any{
+			"thread_id": threadID,
+			"message":   userMsg,
+			"reply":     botMsg,
+			"usage":     usage,
+		})
+	})
+}
+
+// CoachThreads:
+//
+//	GET    /api/coach/threads        — lista (mais recentes primeiro) + uso do dia
+//	GET    /api/coach/threads/{id}   — thread com as mensagens
+//	DELETE /api/coach/threads/{id}
+func CoachThreads(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := strings.TrimSpace(GetUserID(r))
+		if uid == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+		ctx := r.Context()
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/coach/threads"), "/")
+
+		if rest == "" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			limit := clampInt(parseInt(r.URL.Query().Get("limit"), 20), 1, 100)
+			rows, err := db.QueryContext(ctx, `
+				SELECT id, title, created_at, updated_at FROM coach_threads
+				WHERE user_id = $1 ORDER BY updated_at DESC LIMIT $2
+			`, uid, limit)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			defer rows.Close()
+			items := []coachThread{}
+			for rows.Next() {
+				var t coachThread
+				if err := rows.Scan(&t.ID, &t.Title, &t.CreatedAt, &t.UpdatedAt); err != nil {
+					internalErr(w, err)
+					return
+				}
+				items = append(items, t)
+			}
+			if err := rows.Err(); err != nil {
+				internalErr(w, err)
+				return
+			}
+			usage, err := loadCoachUsage(ctx, db, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, map[string]any{"items": items, "usage": usage})
+			return
+		}
+
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil || id <= 0 {
+			badRequest(w, "invalid thread id")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			var t coachThread
+			err := db.QueryRowContext(ctx, `
+				SELECT id, title, created_at, updated_at FROM coach_threads WHERE id = $1 AND user_id = $2
+			`, id, uid).Scan(&t.ID, &t.Title, &t.CreatedAt, &t.UpdatedAt)
+			if errors.Is(err, sql.ErrNoRows) {
+				notFound(w)
+				return
+			}
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if t.Messages, err = loadThreadMessages(ctx, db, id, 500); err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, t)
+		case http.MethodDelete:
+			res, err := db.ExecContext(ctx, `DELETE FROM coach_threads WHERE id = $1 AND user_id = $2`, id, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				notFound(w)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
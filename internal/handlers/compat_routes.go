@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Fonte única do DB neste package.
@@ -541,7 +543,8 @@ func SetsList(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := sessionsDB.Query(`
 		SELECT id, session_id, exercicio_id, set_index,
-		       weight_kg, reps, rir, completed, COALESCE(rest_sec,0), created_at
+		       weight_kg, reps, rir, completed, COALESCE(rest_sec,0), pain,
+		       flagged, COALESCE(flag_reasons, '{}'), created_at
 		FROM workout_sets
 		WHERE session_id = $1
 		ORDER BY set_index ASC, id ASC
@@ -562,6 +565,9 @@ func SetsList(w http.ResponseWriter, r *http.Request) {
 		RIR       *int     `json:"rir,omitempty"`
 		Completed bool     `json:"completed"`
 		RestSec   *int     `json:"rest_sec,omitempty"`
+		Pain      *int     `json:"pain,omitempty"`
+		Flagged   bool     `json:"flagged"`
+		Warnings  []string `json:"warnings,omitempty"`
 		CreatedAt string   `json:"created_at"`
 	}
 	var items []item
@@ -572,7 +578,8 @@ func SetsList(w http.ResponseWriter, r *http.Request) {
 		var rir sql.NullInt64
 		var rest sql.NullInt64
 		if err := rows.Scan(&it.ID, &it.SessionID, &it.Exercicio, &it.SetIndex,
-			&wkg, &reps, &rir, &it.Completed, &rest, &it.CreatedAt); err != nil {
+			&wkg, &reps, &rir, &it.Completed, &rest, &it.Pain,
+			&it.Flagged, pq.Array(&it.Warnings), &it.CreatedAt); err != nil {
 			internalErr(w, err)
 			return
 		}
@@ -665,6 +672,14 @@ func SetsCreate(w http.ResponseWriter, r *http.Request) {
 		badRequest(w, "exercicio_id required")
 		return
 	}
+	// exercícios privados só podem ser usados pelo dono
+	if ok, err := exerciseVisible(r.Context(), sessionsDB, int64(*exID), uid); err != nil {
+		internalErr(w, err)
+		return
+	} else if !ok {
+		badRequest(w, "exercicio_id not found")
+		return
+	}
 
 	setIdx, ok := getInt("set_index", "series")
 	if !ok || setIdx == nil || *setIdx <= 0 {
@@ -677,6 +692,11 @@ func SetsCreate(w http.ResponseWriter, r *http.Request) {
 	reps, _ := getInt("reps", "repeticoes")
 	rir, _ := getInt("rir")
 	rest, _ := getInt("rest_sec")
+	pain, _ := getInt("pain")
+	if pain != nil && (*pain < 0 || *pain > 10) {
+		badRequest(w, "pain must be between 0 and 10")
+		return
+	}
 
 	completed := false
 	if v, ok := body["completed"]; ok {
@@ -684,19 +704,51 @@ func SetsCreate(w http.ResponseWriter, r *http.Request) {
 			completed = b
 		}
 	}
+	confirm, _ := body["confirm"].(bool)
+
+	// anomalias vs histórico do usuário no exercício
+	warnings, err := setAnomalies(r.Context(), sessionsDB, uid, int64(*exID), weight, reps, rir, 0)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	if len(warnings) > 0 && !confirm && anomalyConfirmMode() {
+		jsonWrite(w, http.StatusConflict, map[string]any{
+			"error":    "confirmation required (resend with confirm=true)",
+			"warnings": warnings,
+		})
+		return
+	}
+	flagged := len(warnings) > 0 && !confirm
 
 	var id int64
 	err = sessionsDB.QueryRow(`
 		INSERT INTO workout_sets
-		  (session_id, exercicio_id, set_index, weight_kg, reps, rir, completed, rest_sec)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		  (session_id, exercicio_id, set_index, weight_kg, reps, rir, completed, rest_sec, pain,
+		   flagged, flag_reasons, confirmed_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11, CASE WHEN $12 THEN NOW() END)
 		RETURNING id
-	`, sessionID, *exID, *setIdx, weight, reps, rir, completed, rest).Scan(&id)
+	`, sessionID, *exID, *setIdx, weight, reps, rir, completed, rest, pain,
+		flagged, pq.Array(warnings), len(warnings) > 0 && confirm).Scan(&id)
 	if err != nil {
 		internalErr(w, err)
 		return
 	}
-	jsonWrite(w, http.StatusCreated, map[string]any{"id": id})
+	resp := map[string]any{"id": id}
+	if len(warnings) > 0 {
+		resp["warnings"] = warnings
+		resp["flagged"] = flagged
+	}
+	// próxima série recomendada (com reps + RIR); série suspeita não orienta a próxima
+	if !flagged {
+		if ns, err := nextSetFor(r.Context(), sessionsDB, id); err != nil {
+			internalErr(w, err)
+			return
+		} else if ns != nil {
+			resp["next_set"] = ns
+		}
+	}
+	jsonWrite(w, http.StatusCreated, resp)
 }
 
 func SetsPatch(w http.ResponseWriter, r *http.Request) {
@@ -765,26 +817,63 @@ func SetsPatch(w http.ResponseWriter, r *http.Request) {
 	if v, ok := body["rest_sec"]; ok {
 		sets = append(sets, field{"rest_sec", v})
 	}
+	if v, ok := body["pain"]; ok {
+		// dor 0–10 (null limpa)
+		if v != nil {
+			f, ok := v.(float64)
+			if !ok || f < 0 || f > 10 || f != float64(int(f)) {
+				badRequest(w, "pain must be an integer between 0 and 10")
+				return
+			}
+		}
+		sets = append(sets, field{"pain", v})
+	}
 
-	if len(sets) == 0 {
+	confirm, _ := body["confirm"].(bool)
+	if len(sets) == 0 && !confirm {
 		badRequest(w, "no updatable fields")
 		return
 	}
 
-	args := []any{}
-	sqlSet := make([]string, 0, len(sets))
-	for i, f := range sets {
-		sqlSet = append(sqlSet, f.col+" = $"+strconv.Itoa(i+1))
-		args = append(args, f.val)
+	id := setID
+	changed := false
+	if len(sets) > 0 {
+		args := []any{}
+		sqlSet := make([]string, 0, len(sets))
+		for i, f := range sets {
+			sqlSet = append(sqlSet, f.col+" = $"+strconv.Itoa(i+1))
+			args = append(args, f.val)
+		}
+		args = append(args, setID)
+		// self-join "old" = linha antes do UPDATE, p/ saber se carga/reps/rir mudaram
+		q := `UPDATE workout_sets s SET ` + strings.Join(sqlSet, ", ") + ` FROM workout_sets old` +
+			` WHERE s.id = $` + strconv.Itoa(len(args)) + ` AND old.id = s.id RETURNING s.id, ` + setValuesChanged
+		if err := sessionsDB.QueryRow(q, args...).Scan(&id, &changed); err != nil {
+			internalErr(w, err)
+			return
+		}
 	}
-	args = append(args, setID)
-	q := `UPDATE workout_sets SET ` + strings.Join(sqlSet, ", ") + ` WHERE id = $` + strconv.Itoa(len(args)) + ` RETURNING id`
-	var id int64
-	if err := sessionsDB.QueryRow(q, args...).Scan(&id); err != nil {
+
+	// reavalia anomalias com os valores gravados (confirm libera a série)
+	warnings, flagged, err := recheckSet(r.Context(), sessionsDB, id, confirm, changed)
+	if err != nil {
 		internalErr(w, err)
 		return
 	}
-	jsonWrite(w, http.StatusOK, map[string]any{"id": id})
+	resp := map[string]any{"id": id}
+	if len(warnings) > 0 {
+		resp["warnings"] = warnings
+		resp["flagged"] = flagged
+	}
+	if !flagged {
+		if ns, err := nextSetFor(r.Context(), sessionsDB, id); err != nil {
+			internalErr(w, err)
+			return
+		} else if ns != nil {
+			resp["next_set"] = ns
+		}
+	}
+	jsonWrite(w, http.StatusOK, resp)
 }
 
 func SetsDelete(w http.ResponseWriter, r *http.Request) {
@@ -1,17 +1,67 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"anima/internal/i18n"
+
+	"github.com/lib/pq"
 )
 
 type ExerciseItem struct {
-	ID    int64  `json:"id"`
-	Nome  string `json:"nome"`
-	Grupo string `json:"grupo"`
+	ID      int64  `json:"id"`
+	Nome    string `json:"nome"`
+	Grupo   string `json:"grupo"`
+	Privado bool   `json:"privado,omitempty"` // exercício personalizado do usuário
+}
+
+// exerciseNameSQL: nome do exercício no idioma (name_en cai para name).
+func exerciseNameSQL(alias string, l i18n.Lang) string {
+	if alias != "" {
+		alias += "."
+	}
+	if l == i18n.EN {
+		return "COALESCE(" + alias + "name_en, " + alias + "name)"
+	}
+	return alias + "name"
+}
+
+// localizeExerciseNames troca os nomes pelos do idioma (só busca no banco se não for pt-BR).
+func localizeExerciseNames(ctx context.Context, db *sql.DB, l i18n.Lang, exs []GeneratedExercise) {
+	if l == i18n.PT || len(exs) == 0 {
+		return
+	}
+	ids := make([]int64, len(exs))
+	for i, e := range exs {
+		ids[i] = int64(e.ExercicioID)
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, name_en FROM exercises WHERE id = ANY($1) AND name_en IS NOT NULL`, pq.Array(ids))
+	if err != nil {
+		log.Printf("[i18n] exercise names: %v", err)
+		return
+	}
+	defer rows.Close()
+	names := map[int]string{}
+	for rows.Next() {
+		var (
+			id int
+			n  string
+		)
+		if rows.Scan(&id, &n) == nil {
+			names[id] = n
+		}
+	}
+	for i := range exs {
+		if n, ok := names[exs[i].ExercicioID]; ok {
+			exs[i].Nome = n
+		}
+	}
 }
 
 type ListExercisesResp struct {
@@ -25,19 +75,21 @@ func ListExercises(db *sql.DB) http.Handler {
 
 		limit := clampInt(parseInt(r.URL.Query().Get("limit"), 100), 1, 500)
 
-		// Usa tabela EN e busca sem acento
-		base := `SELECT id, name AS nome, muscle_group AS grupo FROM exercises WHERE 1=1`
-		args := []any{}
+		// Usa tabela EN e busca sem acento (nome pt ou en); catálogo global + privados do usuário
+		nome := exerciseNameSQL("", requestLang(r))
+		base := `SELECT id, ` + nome + ` AS nome, muscle_group AS grupo, owner_user_id IS NOT NULL AS privado
+			FROM exercises WHERE ` + exerciseVisibleSQL("owner_user_id", 1)
+		args := []any{strings.TrimSpace(GetUserID(r))}
 
 		if q != "" {
-			base += ` AND unaccent(name) ILIKE unaccent(` + place(len(args)+1) + `)`
+			base += ` AND (unaccent(name) ILIKE unaccent(` + place(len(args)+1) + `) OR unaccent(COALESCE(name_en, '')) ILIKE unaccent(` + place(len(args)+1) + `))`
 			args = append(args, "%"+q+"%")
 		}
 		if grupo != "" {
 			base += ` AND lower(unaccent(muscle_group)) = lower(unaccent(` + place(len(args)+1) + `))`
 			args = append(args, grupo)
 		}
-		base += ` ORDER BY name LIMIT ` + strconv.Itoa(limit)
+		base += ` ORDER BY ` + nome + ` LIMIT ` + strconv.Itoa(limit)
 
 		rows, err := db.Query(base, args...)
 		if err != nil {
@@ -49,7 +101,7 @@ func ListExercises(db *sql.DB) http.Handler {
 		var items []ExerciseItem
 		for rows.Next() {
 			var it ExerciseItem
-			if err := rows.Scan(&it.ID, &it.Nome, &it.Grupo); err != nil {
+			if err := rows.Scan(&it.ID, &it.Nome, &it.Grupo, &it.Privado); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"anima/internal/i18n"
+)
+
+// ====== Fadiga e deload
+//
+// Sinais (últimas semanas, sets concluídos do usuário):
+//   - e1rm_decline: e1RM da sessão caindo em sessões consecutivas no mesmo exercício
+//   - rir_collapse: RIR médio recente perto de 0 vs. linha de base
+//   - rpe_rise:     RPE da sessão subindo com tonelagem igual ou menor
+// Dois sinais (ou queda de e1RM em 2+ exercícios) recomendam uma semana de deload.
+
+const (
+	deloadLoadFactor   = 0.85 // carga sugerida durante deload (fração da média recente)
+	deloadVolumeFactor = 0.5
+	fatigueMinDrops    = 2    // quedas seguidas de e1RM (3 sessões)
+	fatigueDropPct     = 0.01 // queda mínima considerada (1%)
+)
+
+type fatigueSignal struct {
+	Kind        string    `json:"kind"` // e1rm_decline | rir_collapse | rpe_rise
+	Detail      string    `json:"detail"`
+	ExercicioID *int64    `json:"exercicio_id,omitempty"`
+	Values      []float64 `json:"values,omitempty"`
+}
+
+type deloadPeriod struct {
+	ID           *int64  `json:"id,omitempty"` // ausente quando vem do programa
+	StartsOn     string  `json:"starts_on"`
+	EndsOn       string  `json:"ends_on"`
+	Reason       *string `json:"reason,omitempty"`
+	Source       string  `json:"source"` // manual | recommended | program
+	LoadFactor   float64 `json:"load_factor"`
+	VolumeFactor float64 `json:"volume_factor"`
+}
+
+type fatigueReport struct {
+	Score           int             `json:"score"`
+	RecommendDeload bool            `json:"recommend_deload"`
+	Recommendation  string          `json:"recommendation"`
+	Signals         []fatigueSignal `json:"signals"`
+	ActiveDeload    *deloadPeriod   `json:"active_deload,omitempty"`
+}
+
+// trailingDrops conta quedas consecutivas (> dropPct) no fim da série.
+func trailingDrops(vals []float64, dropPct float64) int {
+	n := 0
+	for i := len(vals) - 1; i > 0; i-- {
+		if vals[i] < vals[i-1]*(1-dropPct) {
+			n++
+			continue
+		}
+		break
+	}
+	return n
+}
+
+// fatigueData: séries já carregadas do banco; evalFatigue é a regra pura.
+type fatigueData struct {
+	E1RM      map[int64][]float64 // e1RM por sessão (ordem cronológica) por exercício
+	Order     []int64             // exercícios na ordem de leitura
+	RecentRIR *float64            // média dos últimos 14 dias
+	RecentN   int
+	BaseRIR   *float64 // média de 15–42 dias atrás
+	BaseN     int
+	RPE       []float64 // RPE das últimas sessões (cronológico)
+	Tonnage   []float64
+}
+
+// FatigueData / FatigueReport / EvalFatigue exportados pros testes
+type (
+	FatigueData   = fatigueData
+	FatigueReport = fatigueReport
+)
+
+func EvalFatigue(lang i18n.Lang, d FatigueData) FatigueReport { return evalFatigue(lang, d) }
+
+func evalFatigue(lang i18n.Lang, d fatigueData) fatigueReport {
+	rep := fatigueReport{Signals: []fatigueSignal{}}
+
+	declining := 0
+	for _, id := range d.Order {
+		vals := d.E1RM[id]
+		drops := trailingDrops(vals, fatigueDropPct)
+		if drops < fatigueMinDrops {
+			continue
+		}
+		declining++
+		exID := id
+		tail := vals[len(vals)-drops-1:]
+		rep.Signals = append(rep.Signals, fatigueSignal{
+			Kind:        "e1rm_decline",
+			Detail:      i18n.T(lang, "fatigue.e1rm_decline", drops),
+			ExercicioID: &exID,
+			Values:      tail,
+		})
+	}
+	if declining > 0 {
+		rep.Score++
+	}
+
+	if d.RecentN >= 6 && d.BaseN >= 6 && d.RecentRIR != nil && d.BaseRIR != nil &&
+		*d.RecentRIR <= 0.75 && *d.BaseRIR-*d.RecentRIR >= 1.0 {
+		rep.Score++
+		rep.Signals = append(rep.Signals, fatigueSignal{
+			Kind:   "rir_collapse",
+			Detail: i18n.T(lang, "fatigue.rir_collapse", *d.BaseRIR, *d.RecentRIR),
+			Values: []float64{roundTo(*d.BaseRIR, 0.1), roundTo(*d.RecentRIR, 0.1)},
+		})
+	}
+
+	if len(d.RPE) == 6 && len(d.Tonnage) == 6 {
+		rpeOld, rpeNew := mean(d.RPE[:3]), mean(d.RPE[3:])
+		tonOld, tonNew := mean(d.Tonnage[:3]), mean(d.Tonnage[3:])
+		if rpeNew-rpeOld >= 1.5 && tonOld > 0 && tonNew <= tonOld*1.1 {
+			rep.Score++
+			rep.Signals = append(rep.Signals, fatigueSignal{
+				Kind:   "rpe_rise",
+				Detail: i18n.T(lang, "fatigue.rpe_rise", rpeOld, rpeNew),
+				Values: []float64{roundTo(rpeOld, 0.1), roundTo(rpeNew, 0.1)},
+			})
+		}
+	}
+
+	rep.RecommendDeload = rep.Score >= 2 || declining >= 2
+	switch {
+	case rep.RecommendDeload:
+		rep.Recommendation = i18n.T(lang, "fatigue.rec.deload", deloadLoadFactor*100)
+	case rep.Score == 1:
+		rep.Recommendation = i18n.T(lang, "fatigue.rec.monitor")
+	default:
+		rep.Recommendation = i18n.T(lang, "fatigue.rec.none")
+	}
+	return rep
+}
+
+// detectFatigue: sinais das últimas semanas; day (YYYY-MM-DD local) só define o deload ativo.
+func detectFatigue(ctx context.Context, db *sql.DB, uid, day string) (fatigueReport, error) {
+	d := fatigueData{E1RM: map[int64][]float64{}}
+
+	// 1) e1RM por sessão e exercício (8 semanas)
+	rows, err := db.QueryContext(ctx, `
+		SELECT s.exercicio_id, MAX(`+e1rmSQL+`)::float8
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE ws.user_id = $1
+		  AND s.completed = TRUE
+		  AND NOT s.flagged
+		  AND s.weight_kg > 0
+		  AND s.reps BETWEEN 1 AND 12
+		  AND ws.started_at >= NOW() - INTERVAL '56 days'
+		GROUP BY s.exercicio_id, ws.id, ws.started_at
+		ORDER BY s.exercicio_id, ws.started_at
+	`, uid)
+	if err != nil {
+		return fatigueReport{}, err
+	}
+	for rows.Next() {
+		var (
+			id int64
+			v  float64
+		)
+		if err := rows.Scan(&id, &v); err != nil {
+			rows.Close()
+			return fatigueReport{}, err
+		}
+		if _, ok := d.E1RM[id]; !ok {
+			d.Order = append(d.Order, id)
+		}
+		d.E1RM[id] = append(d.E1RM[id], roundTo(v, 0.1))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fatigueReport{}, err
+	}
+
+	// 2) RIR: últimos 14 dias vs. 15–42 dias atrás
+	var recentRIR, baseRIR sql.NullFloat64
+	err = db.QueryRowContext(ctx, `
+		SELECT AVG(s.rir) FILTER (WHERE ws.started_at >= NOW() - INTERVAL '14 days')::float8,
+		       COUNT(*)   FILTER (WHERE ws.started_at >= NOW() - INTERVAL '14 days'),
+		       AVG(s.rir) FILTER (WHERE ws.started_at <  NOW() - INTERVAL '14 days')::float8,
+		       COUNT(*)   FILTER (WHERE ws.started_at <  NOW() - INTERVAL '14 days')
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE ws.user_id = $1
+		  AND s.completed = TRUE
+		  AND NOT s.flagged
+		  AND s.rir IS NOT NULL
+		  AND ws.started_at >= NOW() - INTERVAL '42 days'
+	`, uid).Scan(&recentRIR, &d.RecentN, &baseRIR, &d.BaseN)
+	if err != nil {
+		return fatigueReport{}, err
+	}
+	if recentRIR.Valid {
+		d.RecentRIR = &recentRIR.Float64
+	}
+	if baseRIR.Valid {
+		d.BaseRIR = &baseRIR.Float64
+	}
+
+	// 3) RPE da sessão subindo com tonelagem igual/menor (últimas 6 sessões com RPE)
+	rows, err = db.QueryContext(ctx, `
+		SELECT rpe, tonnage FROM (
+		  SELECT ws.started_at, ws.rpe_session::float8 AS rpe,
+		         COALESCE(SUM(s.weight_kg * s.reps) FILTER (WHERE s.completed AND NOT s.flagged), 0)::float8 AS tonnage
+		  FROM workout_sessions ws
+		  LEFT JOIN workout_sets s ON s.session_id = ws.id
+		  WHERE ws.user_id = $1
+		    AND ws.rpe_session IS NOT NULL
+		    AND ws.started_at >= NOW() - INTERVAL '42 days'
+		  GROUP BY ws.id, ws.started_at, ws.rpe_session
+		  ORDER BY ws.started_at DESC
+		  LIMIT 6
+		) t ORDER BY started_at
+	`, uid)
+	if err != nil {
+		return fatigueReport{}, err
+	}
+	for rows.Next() {
+		var rpe, ton float64
+		if err := rows.Scan(&rpe, &ton); err != nil {
+			rows.Close()
+			return fatigueReport{}, err
+		}
+		d.RPE = append(d.RPE, rpe)
+		d.Tonnage = append(d.Tonnage, ton)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fatigueReport{}, err
+	}
+
+	rep := evalFatigue(langOf(ctx), d)
+	dl, err := activeDeload(ctx, db, uid, day)
+	if err != nil {
+		return rep, err
+	}
+	rep.ActiveDeload = dl
+	return rep, nil
+}
+
+// TrailingDrops exportado pros testes
+func TrailingDrops(vals []float64, dropPct float64) int { return trailingDrops(vals, dropPct) }
+
+// utcDay: data (YYYY-MM-DD) em UTC; "hoje" do usuário vem de requestDay/localDay.
+func utcDay(t time.Time) string { return t.UTC().Format("2006-01-02") }
+
+func mean(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	s := 0.0
+	for _, x := range v {
+		s += x
+	}
+	return s / float64(len(v))
+}
+
+// activeDeload: deload manual/recomendado vigente em day (YYYY-MM-DD, data local
+// do usuário) ou semana de deload do programa.
+func activeDeload(ctx context.Context, db *sql.DB, uid, day string) (*deloadPeriod, error) {
+	if uid == "" {
+		return nil, nil
+	}
+
+	var (
+		d  deloadPeriod
+		id int64
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT id, to_char(starts_on, 'YYYY-MM-DD'), to_char(ends_on, 'YYYY-MM-DD'), reason, source,
+		       load_factor::float8, volume_factor::float8
+		FROM deload_periods
+		WHERE user_id = $1 AND cancelled_at IS NULL
+		  AND $2::date BETWEEN starts_on AND ends_on
+		ORDER BY starts_on DESC
+		LIMIT 1
+	`, uid, day).Scan(&id, &d.StartsOn, &d.EndsOn, &d.Reason, &d.Source, &d.LoadFactor, &d.VolumeFactor)
+	if err == nil {
+		d.ID = &id
+		return &d, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// semana de deload planejada no programa mais recente
+	err = db.QueryRowContext(ctx, `
+		SELECT to_char(p.start_date + (pw.week - 1) * 7, 'YYYY-MM-DD'),
+		       to_char(p.start_date + pw.week * 7 - 1, 'YYYY-MM-DD')
+		FROM program_workouts pw
+		JOIN programs p ON p.id = pw.program_id
+		WHERE p.user_id = $1 AND pw.deload
+		  AND $2::date >= p.start_date + (pw.week - 1) * 7
+		  AND $2::date <  p.start_date + pw.week * 7
+		ORDER BY p.created_at DESC
+		LIMIT 1
+	`, uid, day).Scan(&d.StartsOn, &d.EndsOn)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Source = "program"
+	d.LoadFactor = deloadLoadFactor
+	d.VolumeFactor = deloadVolumeFactor
+	return &d, nil
+}
+
+// MeFatigue: GET /api/me/fatigue
+func MeFatigue(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+		day, err := requestDay(r)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		rep, err := detectFatigue(r.Context(), db, userID, day)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		jsonWrite(w, http.StatusOK, rep)
+	})
+}
+
+// MeDeload
+// GET    /api/me/deload   -> deload ativo (404 se nenhum)
+// POST   /api/me/deload   {days?, reason?, load_factor?} -> inicia hoje (default 7 dias)
+// DELETE /api/me/deload   -> encerra o deload manual/recomendado ativo
+// "hoje" = data local (?date ou X-Timezone).
+func MeDeload(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+		day, err := requestDay(r)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			dl, err := activeDeload(r.Context(), db, userID, day)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if dl == nil {
+				notFound(w)
+				return
+			}
+			jsonWrite(w, http.StatusOK, dl)
+
+		case http.MethodPost:
+			var in struct {
+				Days       int      `json:"days,omitempty"`
+				Reason     string   `json:"reason,omitempty"`
+				LoadFactor *float64 `json:"load_factor,omitempty"`
+			}
+			// body opcional
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil && err != io.EOF {
+				badRequest(w, "invalid json")
+				return
+			}
+			if in.Days == 0 {
+				in.Days = 7
+			}
+			if in.Days < 3 || in.Days > 14 {
+				badRequest(w, "days must be between 3 and 14")
+				return
+			}
+			lf := deloadLoadFactor
+			if in.LoadFactor != nil {
+				lf = *in.LoadFactor
+			}
+			if lf < 0.5 || lf > 0.95 {
+				badRequest(w, "load_factor must be between 0.5 and 0.95")
+				return
+			}
+
+			// recomendado quando a detecção aponta fadiga no momento do pedido
+			source := "manual"
+			reason := strings.TrimSpace(in.Reason)
+			if rep, err := detectFatigue(r.Context(), db, userID, day); err == nil && rep.RecommendDeload {
+				source = "recommended"
+				if reason == "" {
+					reason = rep.Recommendation
+				}
+			}
+
+			var id int64
+			err := db.QueryRowContext(r.Context(), `
+				INSERT INTO deload_periods (user_id, starts_on, ends_on, reason, source, load_factor, volume_factor)
+				SELECT $1, $2::date, $2::date + ($3::int - 1), $4, $5, $6, $7
+				WHERE NOT EXISTS (
+				  SELECT 1 FROM deload_periods
+				  WHERE user_id = $1 AND cancelled_at IS NULL AND $2::date BETWEEN starts_on AND ends_on
+				)
+				RETURNING id
+			`, userID, day, in.Days, nullIfEmpty(reason), source, lf, deloadVolumeFactor).Scan(&id)
+			if err == sql.ErrNoRows {
+				http.Error(w, "deload already active", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			dl, err := activeDeload(r.Context(), db, userID, day)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusCreated, dl)
+
+		case http.MethodDelete:
+			res, err := db.ExecContext(r.Context(), `
+				UPDATE deload_periods SET cancelled_at = NOW()
+				WHERE user_id = $1 AND cancelled_at IS NULL AND $2::date BETWEEN starts_on AND ends_on
+			`, userID, day)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				notFound(w)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
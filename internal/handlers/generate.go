@@ -5,10 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"math"
 	"net/http"
 	"strings"
 	"time"
+
+	"anima/internal/i18n"
 )
 
 // ====== Tipos de request/response
@@ -20,6 +21,7 @@ type GenerateReq struct {
 	Dias     int    `json:"dias,omitempty"`      // default 3 (hint)
 	Persist  *bool  `json:"persist,omitempty"`   // default: true (persiste)
 	TreinoID string `json:"treino_id,omitempty"` // opcional: fixa chave lógica
+	Explain  bool   `json:"explain,omitempty"`   // inclui o porquê de cada escolha (também via ?explain=true)
 }
 
 type GeneratedExercise struct {
@@ -28,13 +30,39 @@ type GeneratedExercise struct {
 	Series      int    `json:"series"`
 	Repeticoes  string `json:"repeticoes"`
 	DescansoSeg int    `json:"descanso_seg,omitempty"` // 🆕 descanso entre séries
+
+	Explain *ExerciseExplain `json:"explain,omitempty"`
+}
+
+// ExerciseExplain: por que o exercício entrou e de onde vieram descanso/séries/reps.
+type ExerciseExplain struct {
+	Selection   string    `json:"selection"`              // "target_group" | "fill"
+	TargetGroup string    `json:"target_group,omitempty"` // grupo-alvo atendido (selection=target_group)
+	Level       string    `json:"level"`                  // "match" | "fallback" (sem opção no nível) | "any"
+	Motivo      string    `json:"motivo"`
+	Rest        restTrace `json:"rest"`
+	Series      string    `json:"series"` // regra aplicada, ex. "goal:hipertrofia alt (a cada 2)"
+	Reps        string    `json:"reps"`   // regra aplicada, ex. "goal:forca"
+}
+
+// PlanExplain: visão geral da sessão gerada.
+type PlanExplain struct {
+	RuleVersion     int                `json:"rule_version"`
+	Goal            string             `json:"goal"`     // regra de objetivo aplicada
+	Division        string             `json:"division"` // divisão aplicada
+	Target          int                `json:"target"`   // nº-alvo de exercícios
+	TargetGroups    []string           `json:"target_groups"`
+	UncoveredGroups []string           `json:"uncovered_groups"` // grupos sem exercício no plano final
+	Excluded        []ExcludedExercise `json:"excluded"`         // contraindicados (limitação/dor)
 }
 
 type GenerateResp struct {
-	ID         *int                `json:"id,omitempty"` // presente se persistido
-	TreinoID   string              `json:"treino_id"`    // key lógica
-	Exercicios []GeneratedExercise `json:"exercicios"`   // plano gerado
-	CoachNotes string              `json:"coach_notes,omitempty"`
+	ID          *int                `json:"id,omitempty"` // presente se persistido
+	TreinoID    string              `json:"treino_id"`    // key lógica
+	Exercicios  []GeneratedExercise `json:"exercicios"`   // plano gerado
+	CoachNotes  string              `json:"coach_notes,omitempty"`
+	RuleVersion int                 `json:"rule_version"` // versão das regras usada na geração
+	Explain     *PlanExplain        `json:"explain,omitempty"`
 }
 
 // ====== Handler
@@ -53,6 +81,9 @@ func GenerateTreino(db *sql.DB) http.Handler {
 		}
 
 		normalizeReq(&req)
+		if strings.EqualFold(r.URL.Query().Get("explain"), "true") {
+			req.Explain = true
+		}
 		if req.Objetivo == "" || req.Nivel == "" || req.Divisao == "" {
 			http.Error(w, "campos obrigatórios: objetivo, nivel, divisao", http.StatusBadRequest)
 			return
@@ -62,16 +93,28 @@ func GenerateTreino(db *sql.DB) http.Handler {
 			persist = *req.Persist
 		}
 
-		uid := getUserID(r)
+		uid := strings.TrimSpace(GetUserID(r))
+		if uid == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		// Perfil + peso de tendência (mesmo usuário do JWT que gravou perfil, métricas e limitações)
+		prof, err := loadUserProfile(r.Context(), db, uid)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		applyWeightTrend(r.Context(), db, uid, &prof)
 
-		// Perfil + métrica recente
-		prof, _ := loadUserProfile(r.Context(), db, uid)
-		if wkg, _ := latestWeight(r.Context(), db, uid); wkg != nil {
-			prof.WeightKG = wkg
+		env, err := loadPlanEnv(r.Context(), db, uid)
+		if err != nil {
+			http.Error(w, "falha ao carregar regras: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		// Plano com diversidade por grupo + divisão (v1.1) + descanso
-		exs, err := buildPlanV11(r.Context(), db, req)
+		exs, explain, err := buildPlanV11(r.Context(), db, env, req)
 		if err != nil {
 			http.Error(w, "falha ao montar plano: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -81,11 +124,8 @@ func GenerateTreino(db *sql.DB) http.Handler {
 			return
 		}
 
-		// Coach notes (se use_ai = true)
-		coach := ""
-		if prof.UseAI == nil || *prof.UseAI {
-			coach = buildCoachNotes(req, prof)
-		}
+		// Coach notes (provider configurado; vazio se use_ai = false)
+		coach := buildCoachNotes(r.Context(), db, uid, req, prof, exs)
 
 		// Persistência opcional
 		key := req.TreinoID
@@ -95,11 +135,11 @@ func GenerateTreino(db *sql.DB) http.Handler {
 
 		var insertedID *int
 		if persist {
-			id, err := persistPlan(r.Context(), db, key, req, coach, exs)
+			id, err := persistPlan(r.Context(), db, key, req, env.rules.Version, coach, exs)
 			if err != nil {
 				if strings.Contains(err.Error(), "duplicate key") {
 					key = "gen-" + time.Now().Format("20060102T150405.000")
-					id, err = persistPlan(r.Context(), db, key, req, coach, exs)
+					id, err = persistPlan(r.Context(), db, key, req, env.rules.Version, coach, exs)
 				}
 			}
 			if err != nil {
@@ -112,11 +152,14 @@ func GenerateTreino(db *sql.DB) http.Handler {
 			w.WriteHeader(http.StatusOK)
 		}
 
+		localizeExerciseNames(r.Context(), db, requestLang(r), exs)
 		resp := GenerateResp{
-			ID:         insertedID,
-			TreinoID:   key,
-			Exercicios: exs,
-			CoachNotes: coach,
+			ID:          insertedID,
+			TreinoID:    key,
+			Exercicios:  exs,
+			CoachNotes:  coach,
+			RuleVersion: env.rules.Version,
+			Explain:     explain,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
@@ -125,30 +168,62 @@ func GenerateTreino(db *sql.DB) http.Handler {
 
 // ====== v1.1 + descanso: diversidade por grupo + divisão
 
-func buildPlanV11(ctx context.Context, db *sql.DB, req GenerateReq) ([]GeneratedExercise, error) {
-	target := 6 // nº-alvo por sessão
+// planEnv: contexto da geração (usuário p/ visibilidade do catálogo, regras ativas
+// e limitações/dor vigentes).
+type planEnv struct {
+	uid    string
+	rules  *ruleSet
+	limits *limitProfile
+}
 
-	// grupos da sessão conforme divisão
-	sessionGroups := groupsForDivision(req.Divisao)
+func loadPlanEnv(ctx context.Context, db *sql.DB, uid string) (planEnv, error) {
+	rs, err := loadActiveRules(ctx, db)
+	if err != nil {
+		return planEnv{}, err
+	}
+	lp, err := loadLimits(ctx, db, uid)
+	if err != nil {
+		return planEnv{}, err
+	}
+	return planEnv{uid: uid, rules: rs, limits: lp}, nil
+}
+
+// buildPlanV11 monta a sessão; com req.Explain também devolve o porquê de cada escolha.
+func buildPlanV11(ctx context.Context, db *sql.DB, env planEnv, req GenerateReq) ([]GeneratedExercise, *PlanExplain, error) {
+	uid, rules := env.uid, env.rules
+	target := rules.TargetPerSession // nº-alvo por sessão
 
-	// 1) tenta 1 exercício por grupo-alvo (em ordem)
-	var pool []exRow
+	// grupos da sessão conforme divisão
+	sessionGroups := rules.groupsFor(req.Divisao)
+
+	// 1) tenta 1 exercício por grupo-alvo (em ordem); contraindicados são trocados
+	// pelo próximo do mesmo grupo
+	var (
+		pool     []exRow
+		excluded []ExcludedExercise
+	)
 	for _, g := range sessionGroups {
-		row, err := queryFirstByGroup(ctx, db, g, req.Nivel)
+		row, skipped, err := queryFirstByGroup(ctx, db, uid, g, req.Nivel, env.limits)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		excluded = append(excluded, skipped...)
 		if row != nil {
+			row.reason, row.group = "target_group", g
+			if len(skipped) > 0 {
+				row.replaced = skipped[0].Nome
+			}
 			pool = append(pool, *row)
 		}
 	}
 
 	// 2) completa com catálogo geral do nível (sem repetir IDs)
 	if len(pool) < target {
-		rest, err := queryExercises(ctx, db, req.Nivel, target-len(pool))
+		rest, skipped, err := queryExercises(ctx, db, uid, req.Nivel, target-len(pool), env.limits)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		excluded = append(excluded, skipped...)
 		used := make(map[int]struct{}, len(pool))
 		for _, r := range pool {
 			used[r.id] = struct{}{}
@@ -157,6 +232,7 @@ func buildPlanV11(ctx context.Context, db *sql.DB, req GenerateReq) ([]Generated
 			if _, seen := used[r.id]; seen {
 				continue
 			}
+			r.reason = "fill"
 			pool = append(pool, r)
 			if len(pool) == target {
 				break
@@ -164,46 +240,109 @@ func buildPlanV11(ctx context.Context, db *sql.DB, req GenerateReq) ([]Generated
 		}
 	}
 
-	if len(pool) == 0 {
-		return nil, nil
-	}
 	if len(pool) > target {
 		pool = pool[:target]
 	}
 
-	reps := repsByGoal(req.Objetivo)
+	var explain *PlanExplain
+	if req.Explain {
+		explain = &PlanExplain{
+			RuleVersion:     rules.Version,
+			Goal:            rules.goalKey(req.Objetivo),
+			Division:        rules.divisionKey(req.Divisao),
+			Target:          target,
+			TargetGroups:    sessionGroups,
+			UncoveredGroups: uncoveredGroups(sessionGroups, pool),
+			Excluded:        dedupExcluded(excluded),
+		}
+	}
+	if len(pool) == 0 {
+		return nil, explain, nil
+	}
+
+	reps := rules.repsFor(req.Objetivo, req.Nivel)
 
 	out := make([]GeneratedExercise, 0, len(pool))
 	for i, it := range pool {
-		series := 3
-		if req.Objetivo == "hipertrofia" && i%2 == 1 {
-			series = 4
-		}
-		rest := restForExercise(req.Objetivo, req.Nivel, it) // 🆕 descanso por exercício
+		series := rules.seriesFor(req.Objetivo, req.Nivel, i)
+		rest := rules.restFor(req.Objetivo, req.Nivel, it) // descanso por exercício
 
-		out = append(out, GeneratedExercise{
+		ge := GeneratedExercise{
 			ExercicioID: it.id,
 			Nome:        it.name,
 			Series:      series,
 			Repeticoes:  reps,
 			DescansoSeg: rest,
-		})
+		}
+		if req.Explain {
+			ge.Explain = &ExerciseExplain{
+				Selection:   it.reason,
+				TargetGroup: it.group,
+				Level:       it.levelMatch,
+				Motivo:      selectionMotivo(langOf(ctx), it, req.Nivel),
+				Rest:        rules.traceRest(req.Objetivo, req.Nivel, it),
+				Series:      rules.seriesSource(req.Objetivo, req.Nivel, i),
+				Reps:        rules.repsSource(req.Objetivo, req.Nivel),
+			}
+		}
+		out = append(out, ge)
+	}
+	return out, explain, nil
+}
+
+func dedupExcluded(in []ExcludedExercise) []ExcludedExercise {
+	seen := map[int]bool{}
+	out := []ExcludedExercise{}
+	for _, e := range in {
+		if !seen[e.ExercicioID] {
+			seen[e.ExercicioID] = true
+			out = append(out, e)
+		}
 	}
-	return out, nil
+	return out
 }
 
-func repsByGoal(goal string) string {
-	switch strings.ToLower(goal) {
-	case "hipertrofia":
-		return "8-12"
-	case "emagrecimento":
-		return "12-15"
-	case "forca", "força":
-		return "4-6"
-	case "resistencia", "resistência":
-		return "12-20"
+// grupos-alvo sem nenhum exercício correspondente no plano final
+func uncoveredGroups(groups []string, pool []exRow) []string {
+	out := []string{}
+	for _, g := range groups {
+		covered := false
+		for _, alt := range normalizeGroupName(g) {
+			for _, it := range pool {
+				if it.muscleGroup == alt {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				break
+			}
+		}
+		if !covered {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+func selectionMotivo(l i18n.Lang, it exRow, nivel string) string {
+	nivel = i18n.Label(l, "level", nivel)
+	if it.replaced != "" {
+		return i18n.T(l, "plan.motive.replaced", it.group, it.replaced)
+	}
+	if it.reason == "fill" {
+		if it.levelMatch == "match" {
+			return i18n.T(l, "plan.motive.fill_lvl", nivel)
+		}
+		return i18n.T(l, "plan.motive.fill")
+	}
+	switch it.levelMatch {
+	case "match":
+		return i18n.T(l, "plan.motive.match", it.group, nivel)
+	case "fallback":
+		return i18n.T(l, "plan.motive.fallback", it.group, nivel)
 	default:
-		return "8-12"
+		return i18n.T(l, "plan.motive.group", it.group)
 	}
 }
 
@@ -215,65 +354,12 @@ type exRow struct {
 	muscleGroup  string
 	difficulty   string
 	isBodyweight bool
-}
 
-// Define descanso base por objetivo, ajusta por composto/isolado, peso corporal e dificuldade.
-// Intervalos alvo (heurística segura):
-// - hipertrofia: 60–90s; compostos tendem a ~90s, isolados ~60–75s
-// - força: 120–180s; compostos mais altos (~150–180s)
-// - emagrecimento/resistência: 30–60s
-func restForExercise(goal, nivel string, ex exRow) int {
-	goal = strings.ToLower(strings.TrimSpace(goal))
-	nivel = strings.ToLower(strings.TrimSpace(nivel))
-
-	// base por objetivo
-	base := 75 // default
-	switch goal {
-	case "hipertrofia":
-		base = 75
-	case "emagrecimento", "resistencia", "resistência":
-		base = 45
-	case "forca", "força":
-		base = 150
-	}
-
-	// composto vs isolado
-	if isCompound(ex.name, ex.muscleGroup) {
-		base += 30 // compostos pedem mais recuperação
-	} else {
-		base -= 10 // isolados, um pouco menos
-	}
-
-	// peso corporal costuma exigir menos descanso que máximos pesados
-	if ex.isBodyweight {
-		base -= 10
-	}
-
-	// ajuste por dificuldade
-	switch nivel {
-	case "iniciante":
-		// manter faixas moderadas
-		if base > 90 && (goal == "hipertrofia" || goal == "emagrecimento" || strings.HasPrefix(goal, "resist")) {
-			base = 90
-		}
-	case "avancado":
-		// para força avançada, um pouco mais
-		if goal == "forca" || goal == "força" {
-			base += 15
-		}
-	}
-
-	// clamp por objetivo
-	switch goal {
-	case "hipertrofia":
-		return clampRest(base, 60, 105)
-	case "forca", "força":
-		return clampRest(base, 120, 180)
-	case "emagrecimento", "resistencia", "resistência":
-		return clampRest(base, 30, 75)
-	default:
-		return clampRest(base, 45, 105)
-	}
+	// metadados da seleção (explain)
+	reason     string // "target_group" | "fill"
+	group      string // grupo-alvo atendido
+	levelMatch string // "match" | "fallback" | "any"
+	replaced   string // contraindicado que este substitui
 }
 
 func clampRest(v, lo, hi int) int {
@@ -317,25 +403,7 @@ func isCompound(name, mg string) bool {
 	return false
 }
 
-// ====== Divisão / grupos
-
-func groupsForDivision(div string) []string {
-	d := strings.ToLower(strings.TrimSpace(div))
-	switch d {
-	case "upper", "upperlower":
-		return []string{"peito", "costas", "ombros", "biceps", "triceps", "core"}
-	case "lower":
-		return []string{"quadriceps", "posterior", "gluteos", "panturrilhas", "lombar", "core"}
-	case "ppl", "push":
-		return []string{"peito", "ombros", "triceps", "core", "quadriceps", "panturrilhas"}
-	case "pull":
-		return []string{"costas", "lombar", "biceps", "posterior", "core", "trapézio"}
-	case "legs":
-		return []string{"quadriceps", "posterior", "gluteos", "panturrilhas", "lombar", "core"}
-	default: // fullbody
-		return []string{"peito", "costas", "pernas", "ombros", "core", "biceps", "triceps"}
-	}
-}
+// ====== Grupos
 
 func normalizeGroupName(g string) []string {
 	g = strings.ToLower(strings.TrimSpace(g))
@@ -376,13 +444,12 @@ func normalizeGroupName(g string) []string {
 
 // ====== Acesso ao catálogo
 
-// pega 1 exercício do grupo (normalizado), preferindo por nível
-func queryFirstByGroup(ctx context.Context, db *sql.DB, group string, nivel string) (*exRow, error) {
-	var row exRow
-
+// pega 1 exercício do grupo (normalizado), preferindo por nível;
+// pula os contraindicados (limitações/dor) e devolve quais foram pulados
+func queryFirstByGroup(ctx context.Context, db *sql.DB, uid string, group string, nivel string, lp *limitProfile) (*exRow, []ExcludedExercise, error) {
 	alts := normalizeGroupName(group)
 	if len(alts) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 	inList := "'" + strings.Join(alts, "','") + "'"
 
@@ -391,54 +458,102 @@ func queryFirstByGroup(ctx context.Context, db *sql.DB, group string, nivel stri
 		       COALESCE(is_bodyweight, false) AS bw
 		FROM exercises
 		WHERE lower(muscle_group) IN (` + inList + `)
+		  AND ` + exerciseVisibleSQL("owner_user_id", 1) + `
 	`
-	args := []any{}
+	args := []any{uid}
 	if nivel != "" {
-		q += ` AND lower(difficulty) = $1 `
+		q += ` AND lower(difficulty) = $2 `
 		args = append(args, strings.ToLower(nivel))
 	}
-	q += ` ORDER BY id ASC LIMIT 1`
+	q += ` ORDER BY id ASC LIMIT ` + fmt.Sprint(1+limitScan(lp))
 
-	err := db.QueryRowContext(ctx, q, args...).Scan(&row.id, &row.name, &row.muscleGroup, &row.difficulty, &row.isBodyweight)
-	if err == sql.ErrNoRows {
-		// sem filtro de nível
-		q2 := `
-			SELECT id, name, lower(muscle_group) AS mg, lower(difficulty) AS diff,
-			       COALESCE(is_bodyweight, false) AS bw
-			FROM exercises
-			WHERE lower(muscle_group) IN (` + inList + `)
-			ORDER BY id ASC LIMIT 1
-		`
-		err2 := db.QueryRowContext(ctx, q2).Scan(&row.id, &row.name, &row.muscleGroup, &row.difficulty, &row.isBodyweight)
-		if err2 == sql.ErrNoRows {
-			return nil, nil
-		}
-		if err2 != nil {
-			return nil, err2
+	var excluded []ExcludedExercise
+	rows, err := scanExRows(ctx, db, q, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if row := firstAllowed(rows, lp, &excluded); row != nil {
+		row.levelMatch = "any"
+		if nivel != "" {
+			row.levelMatch = "match"
 		}
-		return &row, nil
+		return row, excluded, nil
 	}
+
+	// sem filtro de nível
+	q2 := `
+		SELECT id, name, lower(muscle_group) AS mg, lower(difficulty) AS diff,
+		       COALESCE(is_bodyweight, false) AS bw
+		FROM exercises
+		WHERE lower(muscle_group) IN (` + inList + `)
+		  AND ` + exerciseVisibleSQL("owner_user_id", 1) + `
+		ORDER BY id ASC LIMIT ` + fmt.Sprint(1+limitScan(lp))
+	rows, err = scanExRows(ctx, db, q2, uid)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	row := firstAllowed(rows, lp, &excluded)
+	if row == nil {
+		return nil, excluded, nil
 	}
-	return &row, nil
+	row.levelMatch = "any"
+	if nivel != "" {
+		row.levelMatch = "fallback"
+	}
+	return row, excluded, nil
 }
 
-// catálogo geral (por nível, fallback sem nível)
-func queryExercises(ctx context.Context, db *sql.DB, nivel string, limit int) ([]exRow, error) {
-	args := []any{}
+// catálogo geral (por nível), sem contraindicados
+func queryExercises(ctx context.Context, db *sql.DB, uid string, nivel string, limit int, lp *limitProfile) ([]exRow, []ExcludedExercise, error) {
+	args := []any{uid}
 	q := `
 		SELECT id, name, lower(muscle_group) AS mg, lower(difficulty) AS diff,
 		       COALESCE(is_bodyweight, false) AS bw
 		FROM exercises
+		WHERE ` + exerciseVisibleSQL("owner_user_id", 1) + `
 	`
 	if nivel != "" {
-		q += ` WHERE lower(difficulty) = $1 `
+		q += ` AND lower(difficulty) = $2 `
 		args = append(args, strings.ToLower(nivel))
 	}
 	q += ` ORDER BY id ASC LIMIT $` + fmt.Sprint(len(args)+1)
-	args = append(args, limit)
+	args = append(args, limit+limitScan(lp))
+
+	rows, err := scanExRows(ctx, db, q, args...)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	var (
+		out      []exRow
+		excluded []ExcludedExercise
+	)
+	for _, r := range rows {
+		if why := lp.contraindication(int64(r.id), r.name, r.muscleGroup); why != "" {
+			excluded = append(excluded, ExcludedExercise{ExercicioID: r.id, Nome: r.name, Reason: why})
+			continue
+		}
+		r.levelMatch = "any"
+		if nivel != "" {
+			r.levelMatch = "match"
+		}
+		out = append(out, r)
+		if len(out) == limit {
+			break
+		}
+	}
+	return out, excluded, nil
+}
+
+// folga de linhas a buscar quando há restrições (para sobrar opções após o filtro)
+func limitScan(lp *limitProfile) int {
+	if lp.empty() {
+		return 0
+	}
+	return 50
+}
+
+func scanExRows(ctx context.Context, db *sql.DB, q string, args ...any) ([]exRow, error) {
 	rows, err := db.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, err
@@ -456,21 +571,47 @@ func queryExercises(ctx context.Context, db *sql.DB, nivel string, limit int) ([
 	return out, rows.Err()
 }
 
+// primeiro liberado; os anteriores contraindicados vão para excluded
+func firstAllowed(rows []exRow, lp *limitProfile, excluded *[]ExcludedExercise) *exRow {
+	for i := range rows {
+		r := rows[i]
+		if why := lp.contraindication(int64(r.id), r.name, r.muscleGroup); why != "" {
+			*excluded = append(*excluded, ExcludedExercise{ExercicioID: r.id, Nome: r.name, Reason: why})
+			continue
+		}
+		return &r
+	}
+	return nil
+}
+
 // ====== Persistência
 
-func persistPlan(ctx context.Context, db *sql.DB, key string, req GenerateReq, coach string, plan []GeneratedExercise) (int, error) {
+func persistPlan(ctx context.Context, db *sql.DB, key string, req GenerateReq, ruleVersion int, coach string, plan []GeneratedExercise) (int, error) {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	treinoID, err := insertTreinoTx(ctx, tx, key, req, ruleVersion, coach, plan)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return treinoID, nil
+}
+
+// insertTreinoTx grava treino + itens dentro de uma transação existente.
+func insertTreinoTx(ctx context.Context, tx *sql.Tx, key string, req GenerateReq, ruleVersion int, coach string, plan []GeneratedExercise) (int, error) {
 	var treinoID int
-	err = tx.QueryRowContext(ctx, `
-		INSERT INTO treinos (objetivo, nivel, dias, divisao, treino_key, coach_notes)
-		VALUES ($1,$2,$3,$4,$5,$6)
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO treinos (objetivo, nivel, dias, divisao, treino_key, coach_notes, rule_version)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
 		RETURNING id
-	`, req.Objetivo, req.Nivel, req.Dias, req.Divisao, key, nullIfEmpty(coach)).Scan(&treinoID)
+	`, req.Objetivo, req.Nivel, req.Dias, req.Divisao, key, nullIfEmpty(coach), ruleVersion).Scan(&treinoID)
 	if err != nil {
 		return 0, err
 	}
@@ -484,10 +625,6 @@ func persistPlan(ctx context.Context, db *sql.DB, key string, req GenerateReq, c
 			return 0, err
 		}
 	}
-
-	if err := tx.Commit(); err != nil {
-		return 0, err
-	}
 	return treinoID, nil
 }
 
@@ -508,67 +645,3 @@ func normalizeReq(req *GenerateReq) {
 		req.Dias = 3
 	}
 }
-
-func buildCoachNotes(req GenerateReq, p userProfile) string {
-	obj := req.Objetivo
-	if obj == "" && p.TrainingGoal != nil && *p.TrainingGoal != "" {
-		obj = *p.TrainingGoal
-	}
-
-	// idade
-	var idade *int
-	now := time.Now()
-	if p.BirthDate != nil {
-		y := computeAge(*p.BirthDate, now)
-		idade = &y
-	}
-
-	// IMC
-	var imc *float64
-	if p.HeightCM != nil && *p.HeightCM > 0 && p.WeightKG != nil && *p.WeightKG > 0 {
-		hm := float64(*p.HeightCM) / 100.0
-		v := *p.WeightKG / (hm * hm)
-		v = math.Round(v*100) / 100
-		imc = &v
-	}
-
-	txt := fmt.Sprintf("Plano %s (%s), divisão %s. ",
-		valOr("geral", obj), valOr("nível indefinido", req.Nivel), valOr("indefinida", req.Divisao))
-
-	if p.HeightCM != nil {
-		txt += fmt.Sprintf("Altura %dcm. ", *p.HeightCM)
-	}
-	if p.WeightKG != nil {
-		txt += fmt.Sprintf("Peso %.1fkg. ", *p.WeightKG)
-	}
-	if idade != nil {
-		txt += fmt.Sprintf("Idade %d. ", *idade)
-	}
-	if imc != nil {
-		txt += fmt.Sprintf("IMC=%.2f. ", *imc)
-	}
-
-	switch obj {
-	case "hipertrofia":
-		txt += "Foque em progressão de carga com técnica sólida; 8–12 reps nos compostos; sono ≥ 7h."
-	case "emagrecimento":
-		txt += "Aumente densidade do treino (descanso curto) e mantenha leve déficit calórico."
-	case "forca", "força":
-		txt += "Priorize compostos pesados; séries curtas e descanso maior; monitore a técnica."
-	case "resistencia", "resistência":
-		txt += "Volume moderado/alto, cadência controlada e constância semanal."
-	default:
-		txt += "Mantenha técnica perfeita, aquecimento e progressão gradual."
-	}
-	if idade != nil && *idade >= 40 {
-		txt += " Aqueça bem ombros/quadril; evite picos de carga abruptos."
-	}
-	return txt
-}
-
-func valOr(def, v string) string {
-	if strings.TrimSpace(v) == "" {
-		return def
-	}
-	return v
-}
@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metas do usuário: força (e1RM de um exercício) ou peso corporal, com prazo
+// opcional. Progresso vem do histórico (e1RM por sessão / user_metrics) e a
+// data prevista de uma regressão linear sobre os últimos 84 dias.
+
+const (
+	goalForecastDays = 84 // janela da regressão
+	goalMinPoints    = 3
+	goalMinSpanDays  = 7
+)
+
+type goalForecast struct {
+	Status         string   `json:"status"` // achieved | on_track | off_track | insufficient_data
+	RatePerWeek    *float64 `json:"rate_per_week,omitempty"`
+	RequiredPerWk  *float64 `json:"required_per_week,omitempty"` // ritmo necessário até o prazo
+	ForecastDate   *string  `json:"forecast_date,omitempty"`
+	DaysToDeadline *int     `json:"days_to_deadline,omitempty"`
+	Points         int      `json:"points"`
+}
+
+type userGoal struct {
+	ID           int64        `json:"id"`
+	Kind         string       `json:"kind"` // strength | bodyweight
+	ExercicioID  *int64       `json:"exercicio_id,omitempty"`
+	Exercicio    *string      `json:"exercicio,omitempty"`
+	TargetValue  float64      `json:"target_value"`
+	StartValue   *float64     `json:"start_value,omitempty"`
+	CurrentValue *float64     `json:"current_value,omitempty"`
+	ProgressPct  *float64     `json:"progress_pct,omitempty"`
+	Deadline     *string      `json:"deadline,omitempty"`
+	Notes        *string      `json:"notes,omitempty"`
+	AchievedAt   *time.Time   `json:"achieved_at,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Forecast     goalForecast `json:"forecast"`
+}
+
+type goalIn struct {
+	Kind        *string  `json:"kind"`
+	ExercicioID *int64   `json:"exercicio_id"`
+	TargetValue *float64 `json:"target_value"`
+	Deadline    *string  `json:"deadline"` // "" limpa
+	Notes       *string  `json:"notes"`
+}
+
+type goalPoint struct {
+	day time.Time
+	v   float64
+}
+
+// goalSeries: histórico recente do valor acompanhado pela meta.
+func goalSeries(ctx context.Context, db *sql.DB, uid string, g *userGoal) ([]goalPoint, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if g.Kind == "strength" {
+		rows, err = db.QueryContext(ctx, `
+			SELECT ws.started_at::date, MAX(`+e1rmSQL+`)::float8
+			FROM workout_sets s
+			JOIN workout_sessions ws ON ws.id = s.session_id
+			WHERE ws.user_id = $1
+			  AND s.exercicio_id = $2
+			  AND s.completed = TRUE
+			  AND NOT s.flagged
+			  AND s.weight_kg > 0
+			  AND s.reps BETWEEN 1 AND 12
+			  AND ws.started_at >= NOW() - make_interval(days => $3)
+			GROUP BY 1
+			ORDER BY 1
+		`, uid, *g.ExercicioID, goalForecastDays)
+	} else {
+		rows, err = db.QueryContext(ctx, `
+			SELECT measured_at, weight_kg::float8
+			FROM user_metrics
+			WHERE user_id = $1
+			  AND weight_kg IS NOT NULL
+			  AND measured_at >= CURRENT_DATE - $2::int
+			ORDER BY measured_at
+		`, uid, goalForecastDays)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []goalPoint
+	for rows.Next() {
+		var p goalPoint
+		if err := rows.Scan(&p.day, &p.v); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// currentGoalValue: força = melhor e1RM das últimas 4 semanas da série; peso = último.
+func currentGoalValue(kind string, pts []goalPoint) *float64 {
+	if len(pts) == 0 {
+		return nil
+	}
+	last := pts[len(pts)-1]
+	if kind != "strength" {
+		v := last.v
+		return &v
+	}
+	best := 0.0
+	cut := last.day.AddDate(0, 0, -28)
+	for _, p := range pts {
+		if !p.day.Before(cut) && p.v > best {
+			best = p.v
+		}
+	}
+	best = math.Round(best*10) / 10
+	return &best
+}
+
+// forecastGoal: regressão linear (valor x dias) e status contra prazo.
+func forecastGoal(g *userGoal, pts []goalPoint, now time.Time) {
+	g.CurrentValue = currentGoalValue(g.Kind, pts)
+	if g.StartValue == nil && len(pts) > 0 {
+		v := pts[0].v
+		g.StartValue = &v
+	}
+	f := goalForecast{Status: "insufficient_data", Points: len(pts)}
+	today := now.UTC().Truncate(24 * time.Hour)
+
+	var deadline time.Time
+	if g.Deadline != nil {
+		deadline, _ = time.Parse("2006-01-02", *g.Deadline)
+		d := int(deadline.Sub(today).Hours() / 24)
+		f.DaysToDeadline = &d
+	}
+
+	// direção: subir (força, ou peso com alvo acima do início) ou descer
+	up := true
+	if g.StartValue != nil && g.TargetValue < *g.StartValue {
+		up = false
+	}
+	if g.Kind == "strength" {
+		up = true
+	}
+
+	if g.CurrentValue != nil {
+		cur := *g.CurrentValue
+		if g.StartValue != nil && *g.StartValue != g.TargetValue {
+			p := (cur - *g.StartValue) / (g.TargetValue - *g.StartValue) * 100
+			p = math.Round(math.Max(0, math.Min(100, p))*10) / 10
+			g.ProgressPct = &p
+		}
+		if (up && cur >= g.TargetValue) || (!up && cur <= g.TargetValue) {
+			f.Status = "achieved"
+			g.Forecast = f
+			return
+		}
+		if f.DaysToDeadline != nil && *f.DaysToDeadline > 0 {
+			req := round2((g.TargetValue - cur) / float64(*f.DaysToDeadline) * 7)
+			f.RequiredPerWk = &req
+		}
+	}
+
+	if len(pts) < goalMinPoints || pts[len(pts)-1].day.Sub(pts[0].day).Hours()/24 < goalMinSpanDays {
+		g.Forecast = f
+		return
+	}
+
+	xs := make([]float64, len(pts))
+	ys := make([]float64, len(pts))
+	for i, p := range pts {
+		xs[i] = p.day.Sub(pts[0].day).Hours() / 24
+		ys[i] = p.v
+	}
+	slope := linreg(xs, ys) // por dia
+	rate := round2(slope * 7)
+	f.RatePerWeek = &rate
+
+	// valor "atual" da reta no último ponto, para não depender de um outlier
+	my, mx := mean(ys), mean(xs)
+	lastX := xs[len(xs)-1]
+	fitted := my + slope*(lastX-mx)
+	remaining := g.TargetValue - fitted
+	if slope == 0 || (remaining > 0) != (slope > 0) {
+		f.Status = "off_track" // parado ou indo na direção contrária
+		g.Forecast = f
+		return
+	}
+	days := remaining / slope
+	fd := pts[len(pts)-1].day.AddDate(0, 0, int(math.Ceil(days)))
+	s := fd.Format("2006-01-02")
+	f.ForecastDate = &s
+
+	f.Status = "on_track"
+	if g.Deadline != nil && fd.After(deadline) {
+		f.Status = "off_track"
+	}
+	g.Forecast = f
+}
+
+// UserGoal / ForecastGoal exportados pros testes (pontos como YYYY-MM-DD + valor)
+type UserGoal = userGoal
+
+func ForecastGoal(g *UserGoal, days []string, vals []float64, now time.Time) {
+	pts := make([]goalPoint, len(days))
+	for i, d := range days {
+		t, _ := time.Parse("2006-01-02", d)
+		pts[i] = goalPoint{day: t, v: vals[i]}
+	}
+	forecastGoal(g, pts, now)
+}
+
+// MeGoals: metas do usuário atual.
+// GET    /api/me/goals
+// POST   /api/me/goals        {kind: strength|bodyweight, exercicio_id?, target_value, deadline?, notes?}
+// PATCH  /api/me/goals/{id}   {target_value?, deadline?, notes?}
+// DELETE /api/me/goals/{id}
+func MeGoals(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/goals"), "/")
+		if rest == "" {
+			switch r.Method {
+			case http.MethodGet:
+				listGoals(db, w, r, userID)
+			case http.MethodPost:
+				createGoal(db, w, r, userID)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil || id <= 0 {
+			badRequest(w, "invalid goal id")
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			patchGoal(db, w, r, userID, id)
+		case http.MethodDelete:
+			res, err := db.ExecContext(r.Context(), `DELETE FROM user_goals WHERE id = $1 AND user_id = $2`, id, userID)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if aff, _ := res.RowsAffected(); aff == 0 {
+				notFound(w)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+const goalCols = `
+	SELECT g.id, g.kind, g.exercicio_id, e.name, g.target_value::float8, g.start_value::float8,
+	       to_char(g.deadline, 'YYYY-MM-DD'), g.notes, g.achieved_at, g.created_at
+	FROM user_goals g
+	LEFT JOIN exercises e ON e.id = g.exercicio_id
+`
+
+func scanGoal(sc interface{ Scan(...any) error }) (*userGoal, error) {
+	var g userGoal
+	err := sc.Scan(&g.ID, &g.Kind, &g.ExercicioID, &g.Exercicio, &g.TargetValue, &g.StartValue,
+		&g.Deadline, &g.Notes, &g.AchievedAt, &g.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// evalGoal: progresso + previsão; marca achieved_at na primeira vez.
+func evalGoal(ctx context.Context, db *sql.DB, uid string, g *userGoal) error {
+	pts, err := goalSeries(ctx, db, uid, g)
+	if err != nil {
+		return err
+	}
+	forecastGoal(g, pts, time.Now())
+	if g.Forecast.Status == "achieved" && g.AchievedAt == nil {
+		now := time.Now().UTC()
+		if _, err := db.ExecContext(ctx, `UPDATE user_goals SET achieved_at = $1 WHERE id = $2 AND achieved_at IS NULL`, now, g.ID); err != nil {
+			return err
+		}
+		g.AchievedAt = &now
+	}
+	return nil
+}
+
+func listGoals(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	rows, err := db.QueryContext(r.Context(), goalCols+` WHERE g.user_id = $1 ORDER BY g.deadline NULLS LAST, g.id`, userID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	items := []*userGoal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			rows.Close()
+			internalErr(w, err)
+			return
+		}
+		items = append(items, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		internalErr(w, err)
+		return
+	}
+
+	for _, g := range items {
+		if err := evalGoal(r.Context(), db, userID, g); err != nil {
+			internalErr(w, err)
+			return
+		}
+	}
+	jsonWrite(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func fetchGoal(ctx context.Context, db *sql.DB, userID string, id int64) (*userGoal, error) {
+	g, err := scanGoal(db.QueryRowContext(ctx, goalCols+` WHERE g.user_id = $1 AND g.id = $2`, userID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g, evalGoal(ctx, db, userID, g)
+}
+
+func createGoal(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	var in goalIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	kind := ""
+	if in.Kind != nil {
+		kind = strings.ToLower(strings.TrimSpace(*in.Kind))
+	}
+	if kind != "strength" && kind != "bodyweight" {
+		badRequest(w, "kind must be strength|bodyweight")
+		return
+	}
+	if in.TargetValue == nil || *in.TargetValue <= 0 || *in.TargetValue > 1000 {
+		badRequest(w, "target_value must be between 0 and 1000")
+		return
+	}
+	var exID *int64
+	if kind == "strength" {
+		if in.ExercicioID == nil || *in.ExercicioID <= 0 {
+			badRequest(w, "exercicio_id required for strength goals")
+			return
+		}
+		ok, err := exerciseVisible(r.Context(), db, *in.ExercicioID, userID)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if !ok {
+			badRequest(w, "exercicio_id not found")
+			return
+		}
+		exID = in.ExercicioID
+	}
+	var deadline *string
+	if in.Deadline != nil && *in.Deadline != "" {
+		t, err := time.Parse("2006-01-02", *in.Deadline)
+		if err != nil {
+			badRequest(w, "deadline must be YYYY-MM-DD")
+			return
+		}
+		if !t.After(time.Now().UTC()) {
+			badRequest(w, "deadline must be in the future")
+			return
+		}
+		deadline = in.Deadline
+	}
+
+	// valor inicial = atual no momento da criação
+	probe := &userGoal{Kind: kind, ExercicioID: exID}
+	pts, err := goalSeries(r.Context(), db, userID, probe)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	start := currentGoalValue(kind, pts)
+
+	var id int64
+	err = db.QueryRowContext(r.Context(), `
+		INSERT INTO user_goals (user_id, kind, exercicio_id, target_value, start_value, deadline, notes)
+		VALUES ($1, $2, $3, $4, $5, $6::date, $7)
+		RETURNING id
+	`, userID, kind, exID, *in.TargetValue, start, deadline, in.Notes).Scan(&id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	g, err := fetchGoal(r.Context(), db, userID, id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusCreated, g)
+}
+
+func patchGoal(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string, id int64) {
+	var in goalIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+
+	setParts := []string{}
+	args := []any{}
+	argIdx := 1
+
+	if in.TargetValue != nil {
+		if *in.TargetValue <= 0 || *in.TargetValue > 1000 {
+			badRequest(w, "target_value must be between 0 and 1000")
+			return
+		}
+		// novo alvo: volta a acompanhar
+		setParts = append(setParts, "target_value = $"+itoa(argIdx), "achieved_at = NULL")
+		args = append(args, *in.TargetValue)
+		argIdx++
+	}
+	if in.Deadline != nil {
+		var v any
+		if *in.Deadline != "" {
+			if !validDate(*in.Deadline) {
+				badRequest(w, "deadline must be YYYY-MM-DD")
+				return
+			}
+			v = *in.Deadline
+		}
+		setParts = append(setParts, "deadline = $"+itoa(argIdx)+"::date")
+		args = append(args, v)
+		argIdx++
+	}
+	if in.Notes != nil {
+		setParts = append(setParts, "notes = $"+itoa(argIdx))
+		args = append(args, nullIfEmpty(strings.TrimSpace(*in.Notes)))
+		argIdx++
+	}
+	if len(setParts) == 0 {
+		badRequest(w, "no fields to update")
+		return
+	}
+
+	q := `UPDATE user_goals SET ` + strings.Join(setParts, ", ") +
+		` WHERE id = $` + itoa(argIdx) + ` AND user_id = $` + itoa(argIdx+1)
+	args = append(args, id, userID)
+	res, err := db.ExecContext(r.Context(), q, args...)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		notFound(w)
+		return
+	}
+	g, err := fetchGoal(r.Context(), db, userID, id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusOK, g)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"anima/internal/i18n"
+)
+
+// Idioma da requisição: ?lang= > user_profiles.language > Accept-Language > pt-BR.
+// Resolvido só quando alguém precisa (evita ir ao banco em toda requisição).
+// Erros (http.Error em text/plain e {"error"/"message"} em JSON) são traduzidos
+// apenas quando o cliente escolheu um idioma; sem escolha saem como escritos.
+
+const ctxKeyLang ctxKey = "lang"
+
+type langState struct {
+	once     sync.Once
+	resolve  func() (i18n.Lang, bool)
+	lang     i18n.Lang
+	explicit bool
+}
+
+func (s *langState) get() (i18n.Lang, bool) {
+	s.once.Do(func() { s.lang, s.explicit = s.resolve() })
+	return s.lang, s.explicit
+}
+
+func resolveLang(r *http.Request, db *sql.DB) (i18n.Lang, bool) {
+	if l, ok := i18n.Parse(r.URL.Query().Get("lang")); ok {
+		return l, true
+	}
+	if uid := strings.TrimSpace(GetUserID(r)); uid != "" && db != nil {
+		var pref sql.NullString
+		_ = db.QueryRowContext(r.Context(), `SELECT language FROM user_profiles WHERE user_id = $1`, uid).Scan(&pref)
+		if l, ok := i18n.Parse(pref.String); ok {
+			return l, true
+		}
+	}
+	if l, ok := i18n.FromAcceptLanguage(r.Header.Get("Accept-Language")); ok {
+		return l, true
+	}
+	return i18n.Default, false
+}
+
+// langOf: idioma da requisição (pt-BR fora de Localize).
+func langOf(ctx context.Context) i18n.Lang {
+	if st, ok := ctx.Value(ctxKeyLang).(*langState); ok {
+		l, _ := st.get()
+		return l
+	}
+	return i18n.FromContext(ctx)
+}
+
+func requestLang(r *http.Request) i18n.Lang { return langOf(r.Context()) }
+
+// Localize injeta o idioma no context e traduz corpos de erro.
+func Localize(db *sql.DB, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := &langState{resolve: func() (i18n.Lang, bool) { return resolveLang(r, db) }}
+		w.Header().Add("Vary", "Accept-Language")
+		lw := &localizedWriter{ResponseWriter: w, st: st}
+		next.ServeHTTP(lw, r.WithContext(context.WithValue(r.Context(), ctxKeyLang, st)))
+	})
+}
+
+type localizedWriter struct {
+	http.ResponseWriter
+	st     *langState
+	status int
+}
+
+func (w *localizedWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *localizedWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.status < 400 {
+		return w.ResponseWriter.Write(b)
+	}
+	l, explicit := w.st.get()
+	if !explicit {
+		return w.ResponseWriter.Write(b)
+	}
+	ct := w.Header().Get("Content-Type")
+	var out []byte
+	switch {
+	case strings.HasPrefix(ct, "text/plain"):
+		out = []byte(i18n.Translate(l, strings.TrimRight(string(b), "\n")) + "\n")
+	case strings.HasPrefix(ct, "application/json"):
+		out = translateJSONError(l, b)
+	default:
+		out = b
+	}
+	if _, err := w.ResponseWriter.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *localizedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// translateJSONError traduz "error"/"message" de um objeto JSON; outro formato passa direto.
+func translateJSONError(l i18n.Lang, b []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return b
+	}
+	changed := false
+	for _, k := range []string{"error", "message"} {
+		if s, ok := m[k].(string); ok {
+			if t := i18n.Translate(l, s); t != s {
+				m[k] = t
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return b
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return b
+	}
+	return append(out, '\n')
+}
@@ -0,0 +1,709 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"anima/internal/i18n"
+)
+
+// Lesões/limitações do usuário: região do corpo + padrões de movimento a evitar,
+// com severidade e vigência. Enquanto ativas, a geração (buildPlanV11/PlanWeekly)
+// e as substituições excluem ou trocam exercícios contraindicados.
+// Dor alta registrada por série (workout_sets.pain) também exclui o exercício
+// por algumas semanas.
+
+const (
+	painExcludeMin = 5  // dor (0–10) a partir da qual o exercício é evitado
+	painWindowDays = 14 // janela da dor registrada
+)
+
+// padrões de movimento reconhecidos
+var movementPatternSet = map[string]bool{
+	"overhead_press": true, "horizontal_press": true, "dip": true, "shoulder_raise": true,
+	"vertical_pull": true, "horizontal_pull": true,
+	"squat": true, "hinge": true, "lunge": true, "jump": true,
+	"knee_extension": true, "knee_flexion": true, "calf_raise": true,
+	"elbow_flexion": true, "elbow_extension": true,
+}
+
+// região => padrões contraindicados por severidade (cumulativo: moderate inclui mild)
+var regionPatterns = map[string][3][]string{
+	"shoulder":   {{"overhead_press"}, {"dip", "shoulder_raise"}, {"horizontal_press", "vertical_pull"}},
+	"elbow":      {{"elbow_extension"}, {"elbow_flexion", "dip"}, {"horizontal_press", "vertical_pull", "horizontal_pull"}},
+	"wrist":      {{"dip"}, {"horizontal_press", "elbow_flexion"}, {"overhead_press", "horizontal_pull", "vertical_pull"}},
+	"neck":       {{"overhead_press"}, {"shoulder_raise"}, {"hinge", "squat"}},
+	"lower_back": {{"hinge"}, {"squat"}, {"horizontal_pull", "lunge", "overhead_press"}},
+	"hip":        {{"lunge"}, {"hinge"}, {"squat"}},
+	"knee":       {{"jump"}, {"lunge", "knee_extension"}, {"squat"}},
+	"ankle":      {{"jump"}, {"lunge", "calf_raise"}, {"squat"}},
+}
+
+var severityLevels = map[string]int{"mild": 0, "moderate": 1, "severe": 2}
+
+type limitation struct {
+	ID            int64     `json:"id"`
+	BodyRegion    string    `json:"body_region"`
+	AvoidPatterns []string  `json:"avoid_patterns"`
+	Severity      string    `json:"severity"` // mild | moderate | severe
+	StartsOn      string    `json:"starts_on"`
+	EndsOn        *string   `json:"ends_on,omitempty"` // nil = sem previsão
+	Notes         *string   `json:"notes,omitempty"`
+	Active        bool      `json:"active"`
+	Blocked       []string  `json:"blocked_patterns"` // padrões efetivamente evitados
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type limitationIn struct {
+	BodyRegion    *string   `json:"body_region"`
+	AvoidPatterns *[]string `json:"avoid_patterns"`
+	Severity      *string   `json:"severity"`
+	StartsOn      *string   `json:"starts_on"`
+	EndsOn        *string   `json:"ends_on"` // "" limpa
+	Notes         *string   `json:"notes"`
+}
+
+// ExcludedExercise: exercício descartado por limitação ou dor.
+type ExcludedExercise struct {
+	ExercicioID int    `json:"exercicio_id"`
+	Nome        string `json:"nome"`
+	Reason      string `json:"reason"`
+}
+
+// blockedPatterns: padrões da região (pela severidade) + os informados.
+func blockedPatterns(region, severity string, avoid []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	if byLevel, ok := regionPatterns[region]; ok {
+		for lvl := 0; lvl <= severityLevels[severity]; lvl++ {
+			for _, p := range byLevel[lvl] {
+				add(p)
+			}
+		}
+	}
+	for _, p := range avoid {
+		add(p)
+	}
+	return out
+}
+
+// movementPatterns: heurística por nome (e grupo como fallback), como isCompound.
+func movementPatterns(name, mg string) []string {
+	n := strings.ToLower(name)
+	var out []string
+	has := func(kws ...string) bool {
+		for _, k := range kws {
+			if strings.Contains(n, k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has("flexora", "leg curl", "mesa flexora"):
+		out = append(out, "knee_flexion")
+	case has("rosca", "curl"):
+		out = append(out, "elbow_flexion")
+	}
+	if has("extensora", "leg extension") {
+		out = append(out, "knee_extension")
+	}
+	if has("desenvolvimento", "overhead", "militar", "shoulder press", "arnold", "push press") {
+		out = append(out, "overhead_press")
+	}
+	if has("supino", "bench", "flexão", "flexao", "push-up", "pushup", "crucifixo", "fly", "chest press") {
+		out = append(out, "horizontal_press")
+	}
+	if has("paralela", "dip", "mergulho") {
+		out = append(out, "dip")
+	}
+	if has("elevação lateral", "elevacao lateral", "elevação frontal", "elevacao frontal", "lateral raise", "front raise", "encolhimento", "shrug") {
+		out = append(out, "shoulder_raise")
+	}
+	if has("puxada", "pulldown", "barra fixa", "pull-up", "pullup", "chin-up") {
+		out = append(out, "vertical_pull")
+	}
+	if has("remada", "row") {
+		out = append(out, "horizontal_pull")
+	}
+	if has("agachamento", "squat", "leg press", "hack") {
+		out = append(out, "squat")
+	}
+	if has("terra", "deadlift", "stiff", "good morning", "romeno", "romanian", "hip thrust", "levantamento") {
+		out = append(out, "hinge")
+	}
+	if has("lunge", "passada", "afundo", "búlgaro", "bulgaro", "bulgarian", "step-up", "step up") {
+		out = append(out, "lunge")
+	}
+	if has("salto", "jump", "burpee", "pliometria") {
+		out = append(out, "jump")
+	}
+	if has("tríceps", "triceps", "testa", "francês", "frances", "pushdown", "coice", "kickback") {
+		out = append(out, "elbow_extension")
+	}
+	if has("panturrilha", "calf") {
+		out = append(out, "calf_raise")
+	}
+	if len(out) > 0 {
+		return out
+	}
+
+	// nome genérico: infere pelo grupo
+	switch strings.ToLower(mg) {
+	case "ombros", "shoulders":
+		return []string{"shoulder_raise"}
+	case "biceps":
+		return []string{"elbow_flexion"}
+	case "triceps":
+		return []string{"elbow_extension"}
+	case "panturrilhas", "calves":
+		return []string{"calf_raise"}
+	case "quadriceps":
+		return []string{"squat"}
+	case "posterior", "hamstrings", "lombar":
+		return []string{"hinge"}
+	}
+	return nil
+}
+
+// limitProfile: restrições vigentes do usuário (nil = nenhuma).
+type limitProfile struct {
+	blocked map[string]string // padrão => região que o bloqueia
+	painful map[int64]int     // exercício => dor máx. recente
+	lang    i18n.Lang         // idioma dos motivos de exclusão
+}
+
+func (lp *limitProfile) empty() bool {
+	return lp == nil || (len(lp.blocked) == 0 && len(lp.painful) == 0)
+}
+
+// contraindication: motivo para evitar o exercício ("" = liberado), no idioma
+// da requisição que carregou o perfil.
+func (lp *limitProfile) contraindication(id int64, name, mg string) string {
+	if lp.empty() {
+		return ""
+	}
+	if p, ok := lp.painful[id]; ok {
+		return i18n.T(lp.lang, "limit.pain", p, painWindowDays)
+	}
+	for _, pat := range movementPatterns(name, mg) {
+		if region, ok := lp.blocked[pat]; ok {
+			return i18n.T(lp.lang, "limit.pattern", region, pat)
+		}
+	}
+	return ""
+}
+
+// loadLimits: limitações ativas hoje + exercícios com dor alta recente.
+func loadLimits(ctx context.Context, db *sql.DB, uid string) (*limitProfile, error) {
+	if uid == "" {
+		return nil, nil
+	}
+	lp := &limitProfile{blocked: map[string]string{}, painful: map[int64]int{}, lang: langOf(ctx)}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT body_region, avoid_patterns, severity
+		FROM user_limitations
+		WHERE user_id = $1
+		  AND starts_on <= $2::date
+		  AND (ends_on IS NULL OR ends_on >= $2::date)
+	`, uid, utcDay(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			region, sev string
+			avoid       []string
+		)
+		if err := rows.Scan(&region, pq.Array(&avoid), &sev); err != nil {
+			return nil, err
+		}
+		for _, p := range blockedPatterns(region, sev, avoid) {
+			if _, ok := lp.blocked[p]; !ok {
+				lp.blocked[p] = region
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// sem filtro de flagged: a suspeita é sobre carga/reps, a dor relatada vale igual
+	prow, err := db.QueryContext(ctx, `
+		SELECT s.exercicio_id, MAX(s.pain)
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE ws.user_id = $1
+		  AND s.pain >= $2
+		  AND ws.started_at >= NOW() - make_interval(days => $3)
+		GROUP BY s.exercicio_id
+	`, uid, painExcludeMin, painWindowDays)
+	if err != nil {
+		return nil, err
+	}
+	defer prow.Close()
+	for prow.Next() {
+		var (
+			id int64
+			p  int
+		)
+		if err := prow.Scan(&id, &p); err != nil {
+			return nil, err
+		}
+		lp.painful[id] = p
+	}
+	return lp, prow.Err()
+}
+
+func normalizeRegion(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "ombro", "ombros", "shoulders":
+		s = "shoulder"
+	case "cotovelo":
+		s = "elbow"
+	case "punho", "pulso":
+		s = "wrist"
+	case "pescoço", "pescoco", "cervical":
+		s = "neck"
+	case "lombar", "costas", "lowerback", "lower back":
+		s = "lower_back"
+	case "quadril":
+		s = "hip"
+	case "joelho":
+		s = "knee"
+	case "tornozelo":
+		s = "ankle"
+	}
+	_, ok := regionPatterns[s]
+	return s, ok
+}
+
+func cleanPatterns(in []string) ([]string, bool) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, p := range in {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" || seen[p] {
+			continue
+		}
+		if !movementPatternSet[p] {
+			return nil, false
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out, true
+}
+
+func validDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// MeLimitations: lesões/limitações do usuário atual.
+// GET    /api/me/limitations[?active=true]
+// POST   /api/me/limitations        {body_region, avoid_patterns, severity, starts_on?, ends_on?, notes?}
+// PATCH  /api/me/limitations/{id}
+// DELETE /api/me/limitations/{id}
+func MeLimitations(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/limitations"), "/")
+		if rest == "" {
+			switch r.Method {
+			case http.MethodGet:
+				listLimitations(db, w, r, userID)
+			case http.MethodPost:
+				createLimitation(db, w, r, userID)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil || id <= 0 {
+			badRequest(w, "invalid limitation id")
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			patchLimitation(db, w, r, userID, id)
+		case http.MethodDelete:
+			res, err := db.ExecContext(r.Context(), `DELETE FROM user_limitations WHERE id = $1 AND user_id = $2`, id, userID)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if aff, _ := res.RowsAffected(); aff == 0 {
+				notFound(w)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+const limitationCols = `
+	SELECT id, body_region, avoid_patterns, severity,
+	       to_char(starts_on, 'YYYY-MM-DD'), to_char(ends_on, 'YYYY-MM-DD'), notes,
+	       (starts_on <= $2::date AND (ends_on IS NULL OR ends_on >= $2::date)) AS active,
+	       created_at
+	FROM user_limitations
+`
+
+func scanLimitation(sc interface{ Scan(...any) error }) (limitation, error) {
+	var l limitation
+	err := sc.Scan(&l.ID, &l.BodyRegion, pq.Array(&l.AvoidPatterns), &l.Severity,
+		&l.StartsOn, &l.EndsOn, &l.Notes, &l.Active, &l.CreatedAt)
+	if l.AvoidPatterns == nil {
+		l.AvoidPatterns = []string{}
+	}
+	l.Blocked = blockedPatterns(l.BodyRegion, l.Severity, l.AvoidPatterns)
+	return l, err
+}
+
+func listLimitations(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	q := limitationCols + ` WHERE user_id = $1`
+	if r.URL.Query().Get("active") == "true" {
+		q += ` AND starts_on <= $2::date AND (ends_on IS NULL OR ends_on >= $2::date)`
+	}
+	q += ` ORDER BY starts_on DESC, id DESC`
+
+	rows, err := db.QueryContext(r.Context(), q, userID, utcDay(time.Now()))
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	defer rows.Close()
+	items := []limitation{}
+	for rows.Next() {
+		l, err := scanLimitation(rows)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		items = append(items, l)
+	}
+	jsonWrite(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func fetchLimitation(ctx context.Context, db *sql.DB, userID string, id int64) (*limitation, error) {
+	l, err := scanLimitation(db.QueryRowContext(ctx, limitationCols+` WHERE user_id = $1 AND id = $3`,
+		userID, utcDay(time.Now()), id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func createLimitation(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	var in limitationIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	if in.BodyRegion == nil {
+		badRequest(w, "body_region required")
+		return
+	}
+	region, ok := normalizeRegion(*in.BodyRegion)
+	if !ok {
+		badRequest(w, "body_region must be one of "+strings.Join(limitationRegions(), "|"))
+		return
+	}
+	avoid := []string{}
+	if in.AvoidPatterns != nil {
+		if avoid, ok = cleanPatterns(*in.AvoidPatterns); !ok {
+			badRequest(w, "unknown movement pattern in avoid_patterns")
+			return
+		}
+	}
+	sev := "moderate"
+	if in.Severity != nil {
+		sev = strings.ToLower(strings.TrimSpace(*in.Severity))
+		if _, ok := severityLevels[sev]; !ok {
+			badRequest(w, "severity must be mild|moderate|severe")
+			return
+		}
+	}
+	starts := utcDay(time.Now())
+	if in.StartsOn != nil && *in.StartsOn != "" {
+		if !validDate(*in.StartsOn) {
+			badRequest(w, "starts_on must be YYYY-MM-DD")
+			return
+		}
+		starts = *in.StartsOn
+	}
+	var ends *string
+	if in.EndsOn != nil && *in.EndsOn != "" {
+		if !validDate(*in.EndsOn) || *in.EndsOn < starts {
+			badRequest(w, "ends_on must be YYYY-MM-DD and not before starts_on")
+			return
+		}
+		ends = in.EndsOn
+	}
+
+	var id int64
+	err := db.QueryRowContext(r.Context(), `
+		INSERT INTO user_limitations (user_id, body_region, avoid_patterns, severity, starts_on, ends_on, notes)
+		VALUES ($1, $2, $3, $4, $5::date, $6::date, $7)
+		RETURNING id
+	`, userID, region, pq.Array(avoid), sev, starts, ends, in.Notes).Scan(&id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	l, err := fetchLimitation(r.Context(), db, userID, id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusCreated, l)
+}
+
+func patchLimitation(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string, id int64) {
+	var in limitationIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+
+	setParts := []string{}
+	args := []any{}
+	argIdx := 1
+
+	if in.BodyRegion != nil {
+		region, ok := normalizeRegion(*in.BodyRegion)
+		if !ok {
+			badRequest(w, "body_region must be one of "+strings.Join(limitationRegions(), "|"))
+			return
+		}
+		setParts = append(setParts, "body_region = $"+itoa(argIdx))
+		args = append(args, region)
+		argIdx++
+	}
+	if in.AvoidPatterns != nil {
+		avoid, ok := cleanPatterns(*in.AvoidPatterns)
+		if !ok {
+			badRequest(w, "unknown movement pattern in avoid_patterns")
+			return
+		}
+		setParts = append(setParts, "avoid_patterns = $"+itoa(argIdx))
+		args = append(args, pq.Array(avoid))
+		argIdx++
+	}
+	if in.Severity != nil {
+		sev := strings.ToLower(strings.TrimSpace(*in.Severity))
+		if _, ok := severityLevels[sev]; !ok {
+			badRequest(w, "severity must be mild|moderate|severe")
+			return
+		}
+		setParts = append(setParts, "severity = $"+itoa(argIdx))
+		args = append(args, sev)
+		argIdx++
+	}
+	if in.StartsOn != nil {
+		if !validDate(*in.StartsOn) {
+			badRequest(w, "starts_on must be YYYY-MM-DD")
+			return
+		}
+		setParts = append(setParts, "starts_on = $"+itoa(argIdx)+"::date")
+		args = append(args, *in.StartsOn)
+		argIdx++
+	}
+	if in.EndsOn != nil {
+		var v any // "" => sem previsão
+		if *in.EndsOn != "" {
+			if !validDate(*in.EndsOn) {
+				badRequest(w, "ends_on must be YYYY-MM-DD")
+				return
+			}
+			v = *in.EndsOn
+		}
+		setParts = append(setParts, "ends_on = $"+itoa(argIdx)+"::date")
+		args = append(args, v)
+		argIdx++
+	}
+	if in.Notes != nil {
+		setParts = append(setParts, "notes = $"+itoa(argIdx))
+		args = append(args, nullIfEmpty(strings.TrimSpace(*in.Notes)))
+		argIdx++
+	}
+	if len(setParts) == 0 {
+		badRequest(w, "no fields to update")
+		return
+	}
+
+	q := `UPDATE user_limitations SET ` + strings.Join(setParts, ", ") + `, updated_at = NOW()` +
+		` WHERE id = $` + itoa(argIdx) + ` AND user_id = $` + itoa(argIdx+1)
+	args = append(args, id, userID)
+	res, err := db.ExecContext(r.Context(), q, args...)
+	if err != nil {
+		// CHECK (ends_on >= starts_on)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23514" {
+			badRequest(w, "ends_on must not be before starts_on")
+			return
+		}
+		internalErr(w, err)
+		return
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		notFound(w)
+		return
+	}
+
+	l, err := fetchLimitation(r.Context(), db, userID, id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusOK, l)
+}
+
+func limitationRegions() []string {
+	out := make([]string, 0, len(regionPatterns))
+	for k := range regionPatterns {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ExerciseSubstitutes: alternativas do mesmo grupo, sem contraindicação para o usuário.
+// GET /api/exercises/{id}/substitutes?limit=5
+func ExerciseSubstitutes(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/exercises/"), "/"), "/")
+		if len(parts) != 2 || parts[1] != "substitutes" {
+			notFound(w)
+			return
+		}
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || id <= 0 {
+			badRequest(w, "invalid exercise id")
+			return
+		}
+		uid := strings.TrimSpace(GetUserID(r))
+		limit := clampInt(parseInt(r.URL.Query().Get("limit"), 5), 1, 20)
+
+		var src exRow
+		err = db.QueryRowContext(r.Context(), `
+			SELECT id, name, lower(muscle_group), lower(difficulty), COALESCE(is_bodyweight, false)
+			FROM exercises
+			WHERE id = $1 AND `+exerciseVisibleSQL("owner_user_id", 2)+`
+		`, id, uid).Scan(&src.id, &src.name, &src.muscleGroup, &src.difficulty, &src.isBodyweight)
+		if err == sql.ErrNoRows {
+			notFound(w)
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+
+		lp, err := loadLimits(r.Context(), db, uid)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+
+		items, excluded, err := findSubstitutes(r.Context(), db, uid, src, lp, limit)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		resp := map[string]any{
+			"exercicio_id": src.id,
+			"nome":         src.name,
+			"items":        items,
+			"excluded":     excluded,
+		}
+		if why := lp.contraindication(int64(src.id), src.name, src.muscleGroup); why != "" {
+			resp["contraindicated"] = why
+		}
+		jsonWrite(w, http.StatusOK, resp)
+	})
+}
+
+// findSubstitutes: mesmo grupo do src, sem contraindicados; prioriza mesma
+// dificuldade e mesmo tipo (composto/isolado).
+func findSubstitutes(ctx context.Context, db *sql.DB, uid string, src exRow, lp *limitProfile, limit int) ([]ExerciseItem, []ExcludedExercise, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, name, lower(muscle_group), lower(difficulty), COALESCE(is_bodyweight, false)
+		FROM exercises
+		WHERE id <> $1
+		  AND lower(muscle_group) = ANY($3)
+		  AND `+exerciseVisibleSQL("owner_user_id", 2)+`
+		ORDER BY (lower(difficulty) = $4) DESC, id ASC
+		LIMIT 200
+	`, src.id, uid, pq.Array(normalizeGroupName(src.muscleGroup)), src.difficulty)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	srcCompound := isCompound(src.name, src.muscleGroup)
+	var same, other []ExerciseItem
+	excluded := []ExcludedExercise{}
+	for rows.Next() {
+		var e exRow
+		if err := rows.Scan(&e.id, &e.name, &e.muscleGroup, &e.difficulty, &e.isBodyweight); err != nil {
+			return nil, nil, err
+		}
+		if why := lp.contraindication(int64(e.id), e.name, e.muscleGroup); why != "" {
+			excluded = append(excluded, ExcludedExercise{ExercicioID: e.id, Nome: e.name, Reason: why})
+			continue
+		}
+		it := ExerciseItem{ID: int64(e.id), Nome: e.name, Grupo: e.muscleGroup}
+		if isCompound(e.name, e.muscleGroup) == srcCompound {
+			same = append(same, it)
+		} else {
+			other = append(other, it)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	items := append(same, other...)
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []ExerciseItem{}
+	}
+	return items, excluded, nil
+}
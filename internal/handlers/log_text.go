@@ -0,0 +1,520 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"anima/internal/i18n"
+)
+
+// Registro de séries por texto livre (pt-BR/EN), uma linha por exercício:
+//
+//	"supino reto 3x10 60kg rir 2"
+//	"agachamento 100kg x 5, 5, 4"
+//	"bench press 3 sets of 8 @ 135 lbs rpe 8"
+//
+// O exercício é resolvido contra o catálogo visível ao usuário (sem acento,
+// com apelidos de exercise_aliases e o nome em inglês). Linhas ambíguas/não resolvidas não são
+// gravadas e voltam com candidatos; o cliente reenvia com `overrides`.
+//
+// "N x M" sem unidade é ambíguo: N vira nº de séries só até logBareSets (ou
+// quando a carga veio em outro trecho); acima disso é carga em kg, com aviso
+// ("leg press 12 x 10" = 12 kg x 10, "100 x 5, 5, 4" = 100 kg).
+
+var (
+	reRIR      = regexp.MustCompile(`\brir\s*:?\s*(\d+)`)
+	reRPE      = regexp.MustCompile(`\brpe\s*:?\s*(\d+(?:[.,]\d)?)`)
+	reWeight   = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*(kgs?|quilos?|lbs?|libras?)\b`)
+	reSetsOf   = regexp.MustCompile(`\b(\d+)\s*(?:sets?|series?)\s*(?:of|de)\s*(\d+)(?:\s*(?:reps?|repeticoes))?\b`)
+	reNxList   = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*x\s*(\d+(?:\s*[,/]\s*\d+)*)`)
+	reXList    = regexp.MustCompile(`(?:^|\s)x\s*(\d+(?:\s*[,/]\s*\d+)*)`)
+	reRepsWord = regexp.MustCompile(`\b(\d+(?:\s*[,/]\s*\d+)*)\s*(?:reps?|repeticoes)\b`)
+	reAt       = regexp.MustCompile(`@\s*(\d+(?:[.,]\d+)?)`)
+	reListSep  = regexp.MustCompile(`\s*[,/]\s*`)
+)
+
+const (
+	lbToKg       = 0.45359237
+	logTextMaxLn = 30
+	logMaxSets   = 12
+	logBareSets  = 6 // "N x M" sem carga em outro trecho: N ≤ 6 => séries
+)
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n", "×", "x", "°", " ", "º", " ",
+)
+
+// foldText: minúsculas e sem acento.
+func foldText(s string) string {
+	return accentFolder.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+var nameStopwords = map[string]bool{
+	"com": true, "de": true, "da": true, "do": true, "na": true, "no": true, "e": true,
+	"a": true, "o": true, "with": true, "the": true, "on": true, "-": true,
+}
+
+func nameTokens(s string) []string {
+	f := strings.FieldsFunc(foldText(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	out := f[:0]
+	for _, t := range f {
+		if !nameStopwords[t] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// ParsedSetLine: linha interpretada; Invalid (não serializado) recusa o pedido.
+type ParsedSetLine struct {
+	Line     int      `json:"line"`
+	Text     string   `json:"text"`
+	Query    string   `json:"exercise_query,omitempty"`
+	WeightKg *float64 `json:"weight_kg,omitempty"`
+	Reps     []int    `json:"reps,omitempty"`
+	RIR      *int     `json:"rir,omitempty"`
+
+	ExercicioID   *int64         `json:"exercicio_id,omitempty"`
+	ExercicioNome string         `json:"exercicio_nome,omitempty"`
+	Status        string         `json:"status"` // logged | parsed | ambiguous | unresolved | unparsed
+	Candidates    []ExerciseItem `json:"candidates,omitempty"`
+	SetIDs        []int64        `json:"set_ids,omitempty"`
+	Warnings      []string       `json:"warnings,omitempty"`
+	Invalid       string         `json:"-"`
+}
+
+func parseNum(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return v
+}
+
+func parseRepList(s string) []int {
+	var out []int
+	for _, p := range reListSep.Split(strings.TrimSpace(s), -1) {
+		if n, err := strconv.Atoi(p); err == nil && n > 0 {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func repeatInt(v, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
+
+// parseSetLine extrai exercício, carga, reps e RIR de uma linha (regra pura).
+// Trechos reconhecidos são trocados por espaços para manter as posições; o nome
+// do exercício é o texto antes do primeiro trecho reconhecido.
+func parseSetLine(lang i18n.Lang, n int, text string) ParsedSetLine {
+	out := ParsedSetLine{Line: n, Text: strings.TrimSpace(text), Status: "unparsed"}
+	s := foldText(text)
+	first := len(s)
+	take := func(re *regexp.Regexp) []string {
+		loc := re.FindStringSubmatchIndex(s)
+		if loc == nil {
+			return nil
+		}
+		first = min(first, loc[0])
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = s[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		s = s[:loc[0]] + strings.Repeat(" ", loc[1]-loc[0]) + s[loc[1]:]
+		return m
+	}
+
+	// mesmos limites de SetsCreate (pain 0–10): fora disso recusa, não só sinaliza
+	if m := take(reRIR); m != nil {
+		v, _ := strconv.Atoi(m[1])
+		if v > 10 {
+			out.Invalid = "rir must be between 0 and 10"
+		}
+		out.RIR = &v
+	} else if m := take(reRPE); m != nil {
+		rpe := parseNum(m[1])
+		if rpe < 1 || rpe > 10 {
+			out.Invalid = "rpe must be between 1 and 10"
+		}
+		v := int(math.Round(10 - rpe))
+		v = max(v, 0)
+		out.RIR = &v
+	}
+	if m := take(reWeight); m != nil {
+		v := parseNum(m[1])
+		if strings.HasPrefix(m[2], "lb") || strings.HasPrefix(m[2], "libra") {
+			v = roundTo(v*lbToKg, 0.25)
+		}
+		out.WeightKg = &v
+	}
+
+	if m := take(reSetsOf); m != nil {
+		sets, _ := strconv.Atoi(m[1])
+		reps, _ := strconv.Atoi(m[2])
+		if sets > 0 && sets <= logMaxSets && reps > 0 {
+			out.Reps = repeatInt(reps, sets)
+		}
+	} else if m := take(reNxList); m != nil {
+		a := parseNum(m[1])
+		list := parseRepList(m[2])
+		limit := logMaxSets
+		if out.WeightKg == nil {
+			limit = logBareSets
+		}
+		isCount := a == math.Trunc(a) && a > 0 && a <= float64(limit)
+		switch {
+		case out.WeightKg == nil && !isCount:
+			// "100 x 5, 5, 4" / "12 x 10": carga sem unidade × reps, assumida em kg
+			out.WeightKg = &a
+			out.Reps = list
+			out.Warnings = append(out.Warnings, i18n.T(lang, "logtext.unitless_load", a))
+		case !isCount:
+			// carga já informada e N não é nº de séries: não adivinha
+		case len(list) == 1:
+			out.Reps = repeatInt(list[0], int(a))
+		default:
+			out.Reps = list
+		}
+	} else if m := take(reXList); m != nil {
+		out.Reps = parseRepList(m[1])
+	} else if m := take(reRepsWord); m != nil {
+		out.Reps = parseRepList(m[1])
+	}
+	if out.WeightKg == nil {
+		if m := take(reAt); m != nil {
+			v := parseNum(m[1])
+			out.WeightKg = &v
+		}
+	}
+
+	name := strings.Trim(foldText(text)[:first], " :-–,;")
+	out.Query = strings.Join(strings.Fields(name), " ")
+	if out.Query == "" || len(out.Reps) == 0 || len(out.Reps) > logMaxSets {
+		out.Reps = nil
+		return out
+	}
+	out.Status = "parsed"
+	return out
+}
+
+// ===== resolução no catálogo
+
+type catalogEntry struct {
+	item   ExerciseItem
+	tokens []string
+	names  []string // nome + apelidos, normalizados
+}
+
+func loadCatalog(ctx context.Context, db *sql.DB, uid string) ([]catalogEntry, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT e.id, e.name, e.muscle_group, e.owner_user_id IS NOT NULL, COALESCE(e.name_en, ''),
+		       COALESCE(array_agg(a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}')
+		FROM exercises e
+		LEFT JOIN exercise_aliases a ON a.exercicio_id = e.id
+		WHERE `+exerciseVisibleSQL("e.owner_user_id", 1)+`
+		GROUP BY e.id
+		ORDER BY e.id
+	`, strings.TrimSpace(uid))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []catalogEntry
+	for rows.Next() {
+		var (
+			c       catalogEntry
+			nameEN  string
+			aliases []string
+		)
+		if err := rows.Scan(&c.item.ID, &c.item.Nome, &c.item.Grupo, &c.item.Privado, &nameEN, pq.Array(&aliases)); err != nil {
+			return nil, err
+		}
+		if nameEN != "" {
+			aliases = append(aliases, nameEN)
+		}
+		out = append(out, newCatalogEntry(c.item, aliases))
+	}
+	return out, rows.Err()
+}
+
+// newCatalogEntry normaliza nome e apelidos (nome em inglês entra como apelido).
+func newCatalogEntry(it ExerciseItem, aliases []string) catalogEntry {
+	c := catalogEntry{item: it, tokens: nameTokens(it.Nome)}
+	c.names = append(c.names, strings.Join(c.tokens, " "))
+	for _, a := range aliases {
+		c.names = append(c.names, strings.Join(nameTokens(a), " "))
+	}
+	return c
+}
+
+// tokenMatch: igual ou prefixo (≥4 letras) — "halter" ~ "halteres".
+func tokenMatch(q, n string) bool {
+	if q == n {
+		return true
+	}
+	return len(q) >= 4 && len(n) >= 4 && (strings.HasPrefix(n, q) || strings.HasPrefix(q, n))
+}
+
+// coverage: fração dos tokens da consulta presentes em algum nome/apelido.
+func (c catalogEntry) coverage(q []string) float64 {
+	best := 0.0
+	for _, name := range c.names {
+		nt := strings.Fields(name)
+		hit := 0
+		for _, t := range q {
+			for _, u := range nt {
+				if tokenMatch(t, u) {
+					hit++
+					break
+				}
+			}
+		}
+		best = math.Max(best, float64(hit)/float64(len(q)))
+	}
+	return best
+}
+
+// resolveExercise: (exercício, candidatos). Exato (nome/apelido) ou um único
+// com cobertura total resolve; senão devolve até 5 candidatos.
+func resolveExercise(cat []catalogEntry, query string) (*ExerciseItem, []ExerciseItem) {
+	q := nameTokens(query)
+	if len(q) == 0 {
+		return nil, nil
+	}
+	joined := strings.Join(q, " ")
+	for _, c := range cat {
+		for _, n := range c.names {
+			if n == joined {
+				it := c.item
+				return &it, nil
+			}
+		}
+	}
+
+	type scored struct {
+		it    ExerciseItem
+		cov   float64
+		extra int
+	}
+	var cands []scored
+	for _, c := range cat {
+		if cov := c.coverage(q); cov >= 0.5 {
+			cands = append(cands, scored{c.item, cov, len(c.tokens) - len(q)})
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].cov != cands[j].cov {
+			return cands[i].cov > cands[j].cov
+		}
+		return cands[i].extra < cands[j].extra
+	})
+	full := 0
+	for _, c := range cands {
+		if c.cov == 1 {
+			full++
+		}
+	}
+	if full == 1 {
+		it := cands[0].it
+		return &it, nil
+	}
+	out := []ExerciseItem{}
+	for i := 0; i < len(cands) && i < 5; i++ {
+		out = append(out, cands[i].it)
+	}
+	return nil, out
+}
+
+// ParseSetLine exportado pros testes
+func ParseSetLine(lang i18n.Lang, text string) ParsedSetLine { return parseSetLine(lang, 1, text) }
+
+// ResolveExercise exportado pros testes (aliases por id do exercício)
+func ResolveExercise(items []ExerciseItem, aliases map[int64][]string, query string) (*ExerciseItem, []ExerciseItem) {
+	cat := make([]catalogEntry, 0, len(items))
+	for _, it := range items {
+		cat = append(cat, newCatalogEntry(it, aliases[it.ID]))
+	}
+	return resolveExercise(cat, query)
+}
+
+// ===== handler
+
+type logTextReq struct {
+	Text      string           `json:"text"`
+	DryRun    bool             `json:"dry_run"`
+	Overrides map[string]int64 `json:"overrides"` // nº da linha -> exercicio_id
+}
+
+// POST /api/sessions/{id}/log-text  {"text": "...", "dry_run": false, "overrides": {"2": 5}}
+func SessionLogText(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rest := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/sessions/"), "/log-text")
+		sessionID, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil || sessionID <= 0 {
+			badRequest(w, "invalid session id")
+			return
+		}
+		uid := strings.TrimSpace(GetUserID(r))
+		if uid != "" {
+			var ok bool
+			if err := db.QueryRowContext(r.Context(), `SELECT EXISTS(SELECT 1 FROM workout_sessions WHERE id=$1 AND user_id=$2)`, sessionID, uid).Scan(&ok); err != nil || !ok {
+				http.NotFound(w, r)
+				return
+			}
+		}
+
+		var in logTextReq
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Text) == "" {
+			badRequest(w, "text is required")
+			return
+		}
+		if q := r.URL.Query().Get("dry_run"); q != "" {
+			in.DryRun = q == "true" || q == "1"
+		}
+
+		var lines []string
+		for _, l := range strings.FieldsFunc(in.Text, func(r rune) bool { return r == '\n' || r == ';' }) {
+			if strings.TrimSpace(l) != "" {
+				lines = append(lines, l)
+			}
+		}
+		if len(lines) > logTextMaxLn {
+			badRequest(w, "too many lines (max "+strconv.Itoa(logTextMaxLn)+")")
+			return
+		}
+
+		cat, err := loadCatalog(r.Context(), db, uid)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		byID := map[int64]ExerciseItem{}
+		for _, c := range cat {
+			byID[c.item.ID] = c.item
+		}
+
+		lang := requestLang(r)
+		out := make([]ParsedSetLine, 0, len(lines))
+		for i, l := range lines {
+			p := parseSetLine(lang, i+1, l)
+			if p.Invalid != "" {
+				jsonWrite(w, http.StatusBadRequest, map[string]any{"error": p.Invalid, "line": p.Line})
+				return
+			}
+			if p.Status == "parsed" {
+				if id, ok := in.Overrides[strconv.Itoa(p.Line)]; ok {
+					it, ok := byID[id]
+					if !ok {
+						badRequest(w, "override for line "+strconv.Itoa(p.Line)+": unknown exercise")
+						return
+					}
+					p.ExercicioID, p.ExercicioNome = &it.ID, it.Nome
+				} else if it, cands := resolveExercise(cat, p.Query); it != nil {
+					p.ExercicioID, p.ExercicioNome = &it.ID, it.Nome
+				} else if len(cands) > 0 {
+					p.Status, p.Candidates = "ambiguous", cands
+				} else {
+					p.Status = "unresolved"
+				}
+			}
+			out = append(out, p)
+		}
+
+		created := 0
+		if !in.DryRun {
+			if created, err = insertParsedSets(r.Context(), db, uid, sessionID, out); err != nil {
+				internalErr(w, err)
+				return
+			}
+		}
+
+		pending := []int{}
+		for _, p := range out {
+			if p.Status == "ambiguous" || p.Status == "unresolved" || p.Status == "unparsed" {
+				pending = append(pending, p.Line)
+			}
+		}
+		status := http.StatusOK
+		if created > 0 {
+			status = http.StatusCreated
+		}
+		jsonWrite(w, status, map[string]any{
+			"session_id":   sessionID,
+			"dry_run":      in.DryRun,
+			"created":      created,
+			"lines":        out,
+			"needs_review": pending,
+		})
+	})
+}
+
+// insertParsedSets grava as linhas resolvidas (set_index continua a sequência
+// do exercício na sessão); séries suspeitas entram com flagged (ver set_anomaly.go).
+func insertParsedSets(ctx context.Context, db *sql.DB, uid string, sessionID int64, lines []ParsedSetLine) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	created := 0
+	for i := range lines {
+		p := &lines[i]
+		if p.Status != "parsed" {
+			continue
+		}
+		var next int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(MAX(set_index), 0) + 1
+			FROM workout_sets WHERE session_id = $1 AND exercicio_id = $2
+		`, sessionID, *p.ExercicioID).Scan(&next); err != nil {
+			return 0, err
+		}
+		for j, reps := range p.Reps {
+			warnings, err := setAnomalies(ctx, db, uid, *p.ExercicioID, p.WeightKg, &reps, p.RIR, 0)
+			if err != nil {
+				return 0, err
+			}
+			var id int64
+			if err := tx.QueryRowContext(ctx, `
+				INSERT INTO workout_sets
+				  (session_id, exercicio_id, set_index, weight_kg, reps, rir, completed, flagged, flag_reasons)
+				VALUES ($1,$2,$3,$4,$5,$6,TRUE,$7,$8)
+				RETURNING id
+			`, sessionID, *p.ExercicioID, next+j, p.WeightKg, reps, p.RIR,
+				len(warnings) > 0, pq.Array(warnings)).Scan(&id); err != nil {
+				return 0, err
+			}
+			p.SetIDs = append(p.SetIDs, id)
+			p.Warnings = append(p.Warnings, warnings...)
+			created++
+		}
+		p.Status = "logged"
+	}
+	return created, tx.Commit()
+}
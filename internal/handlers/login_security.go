@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Contenção de força bruta no login.
+// Tentativas contam por conta ('acct:<email>', inclusive e-mails inexistentes)
+// e por IP ('ip:<ip>'). Cada tentativa é reservada antes de conferir a senha,
+// na mesma transação (FOR UPDATE) que confere a espera: tentativas paralelas
+// não passam juntas pelo contador. A partir de LOGIN_BACKOFF_AFTER falhas cada
+// nova tentativa espera base*2^n (até LOGIN_BACKOFF_MAX_SEC); com
+// LOGIN_LOCKOUT_THRESHOLD falhas a conta trava por LOGIN_LOCKOUT_MIN (o IP com
+// LOGIN_IP_LOCKOUT_THRESHOLD). Falhas mais antigas que LOGIN_FAILURE_WINDOW_MIN
+// são esquecidas. Login certo zera a conta e devolve a tentativa do IP; reset
+// de senha destrava a conta. O mesmo mecanismo limita os e-mails de conta.
+
+// LoginThrottle: parâmetros de backoff/bloqueio de uma chave.
+type LoginThrottle struct {
+	BackoffAfter int           // falhas livres antes do backoff
+	BackoffBase  time.Duration // primeira espera
+	BackoffMax   time.Duration
+	LockAt       int // falhas que travam a chave (0 = nunca)
+	LockFor      time.Duration
+	Window       time.Duration // falhas mais antigas são esquecidas
+}
+
+// LoginThrottleFromEnv: limites por conta (LOGIN_*).
+func LoginThrottleFromEnv() LoginThrottle {
+	return LoginThrottle{
+		BackoffAfter: atoiEnvInt("LOGIN_BACKOFF_AFTER", 3),
+		BackoffBase:  time.Duration(atoiEnvInt("LOGIN_BACKOFF_BASE_SEC", 1)) * time.Second,
+		BackoffMax:   time.Duration(atoiEnvInt("LOGIN_BACKOFF_MAX_SEC", 300)) * time.Second,
+		LockAt:       atoiEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LockFor:      time.Duration(atoiEnvInt("LOGIN_LOCKOUT_MIN", 30)) * time.Minute,
+		Window:       time.Duration(atoiEnvInt("LOGIN_FAILURE_WINDOW_MIN", 15)) * time.Minute,
+	}
+}
+
+// loginIPThrottle: como a conta, com o limite de bloqueio do IP.
+func loginIPThrottle() LoginThrottle {
+	c := LoginThrottleFromEnv()
+	c.LockAt = atoiEnvInt("LOGIN_IP_LOCKOUT_THRESHOLD", 100)
+	return c
+}
+
+// accountMailThrottle: verificação/reset por e-mail. O 1º envio é livre, o
+// seguinte espera ACCOUNT_MAIL_INTERVAL_SEC, dobrando até 1h; esquecido após 1h.
+func accountMailThrottle(free int) LoginThrottle {
+	return LoginThrottle{
+		BackoffAfter: free,
+		BackoffBase:  time.Duration(atoiEnvInt("ACCOUNT_MAIL_INTERVAL_SEC", 60)) * time.Second,
+		BackoffMax:   time.Hour,
+		Window:       time.Hour,
+	}
+}
+
+// Backoff: espera exigida após n falhas seguidas.
+func (c LoginThrottle) Backoff(failures int) time.Duration {
+	if failures < c.BackoffAfter {
+		return 0
+	}
+	exp := failures - c.BackoffAfter
+	if exp > 30 {
+		return c.BackoffMax
+	}
+	d := time.Duration(float64(c.BackoffBase) * math.Pow(2, float64(exp)))
+	if d > c.BackoffMax {
+		d = c.BackoffMax
+	}
+	return d
+}
+
+// RetryAfter: quanto falta para a próxima tentativa (0 = pode tentar), dadas
+// as falhas, a última em last e o bloqueio até lockedUntil (zero = nenhum).
+func (c LoginThrottle) RetryAfter(failures int, last, lockedUntil, now time.Time) time.Duration {
+	if lockedUntil.After(now) {
+		return lockedUntil.Sub(now)
+	}
+	if now.Sub(last) > c.Window {
+		return 0
+	}
+	if next := last.Add(c.Backoff(failures)); next.After(now) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+// Step conta uma tentativa em now: novo total e bloqueio (zero = não travou agora).
+// Fora da janela (e sem bloqueio vigente) a contagem recomeça.
+func (c LoginThrottle) Step(failures int, last, lockedUntil, now time.Time) (int, time.Time) {
+	if now.Sub(last) > c.Window && !lockedUntil.After(now) {
+		failures = 0
+	}
+	failures++
+	if c.LockAt > 0 && failures >= c.LockAt {
+		return failures, now.Add(c.LockFor)
+	}
+	return failures, time.Time{}
+}
+
+func acctThrottleKey(email string) string { return "acct:" + email }
+func ipThrottleKey(ip string) string      { return "ip:" + ip }
+
+type throttleKey struct {
+	key string
+	cfg LoginThrottle
+}
+
+// throttleHit: resultado da reserva numa chave.
+type throttleHit struct {
+	failures int
+	locked   bool // esta tentativa travou a chave
+}
+
+// reserveAttempt conta uma tentativa em todas as chaves, ou em nenhuma se
+// alguma ainda estiver em espera (devolve a maior espera). As linhas ficam
+// travadas (em ordem de chave) durante a checagem e o incremento.
+func reserveAttempt(ctx context.Context, db *sql.DB, keys ...throttleKey) ([]throttleHit, time.Duration, error) {
+	order := make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return keys[order[a]].key < keys[order[b]].key })
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	type state struct {
+		failures    int
+		last        time.Time
+		lockedUntil time.Time
+	}
+	now := time.Now()
+	states := make([]state, len(keys))
+	var wait time.Duration
+	for _, i := range order {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO login_throttle (key, failures, last_failure_at) VALUES ($1, 0, to_timestamp(0))
+			ON CONFLICT (key) DO NOTHING`, keys[i].key); err != nil {
+			return nil, 0, err
+		}
+		var locked sql.NullTime
+		if err := tx.QueryRowContext(ctx, `
+			SELECT failures, last_failure_at, locked_until FROM login_throttle WHERE key = $1 FOR UPDATE`,
+			keys[i].key).Scan(&states[i].failures, &states[i].last, &locked); err != nil {
+			return nil, 0, err
+		}
+		if locked.Valid {
+			states[i].lockedUntil = locked.Time
+		}
+		st := states[i]
+		wait = max(wait, keys[i].cfg.RetryAfter(st.failures, st.last, st.lockedUntil, now))
+	}
+	if wait > 0 {
+		return nil, wait, nil
+	}
+
+	hits := make([]throttleHit, len(keys))
+	for _, i := range order {
+		st := states[i]
+		n, until := keys[i].cfg.Step(st.failures, st.last, st.lockedUntil, now)
+		var lockedUntil any
+		if !until.IsZero() {
+			lockedUntil = until
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE login_throttle
+			SET failures = $2, last_failure_at = $3, locked_until = COALESCE($4, locked_until)
+			WHERE key = $1`, keys[i].key, n, now, lockedUntil); err != nil {
+			return nil, 0, err
+		}
+		hits[i] = throttleHit{failures: n, locked: !until.IsZero()}
+	}
+	return hits, 0, tx.Commit()
+}
+
+// refundAttempt devolve a tentativa reservada de uma chave (login certo);
+// desfaz o bloqueio se foi esta tentativa que travou.
+func refundAttempt(ctx context.Context, db *sql.DB, key string, hit throttleHit) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE login_throttle
+		SET failures = GREATEST(failures - 1, 0),
+		    locked_until = CASE WHEN $2 THEN NULL ELSE locked_until END
+		WHERE key = $1`, key, hit.locked)
+	return err
+}
+
+// Salt bcrypt de custo igual ao gen_salt('bf') para e-mails sem conta.
+const dummyBcryptSalt = "$2a$06$C6UzMDM.H6dfI/f/IKxGhu"
+
+// loginFailed registra os eventos de uma tentativa errada (já contada na reserva).
+func loginFailed(ctx context.Context, db *sql.DB, r *http.Request, userID, email, reason string, acct, ip throttleHit) {
+	lockMin := int(LoginThrottleFromEnv().LockFor.Minutes())
+	recordSecurityEvent(ctx, db, r, "login_failed", userID, email,
+		map[string]any{"reason": reason, "failures": acct.failures})
+	if acct.locked {
+		recordSecurityEvent(ctx, db, r, "account_locked", userID, email,
+			map[string]any{"failures": acct.failures, "minutes": lockMin})
+	}
+	if ip.locked {
+		recordSecurityEvent(ctx, db, r, "ip_locked", "", "",
+			map[string]any{"failures": ip.failures, "minutes": lockMin})
+	}
+}
+
+func clearLoginThrottle(ctx context.Context, ex execer, key string) error {
+	_, err := ex.ExecContext(ctx, `DELETE FROM login_throttle WHERE key = $1`, key)
+	return err
+}
+
+// recordSecurityEvent grava um evento para revisão (falha só é logada).
+func recordSecurityEvent(ctx context.Context, ex execer, r *http.Request, event, userID, email string, detail map[string]any) {
+	var ip, ua string
+	if r != nil {
+		ip, ua = clientIP(r), userAgent(r)
+	}
+	var d []byte
+	if len(detail) > 0 {
+		d, _ = json.Marshal(detail)
+	}
+	if _, err := ex.ExecContext(ctx, `
+		INSERT INTO security_events (event, user_id, email, ip, user_agent, detail)
+		VALUES ($1, NULLIF($2, '')::uuid, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6::jsonb)`,
+		event, userID, email, ip, ua, nullableJSON(d)); err != nil {
+		log.Printf("[security] %s: %v", event, err)
+	}
+}
+
+func nullableJSON(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+func writeTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	writeRetryAfter(w, wait, "too many login attempts")
+}
+
+func writeRetryAfter(w http.ResponseWriter, wait time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	jsonWrite(w, http.StatusTooManyRequests, map[string]string{"error": msg})
+}
+
+// reserveAccountMail limita e-mails de verificação/reset por destinatário
+// (exista a conta ou não) e por IP (ACCOUNT_MAIL_PER_IP livres por hora).
+// false = 429 já escrito.
+func reserveAccountMail(w http.ResponseWriter, r *http.Request, db *sql.DB, email string) bool {
+	_, wait, err := reserveAttempt(r.Context(), db,
+		throttleKey{"mail:" + email, accountMailThrottle(1)},
+		throttleKey{"mailip:" + clientIP(r), accountMailThrottle(atoiEnvInt("ACCOUNT_MAIL_PER_IP", 10))})
+	if err != nil {
+		internalErr(w, err)
+		return false
+	}
+	if wait > 0 {
+		writeRetryAfter(w, wait, "too many emails requested")
+		return false
+	}
+	return true
+}
+
+type securityEvent struct {
+	ID        int64           `json:"id"`
+	Event     string          `json:"event"`
+	UserID    string          `json:"user_id,omitempty"`
+	Email     string          `json:"email,omitempty"`
+	IP        string          `json:"ip,omitempty"`
+	UserAgent string          `json:"user_agent,omitempty"`
+	Detail    json.RawMessage `json:"detail,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AdminSecurityEvents: GET /api/admin/security/events?event=&user_id=&email=&since=RFC3339&limit=100
+func AdminSecurityEvents(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		limit := clampInt(parseInt(q.Get("limit"), 100), 1, 1000)
+		var since time.Time
+		if s := q.Get("since"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				badRequest(w, "invalid since (RFC3339)")
+				return
+			}
+			since = t
+		}
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT id, event, COALESCE(user_id::text, ''), COALESCE(email, ''), COALESCE(ip, ''),
+			       COALESCE(user_agent, ''), COALESCE(detail::text, ''), created_at
+			FROM security_events
+			WHERE ($1 = '' OR event = $1)
+			  AND ($2 = '' OR user_id::text = $2)
+			  AND ($3 = '' OR email = $3)
+			  AND created_at >= $4
+			ORDER BY created_at DESC, id DESC
+			LIMIT $5`,
+			strings.TrimSpace(q.Get("event")), strings.TrimSpace(q.Get("user_id")),
+			strings.ToLower(strings.TrimSpace(q.Get("email"))), since, limit)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer rows.Close()
+		items := []securityEvent{}
+		for rows.Next() {
+			var e securityEvent
+			var detail string
+			if err := rows.Scan(&e.ID, &e.Event, &e.UserID, &e.Email, &e.IP, &e.UserAgent, &detail, &e.CreatedAt); err != nil {
+				internalErr(w, err)
+				return
+			}
+			if detail != "" {
+				e.Detail = json.RawMessage(detail)
+			}
+			items = append(items, e)
+		}
+		if err := rows.Err(); err != nil {
+			internalErr(w, err)
+			return
+		}
+		jsonWrite(w, http.StatusOK, map[string]any{"items": items})
+	})
+}
+
+// AdminUnlockLogin: POST /api/admin/security/unlock {"email":"..."} ou {"ip":"..."}
+func AdminUnlockLogin(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var in struct {
+			Email string `json:"email"`
+			IP    string `json:"ip"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			badRequest(w, "invalid json")
+			return
+		}
+		email := strings.ToLower(strings.TrimSpace(in.Email))
+		ip := strings.TrimSpace(in.IP)
+		if (email == "") == (ip == "") {
+			badRequest(w, "provide either email or ip")
+			return
+		}
+		key := acctThrottleKey(email)
+		if ip != "" {
+			key = ipThrottleKey(ip)
+		}
+		if err := clearLoginThrottle(r.Context(), db, key); err != nil {
+			internalErr(w, err)
+			return
+		}
+		recordSecurityEvent(r.Context(), db, r, "login_unlocked", "", email,
+			map[string]any{"by": GetUserID(r), "key": key})
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
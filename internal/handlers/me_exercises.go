@@ -0,0 +1,482 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Exercícios personalizados: linhas de `exercises` com owner_user_id preenchido.
+// Como vivem na mesma tabela do catálogo, funcionam em sets, treinos, overload
+// e análises sem caminhos paralelos; só a visibilidade é restrita ao dono.
+
+type customExercise struct {
+	ID           int64     `json:"id"`
+	Nome         string    `json:"nome"`
+	Grupo        string    `json:"grupo"`
+	Equipamentos []string  `json:"equipamentos"`
+	Dificuldade  string    `json:"dificuldade"`
+	PesoCorporal bool      `json:"peso_corporal"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type customExerciseIn struct {
+	Nome         *string   `json:"nome"`
+	Grupo        *string   `json:"grupo"`
+	Equipamentos *[]string `json:"equipamentos"`
+	Dificuldade  *string   `json:"dificuldade"`
+	PesoCorporal *bool     `json:"peso_corporal"`
+}
+
+// filtro de visibilidade do catálogo: globais + privados do usuário
+func exerciseVisibleSQL(col string, argIdx int) string {
+	return "(" + col + " IS NULL OR " + col + " = $" + itoa(argIdx) + ")"
+}
+
+// exerciseVisible informa se o exercício existe e é visível para uid.
+func exerciseVisible(ctx context.Context, db *sql.DB, id int64, uid string) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS(
+		  SELECT 1 FROM exercises
+		  WHERE id = $1 AND `+exerciseVisibleSQL("owner_user_id", 2)+`
+		)`, id, strings.TrimSpace(uid)).Scan(&ok)
+	return ok, err
+}
+
+// MeExercises: catálogo privado do usuário atual.
+// GET    /api/me/exercises
+// POST   /api/me/exercises        {nome, grupo, equipamentos, dificuldade, peso_corporal}
+// PATCH  /api/me/exercises/{id}
+// DELETE /api/me/exercises/{id}
+// GET    /api/me/exercises/{id}/trend?weeks=8
+func MeExercises(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/exercises"), "/")
+		if rest == "" {
+			switch r.Method {
+			case http.MethodGet:
+				listCustomExercises(db, w, r, userID)
+			case http.MethodPost:
+				createCustomExercise(db, w, r, userID)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		// /api/me/exercises/{id}/trend (qualquer exercício visível)
+		if idStr, ok := strings.CutSuffix(rest, "/trend"); ok {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil || id <= 0 {
+				badRequest(w, "invalid exercise id")
+				return
+			}
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			exerciseTrendHandler(db, w, r, userID, id)
+			return
+		}
+
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil || id <= 0 {
+			badRequest(w, "invalid exercise id")
+			return
+		}
+		switch r.Method {
+		case http.MethodPatch:
+			patchCustomExercise(db, w, r, userID, id)
+		case http.MethodDelete:
+			deleteCustomExercise(db, w, r, userID, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func listCustomExercises(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT id, name, muscle_group, equipment, difficulty, is_bodyweight, created_at
+		FROM exercises
+		WHERE owner_user_id = $1
+		ORDER BY name
+	`, userID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	defer rows.Close()
+
+	items := []customExercise{}
+	for rows.Next() {
+		var it customExercise
+		if err := rows.Scan(&it.ID, &it.Nome, &it.Grupo, pq.Array(&it.Equipamentos),
+			&it.Dificuldade, &it.PesoCorporal, &it.CreatedAt); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if it.Equipamentos == nil {
+			it.Equipamentos = []string{}
+		}
+		items = append(items, it)
+	}
+	jsonWrite(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func fetchCustomExercise(ctx context.Context, db *sql.DB, userID string, id int64) (*customExercise, error) {
+	var it customExercise
+	err := db.QueryRowContext(ctx, `
+		SELECT id, name, muscle_group, equipment, difficulty, is_bodyweight, created_at
+		FROM exercises
+		WHERE id = $1 AND owner_user_id = $2
+	`, id, userID).Scan(&it.ID, &it.Nome, &it.Grupo, pq.Array(&it.Equipamentos),
+		&it.Dificuldade, &it.PesoCorporal, &it.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if it.Equipamentos == nil {
+		it.Equipamentos = []string{}
+	}
+	return &it, nil
+}
+
+func createCustomExercise(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	var in customExerciseIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+	if in.Nome == nil || strings.TrimSpace(*in.Nome) == "" {
+		badRequest(w, "nome required")
+		return
+	}
+	if in.Grupo == nil || strings.TrimSpace(*in.Grupo) == "" {
+		badRequest(w, "grupo required")
+		return
+	}
+
+	nome := strings.TrimSpace(*in.Nome)
+	grupo := strings.ToLower(strings.TrimSpace(*in.Grupo))
+	equip := []string{}
+	if in.Equipamentos != nil {
+		equip = cleanEquipment(*in.Equipamentos)
+	}
+	dif := "iniciante"
+	if in.Dificuldade != nil && strings.TrimSpace(*in.Dificuldade) != "" {
+		d, ok := normalizeDifficulty(*in.Dificuldade)
+		if !ok {
+			badRequest(w, "dificuldade must be iniciante|intermediario|avancado")
+			return
+		}
+		dif = d
+	}
+	bw := false
+	if in.PesoCorporal != nil {
+		bw = *in.PesoCorporal
+	}
+
+	var id int64
+	err := db.QueryRowContext(r.Context(), `
+		INSERT INTO exercises (name, muscle_group, equipment, difficulty, is_bodyweight, owner_user_id)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING id
+	`, nome, grupo, pq.Array(equip), dif, bw, userID).Scan(&id)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			jsonWrite(w, http.StatusConflict, map[string]string{"error": "exercise with this name already exists"})
+			return
+		}
+		internalErr(w, err)
+		return
+	}
+
+	it, err := fetchCustomExercise(r.Context(), db, userID, id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusCreated, it)
+}
+
+func patchCustomExercise(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string, id int64) {
+	var in customExerciseIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+
+	setParts := []string{}
+	args := []any{}
+	argIdx := 1
+
+	if in.Nome != nil {
+		s := strings.TrimSpace(*in.Nome)
+		if s == "" {
+			badRequest(w, "nome must not be empty")
+			return
+		}
+		setParts = append(setParts, "name = $"+itoa(argIdx))
+		args = append(args, s)
+		argIdx++
+	}
+	if in.Grupo != nil {
+		s := strings.ToLower(strings.TrimSpace(*in.Grupo))
+		if s == "" {
+			badRequest(w, "grupo must not be empty")
+			return
+		}
+		setParts = append(setParts, "muscle_group = $"+itoa(argIdx))
+		args = append(args, s)
+		argIdx++
+	}
+	if in.Equipamentos != nil {
+		setParts = append(setParts, "equipment = $"+itoa(argIdx))
+		args = append(args, pq.Array(cleanEquipment(*in.Equipamentos)))
+		argIdx++
+	}
+	if in.Dificuldade != nil {
+		d, ok := normalizeDifficulty(*in.Dificuldade)
+		if !ok {
+			badRequest(w, "dificuldade must be iniciante|intermediario|avancado")
+			return
+		}
+		setParts = append(setParts, "difficulty = $"+itoa(argIdx))
+		args = append(args, d)
+		argIdx++
+	}
+	if in.PesoCorporal != nil {
+		setParts = append(setParts, "is_bodyweight = $"+itoa(argIdx))
+		args = append(args, *in.PesoCorporal)
+		argIdx++
+	}
+	if len(setParts) == 0 {
+		badRequest(w, "no fields to update")
+		return
+	}
+
+	q := `UPDATE exercises SET ` + strings.Join(setParts, ", ") +
+		` WHERE id = $` + itoa(argIdx) + ` AND owner_user_id = $` + itoa(argIdx+1)
+	args = append(args, id, userID)
+	res, err := db.ExecContext(r.Context(), q, args...)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			jsonWrite(w, http.StatusConflict, map[string]string{"error": "exercise with this name already exists"})
+			return
+		}
+		internalErr(w, err)
+		return
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		notFound(w)
+		return
+	}
+
+	it, err := fetchCustomExercise(r.Context(), db, userID, id)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusOK, it)
+}
+
+func deleteCustomExercise(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string, id int64) {
+	res, err := db.ExecContext(r.Context(), `DELETE FROM exercises WHERE id = $1 AND owner_user_id = $2`, id, userID)
+	if err != nil {
+		// ainda referenciado por sets/treinos (FK)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			jsonWrite(w, http.StatusConflict, map[string]string{"error": "exercise in use by sets or treinos"})
+			return
+		}
+		internalErr(w, err)
+		return
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		notFound(w)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminCustomExercises lista exercícios privados (candidatos à promoção).
+// Auth: papel admin (RequireRole em main.go).
+// GET /api/admin/exercises/custom?user_id=abc&limit=100
+func AdminCustomExercises(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit := clampInt(parseInt(r.URL.Query().Get("limit"), 100), 1, 500)
+		owner := strings.TrimSpace(r.URL.Query().Get("user_id"))
+
+		// uso = nº de sessões distintas com sets válidos no exercício (sinal para promoção)
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT e.id, e.name, e.muscle_group, e.owner_user_id, e.created_at,
+			       COUNT(DISTINCT ws.session_id) AS sessions
+			FROM exercises e
+			LEFT JOIN workout_sets ws ON ws.exercicio_id = e.id AND NOT ws.flagged
+			WHERE e.owner_user_id IS NOT NULL
+			  AND ($1 = '' OR e.owner_user_id = $1)
+			GROUP BY e.id
+			ORDER BY sessions DESC, e.created_at DESC
+			LIMIT $2
+		`, owner, limit)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer rows.Close()
+
+		type item struct {
+			ID        int64     `json:"id"`
+			Nome      string    `json:"nome"`
+			Grupo     string    `json:"grupo"`
+			UserID    string    `json:"user_id"`
+			CreatedAt time.Time `json:"created_at"`
+			Sessions  int64     `json:"sessions"`
+		}
+		items := []item{}
+		for rows.Next() {
+			var it item
+			if err := rows.Scan(&it.ID, &it.Nome, &it.Grupo, &it.UserID, &it.CreatedAt, &it.Sessions); err != nil {
+				internalErr(w, err)
+				return
+			}
+			items = append(items, it)
+		}
+		jsonWrite(w, http.StatusOK, map[string]any{"items": items})
+	})
+}
+
+// AdminExercisePromote move um exercício privado para o catálogo global.
+// Sets e treinos existentes continuam apontando para o mesmo ID.
+// POST /api/admin/exercises/{id}/promote
+func AdminExercisePromote(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// /api/admin/exercises/{id}/promote
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/exercises/"), "/"), "/")
+		if len(parts) != 2 || parts[1] != "promote" {
+			http.NotFound(w, r)
+			return
+		}
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || id <= 0 {
+			badRequest(w, "invalid exercise id")
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer tx.Rollback()
+
+		var (
+			name, group string
+			prevOwner   sql.NullString
+		)
+		err = tx.QueryRowContext(r.Context(), `
+			SELECT name, muscle_group, owner_user_id FROM exercises WHERE id = $1 FOR UPDATE
+		`, id).Scan(&name, &group, &prevOwner)
+		if err == sql.ErrNoRows || (err == nil && !prevOwner.Valid) {
+			// inexistente ou já global
+			notFound(w)
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+
+		// uq_exercises_owner_name só cobre exercícios com dono: o catálogo global
+		// é checado aqui, serializado por nome para promoções concorrentes
+		if _, err := tx.ExecContext(r.Context(), `SELECT pg_advisory_xact_lock(hashtext('exercise-name:' || lower($1)))`, name); err != nil {
+			internalErr(w, err)
+			return
+		}
+		var existing int64
+		err = tx.QueryRowContext(r.Context(), `
+			SELECT id FROM exercises
+			WHERE owner_user_id IS NULL AND lower(name) = lower($1) AND id <> $2
+			LIMIT 1
+		`, name, id).Scan(&existing)
+		if err == nil {
+			jsonWrite(w, http.StatusConflict, map[string]any{
+				"error":       "a global exercise with this name already exists",
+				"existing_id": existing,
+			})
+			return
+		}
+		if err != sql.ErrNoRows {
+			internalErr(w, err)
+			return
+		}
+
+		if _, err := tx.ExecContext(r.Context(), `
+			UPDATE exercises SET owner_user_id = NULL, promoted_at = NOW() WHERE id = $1
+		`, id); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			internalErr(w, err)
+			return
+		}
+		jsonWrite(w, http.StatusOK, map[string]any{
+			"id":            id,
+			"nome":          name,
+			"grupo":         group,
+			"promoted_from": prevOwner.String,
+		})
+	})
+}
+
+func normalizeDifficulty(s string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "iniciante", "beginner":
+		return "iniciante", true
+	case "intermediario", "intermediário", "intermediate":
+		return "intermediario", true
+	case "avancado", "avançado", "advanced":
+		return "avancado", true
+	}
+	return "", false
+}
+
+func cleanEquipment(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := map[string]bool{}
+	for _, e := range in {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		out = append(out, e)
+	}
+	return out
+}
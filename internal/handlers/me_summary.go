@@ -46,34 +46,13 @@ func MeSummaryHandler(db *sql.DB) http.Handler {
 
 		// Perfil
 		{
-			row := db.QueryRow(`SELECT height_cm, weight_kg, birth_year, gender, level, goal
-				FROM user_profiles WHERE user_id = $1`, userID)
-			var hc, wk sql.NullFloat64
-			var by sql.NullInt64
-			var g, l, goa sql.NullString
-			_ = row.Scan(&hc, &wk, &by, &g, &l, &goa)
-			if hc.Valid {
-				out.Profile.HeightCM = &hc.Float64
-			}
-			if wk.Valid {
-				out.Profile.WeightKG = &wk.Float64
-			}
-			if by.Valid {
-				v := int(by.Int64)
-				out.Profile.BirthYear = &v
-			}
-			if g.Valid {
-				s := g.String
-				out.Profile.Gender = &s
-			}
-			if l.Valid {
-				s := l.String
-				out.Profile.Level = &s
-			}
-			if goa.Valid {
-				s := goa.String
-				out.Profile.Goal = &s
-			}
+			p, _ := loadUserProfile(r.Context(), db, userID)
+			out.Profile.HeightCM = p.HeightCM
+			out.Profile.WeightKG = p.WeightKG
+			out.Profile.BirthYear = p.BirthYear
+			out.Profile.Gender = p.Gender
+			out.Profile.Level = p.ExperienceLevel
+			out.Profile.Goal = p.TrainingGoal
 		}
 
 		// Janelas
@@ -92,6 +71,7 @@ SELECT
   COALESCE((SELECT COUNT(*) FROM workout_sets s
             JOIN workout_sessions ws ON ws.id = s.session_id
             WHERE s.completed = TRUE
+              AND NOT s.flagged
               AND (ws.user_id IS NULL OR ws.user_id = $1)
               AND ws.session_at >= $3),0)
 `, userID, from7, from30)
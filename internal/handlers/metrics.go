@@ -4,17 +4,26 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Série temporal de medidas corporais (user_metrics: 1 linha por usuário/dia).
+// Campos nulos = não medido naquele dia.
+
 type metricIn struct {
 	MeasuredAt *string  `json:"measured_at"` // YYYY-MM-DD; default: hoje
 	WeightKG   *float64 `json:"weight_kg"`
 	BodyfatPct *float64 `json:"bodyfat_pct"`
 	HeightCM   *int     `json:"height_cm"`
 	NeckCM     *float64 `json:"neck_cm"`
+	ChestCM    *float64 `json:"chest_cm"`
 	WaistCM    *float64 `json:"waist_cm"`
 	HipCM      *float64 `json:"hip_cm"`
+	ArmCM      *float64 `json:"arm_cm"`
+	ThighCM    *float64 `json:"thigh_cm"`
+	CalfCM     *float64 `json:"calf_cm"`
 	Notes      *string  `json:"notes"`
 }
 
@@ -24,28 +33,124 @@ type metricOut struct {
 	BodyfatPct *float64 `json:"bodyfat_pct,omitempty"`
 	HeightCM   *int     `json:"height_cm,omitempty"`
 	NeckCM     *float64 `json:"neck_cm,omitempty"`
+	ChestCM    *float64 `json:"chest_cm,omitempty"`
 	WaistCM    *float64 `json:"waist_cm,omitempty"`
 	HipCM      *float64 `json:"hip_cm,omitempty"`
+	ArmCM      *float64 `json:"arm_cm,omitempty"`
+	ThighCM    *float64 `json:"thigh_cm,omitempty"`
+	CalfCM     *float64 `json:"calf_cm,omitempty"`
 	Notes      *string  `json:"notes,omitempty"`
 }
 
-// GET:  /api/me/metrics?from=YYYY-MM-DD&to=YYYY-MM-DD
-// POST: /api/me/metrics
+const metricCols = `measured_at, weight_kg::float8, bodyfat_pct::float8, height_cm,
+	neck_cm::float8, chest_cm::float8, waist_cm::float8, hip_cm::float8,
+	arm_cm::float8, thigh_cm::float8, calf_cm::float8, notes`
+
+func scanMetric(sc interface{ Scan(...any) error }) (metricOut, error) {
+	var (
+		d   metricOut
+		mAt time.Time
+	)
+	err := sc.Scan(&mAt, &d.WeightKG, &d.BodyfatPct, &d.HeightCM,
+		&d.NeckCM, &d.ChestCM, &d.WaistCM, &d.HipCM,
+		&d.ArmCM, &d.ThighCM, &d.CalfCM, &d.Notes)
+	d.MeasuredAt = mAt.Format("2006-01-02")
+	return d, err
+}
+
+// validate: faixas plausíveis (evita 800 kg / 8 kg por typo)
+func (in metricIn) validate() string {
+	switch {
+	case in.WeightKG != nil && (*in.WeightKG < 20 || *in.WeightKG > 500):
+		return "weight_kg must be between 20 and 500"
+	case in.BodyfatPct != nil && (*in.BodyfatPct < 2 || *in.BodyfatPct > 70):
+		return "bodyfat_pct must be between 2 and 70"
+	case in.HeightCM != nil && (*in.HeightCM < 90 || *in.HeightCM > 250):
+		return "height_cm must be between 90 and 250"
+	}
+	circ := []struct {
+		name string
+		v    *float64
+	}{
+		{"neck_cm", in.NeckCM}, {"chest_cm", in.ChestCM}, {"waist_cm", in.WaistCM}, {"hip_cm", in.HipCM},
+		{"arm_cm", in.ArmCM}, {"thigh_cm", in.ThighCM}, {"calf_cm", in.CalfCM},
+	}
+	for _, c := range circ {
+		if c.v != nil && (*c.v <= 0 || *c.v > 300) {
+			return c.name + " must be between 0 and 300"
+		}
+	}
+	return ""
+}
+
+// GET    /api/me/body-metrics?from=YYYY-MM-DD&to=YYYY-MM-DD  -> { items, trend }
+// POST   /api/me/body-metrics                               (upsert por measured_at)
+// GET    /api/me/body-metrics/trend?days=90                 -> tendência de peso (EMA)
+// PATCH  /api/me/body-metrics/{YYYY-MM-DD}                  (parcial)
+// DELETE /api/me/body-metrics/{YYYY-MM-DD}
 func UserMetrics(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			getMetrics(db, w, r)
-		case http.MethodPost:
-			postMetric(db, w, r)
+		uid := strings.TrimSpace(GetUserID(r))
+		if uid == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/body-metrics"), "/")
+		switch {
+		case rest == "":
+			switch r.Method {
+			case http.MethodGet:
+				getMetrics(db, w, r, uid)
+			case http.MethodPost:
+				postMetric(db, w, r, uid)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case rest == "trend":
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			days := clampInt(parseInt(r.URL.Query().Get("days"), 90), 14, 730)
+			t, err := loadWeightTrend(r.Context(), db, uid, days)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if t == nil {
+				notFound(w)
+				return
+			}
+			jsonWrite(w, http.StatusOK, t)
 		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			day, err := time.Parse("2006-01-02", rest)
+			if err != nil {
+				badRequest(w, "invalid date (YYYY-MM-DD)")
+				return
+			}
+			switch r.Method {
+			case http.MethodPatch:
+				patchMetric(db, w, r, uid, day)
+			case http.MethodDelete:
+				res, err := db.ExecContext(r.Context(), `DELETE FROM user_metrics WHERE user_id = $1 AND measured_at = $2`, uid, day)
+				if err != nil {
+					internalErr(w, err)
+					return
+				}
+				if aff, _ := res.RowsAffected(); aff == 0 {
+					notFound(w)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
 		}
 	})
 }
 
-func getMetrics(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	uid := getUserID(r)
+func getMetrics(db *sql.DB, w http.ResponseWriter, r *http.Request, uid string) {
 	q := r.URL.Query()
 	now := time.Now().UTC()
 	from := now.AddDate(0, 0, -90) // padrão: últimos 90 dias
@@ -68,74 +173,53 @@ func getMetrics(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	rows, err := db.Query(`
-		SELECT measured_at, weight_kg, bodyfat_pct, height_cm, neck_cm, waist_cm, hip_cm, notes
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT `+metricCols+`
 		FROM user_metrics
 		WHERE user_id = $1 AND measured_at BETWEEN $2 AND $3
 		ORDER BY measured_at
 	`, uid, from, to)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		internalErr(w, err)
 		return
 	}
 	defer rows.Close()
 
-	var out []metricOut
+	out := []metricOut{}
 	for rows.Next() {
-		var d metricOut
-		var mAt time.Time
-		var wkg, bfp, neck, waist, hip sql.NullFloat64
-		var h sql.NullInt64
-		var notes sql.NullString
-
-		if err := rows.Scan(&mAt, &wkg, &bfp, &h, &neck, &waist, &hip, &notes); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		d, err := scanMetric(rows)
+		if err != nil {
+			internalErr(w, err)
 			return
 		}
-		d.MeasuredAt = mAt.Format("2006-01-02")
-		if wkg.Valid {
-			v := wkg.Float64
-			d.WeightKG = &v
-		}
-		if bfp.Valid {
-			v := bfp.Float64
-			d.BodyfatPct = &v
-		}
-		if h.Valid {
-			v := int(h.Int64)
-			d.HeightCM = &v
-		}
-		if neck.Valid {
-			v := neck.Float64
-			d.NeckCM = &v
-		}
-		if waist.Valid {
-			v := waist.Float64
-			d.WaistCM = &v
-		}
-		if hip.Valid {
-			v := hip.Float64
-			d.HipCM = &v
-		}
-		if notes.Valid {
-			v := notes.String
-			d.Notes = &v
-		}
-
 		out = append(out, d)
 	}
+	if err := rows.Err(); err != nil {
+		internalErr(w, err)
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(out)
+	trend, err := loadWeightTrend(r.Context(), db, uid, int(to.Sub(from).Hours()/24)+1)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	if trend != nil {
+		trend.Series = nil // a série completa fica em /trend
+	}
+	jsonWrite(w, http.StatusOK, map[string]any{"items": out, "trend": trend})
 }
 
-func postMetric(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	uid := getUserID(r)
+func postMetric(db *sql.DB, w http.ResponseWriter, r *http.Request, uid string) {
 	var in metricIn
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		http.Error(w, "json inválido", http.StatusBadRequest)
 		return
 	}
+	if msg := in.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	// measured_at default = hoje (UTC)
 	var mAt time.Time
@@ -150,52 +234,85 @@ func postMetric(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		mAt = t
 	}
 
-	// Converte para Null*
-	var wkg, bfp, neck, waist, hip sql.NullFloat64
-	var h sql.NullInt64
-	var notes sql.NullString
-
-	if in.WeightKG != nil {
-		wkg = sql.NullFloat64{Float64: *in.WeightKG, Valid: true}
+	// upsert: campos ausentes no POST não apagam o que já foi medido no dia
+	row := db.QueryRowContext(r.Context(), `
+		INSERT INTO user_metrics
+			(user_id, measured_at, weight_kg, bodyfat_pct, height_cm, neck_cm, chest_cm,
+			 waist_cm, hip_cm, arm_cm, thigh_cm, calf_cm, notes)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		ON CONFLICT (user_id, measured_at) DO UPDATE SET
+			weight_kg   = COALESCE(EXCLUDED.weight_kg, user_metrics.weight_kg),
+			bodyfat_pct = COALESCE(EXCLUDED.bodyfat_pct, user_metrics.bodyfat_pct),
+			height_cm   = COALESCE(EXCLUDED.height_cm, user_metrics.height_cm),
+			neck_cm     = COALESCE(EXCLUDED.neck_cm, user_metrics.neck_cm),
+			chest_cm    = COALESCE(EXCLUDED.chest_cm, user_metrics.chest_cm),
+			waist_cm    = COALESCE(EXCLUDED.waist_cm, user_metrics.waist_cm),
+			hip_cm      = COALESCE(EXCLUDED.hip_cm, user_metrics.hip_cm),
+			arm_cm      = COALESCE(EXCLUDED.arm_cm, user_metrics.arm_cm),
+			thigh_cm    = COALESCE(EXCLUDED.thigh_cm, user_metrics.thigh_cm),
+			calf_cm     = COALESCE(EXCLUDED.calf_cm, user_metrics.calf_cm),
+			notes       = COALESCE(EXCLUDED.notes, user_metrics.notes)
+		RETURNING `+metricCols,
+		uid, mAt, in.WeightKG, in.BodyfatPct, in.HeightCM, in.NeckCM, in.ChestCM,
+		in.WaistCM, in.HipCM, in.ArmCM, in.ThighCM, in.CalfCM, in.Notes)
+	d, err := scanMetric(row)
+	if err != nil {
+		internalErr(w, err)
+		return
 	}
-	if in.BodyfatPct != nil {
-		bfp = sql.NullFloat64{Float64: *in.BodyfatPct, Valid: true}
+	jsonWrite(w, http.StatusCreated, d)
+}
+
+// patchMetric: só as chaves presentes; null apaga a medida.
+func patchMetric(db *sql.DB, w http.ResponseWriter, r *http.Request, uid string, day time.Time) {
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		badRequest(w, "invalid json")
+		return
 	}
-	if in.HeightCM != nil {
-		h = sql.NullInt64{Int64: int64(*in.HeightCM), Valid: true}
+	var in metricIn
+	raw, _ := json.Marshal(body)
+	if err := json.Unmarshal(raw, &in); err != nil {
+		badRequest(w, "invalid field types")
+		return
 	}
-	if in.NeckCM != nil {
-		neck = sql.NullFloat64{Float64: *in.NeckCM, Valid: true}
+	if msg := in.validate(); msg != "" {
+		badRequest(w, msg)
+		return
 	}
-	if in.WaistCM != nil {
-		waist = sql.NullFloat64{Float64: *in.WaistCM, Valid: true}
+
+	vals := map[string]any{
+		"weight_kg": in.WeightKG, "bodyfat_pct": in.BodyfatPct, "height_cm": in.HeightCM,
+		"neck_cm": in.NeckCM, "chest_cm": in.ChestCM, "waist_cm": in.WaistCM, "hip_cm": in.HipCM,
+		"arm_cm": in.ArmCM, "thigh_cm": in.ThighCM, "calf_cm": in.CalfCM, "notes": in.Notes,
 	}
-	if in.HipCM != nil {
-		hip = sql.NullFloat64{Float64: *in.HipCM, Valid: true}
+	sets := []string{}
+	args := []any{uid, day}
+	for _, col := range []string{"weight_kg", "bodyfat_pct", "height_cm", "neck_cm", "chest_cm",
+		"waist_cm", "hip_cm", "arm_cm", "thigh_cm", "calf_cm", "notes"} {
+		if _, ok := body[col]; !ok {
+			continue
+		}
+		args = append(args, vals[col])
+		sets = append(sets, col+" = $"+strconv.Itoa(len(args)))
 	}
-	if in.Notes != nil {
-		notes = sql.NullString{String: *in.Notes, Valid: true}
+	if len(sets) == 0 {
+		badRequest(w, "no updatable fields")
+		return
 	}
 
-	_, err := db.Exec(`
-		INSERT INTO user_metrics
-			(user_id, measured_at, weight_kg, bodyfat_pct, height_cm, neck_cm, waist_cm, hip_cm, notes)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
-		ON CONFLICT (user_id, measured_at) DO UPDATE SET
-			weight_kg = EXCLUDED.weight_kg,
-			bodyfat_pct = EXCLUDED.bodyfat_pct,
-			height_cm = EXCLUDED.height_cm,
-			neck_cm = EXCLUDED.neck_cm,
-			waist_cm = EXCLUDED.waist_cm,
-			hip_cm = EXCLUDED.hip_cm,
-			notes = EXCLUDED.notes
-	`, uid, mAt, wkg, bfp, h, neck, waist, hip, notes)
+	row := db.QueryRowContext(r.Context(), `
+		UPDATE user_metrics SET `+strings.Join(sets, ", ")+`
+		WHERE user_id = $1 AND measured_at = $2
+		RETURNING `+metricCols, args...)
+	d, err := scanMetric(row)
+	if err == sql.ErrNoRows {
+		notFound(w)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		internalErr(w, err)
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_, _ = w.Write([]byte(`{"ok":true}`))
+	jsonWrite(w, http.StatusOK, d)
 }
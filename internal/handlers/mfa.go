@@ -0,0 +1,541 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"anima/internal/totp"
+)
+
+// Autenticação em dois fatores (TOTP).
+// Cadastro: POST /api/me/mfa/enroll devolve o segredo e o URI otpauth;
+// POST /api/me/mfa/confirm com o primeiro código ativa e entrega os códigos
+// de recuperação (guardamos só o sha256). Com 2FA ativo o login devolve um
+// desafio (mfa_token) e o par de tokens só sai em POST /api/auth/mfa.
+// Papéis em MFA_REQUIRED_ROLES (padrão: coach) só valem em sessões abertas
+// com segundo fator, e quem os tem não pode desligar o 2FA.
+
+const (
+	purposeMFALogin = "mfa_login"
+
+	recoveryCodeCount = 10
+	totpSkew          = 1 // passos aceitos para cada lado (relógio do celular)
+)
+
+var errMFACode = errors.New("invalid code")
+
+func mfaChallengeTTL() time.Duration {
+	return time.Duration(atoiEnvInt("MFA_CHALLENGE_TTL_MIN", 5)) * time.Minute
+}
+
+func mfaRequiredRoles() map[string]bool {
+	out := map[string]bool{}
+	for _, r := range strings.Split(envDefault("MFA_REQUIRED_ROLES", RoleCoach), ",") {
+		if r = strings.ToLower(strings.TrimSpace(r)); r != "" && r != RoleAthlete {
+			out[r] = true
+		}
+	}
+	return out
+}
+
+// effectiveRoles separa os papéis que exigem segundo fator quando a sessão não o tem.
+func effectiveRoles(roles []string, mfa bool) (eff, withheld []string) {
+	if mfa {
+		return roles, nil
+	}
+	req := mfaRequiredRoles()
+	for _, r := range roles {
+		if req[r] {
+			withheld = append(withheld, r)
+		} else {
+			eff = append(eff, r)
+		}
+	}
+	return eff, withheld
+}
+
+func mfaThrottleKey(uid string) string { return "mfa:" + uid }
+
+func totpEnabled(ctx context.Context, db *sql.DB, uid string) (bool, error) {
+	var ok bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM user_totp WHERE user_id = $1 AND confirmed_at IS NOT NULL)`, uid).Scan(&ok)
+	return ok, err
+}
+
+type mfaChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// issueMFAChallenge: desafio de uso único do login em duas etapas.
+func issueMFAChallenge(ctx context.Context, db *sql.DB, uid string) (*mfaChallenge, error) {
+	ttl := mfaChallengeTTL()
+	token, err := issueAuthToken(ctx, db, uid, purposeMFALogin, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &mfaChallenge{MFARequired: true, MFAToken: token, ExpiresIn: int64(ttl / time.Second)}, nil
+}
+
+type secondFactor struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (f secondFactor) empty() bool {
+	return strings.TrimSpace(f.Code) == "" && strings.TrimSpace(f.RecoveryCode) == ""
+}
+
+// verifySecondFactor confere um código TOTP (sem reuso de passo) ou consome um
+// código de recuperação; devolve o método usado ("totp" | "recovery").
+func verifySecondFactor(ctx context.Context, tx *sql.Tx, uid string, f secondFactor) (string, error) {
+	if rc := normalizeRecoveryCode(f.RecoveryCode); rc != "" {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE mfa_recovery_codes SET used_at = now()
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, uid, hashAuthToken(rc))
+		if err != nil {
+			return "", err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return "", errMFACode
+		}
+		return "recovery", nil
+	}
+	var secret string
+	var last int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT secret, last_step FROM user_totp
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL
+		FOR UPDATE`, uid).Scan(&secret, &last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errMFACode
+	}
+	if err != nil {
+		return "", err
+	}
+	step, ok := totp.Validate(secret, f.Code, time.Now(), totpSkew)
+	if !ok || step <= last {
+		return "", errMFACode
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE user_totp SET last_step = $2 WHERE user_id = $1`, uid, step); err != nil {
+		return "", err
+	}
+	return "totp", nil
+}
+
+// mfaReserve / mfaFailed: mesmas regras de backoff do login (tentativa
+// reservada antes de conferir o código), com bloqueio após MFA_MAX_ATTEMPTS
+// códigos errados. false = resposta já escrita.
+func mfaReserve(w http.ResponseWriter, r *http.Request, db *sql.DB, uid string) (throttleHit, bool) {
+	cfg := LoginThrottleFromEnv()
+	cfg.LockAt = atoiEnvInt("MFA_MAX_ATTEMPTS", 5)
+	hits, wait, err := reserveAttempt(r.Context(), db, throttleKey{mfaThrottleKey(uid), cfg})
+	if err != nil {
+		internalErr(w, err)
+		return throttleHit{}, false
+	}
+	if wait > 0 {
+		writeTooManyAttempts(w, wait)
+		return throttleHit{}, false
+	}
+	return hits[0], true
+}
+
+func mfaFailed(ctx context.Context, db *sql.DB, r *http.Request, uid string, hit throttleHit) {
+	recordSecurityEvent(ctx, db, r, "mfa_failed", uid, "", map[string]any{"failures": hit.failures})
+	if hit.locked {
+		recordSecurityEvent(ctx, db, r, "mfa_locked", uid, "", map[string]any{"failures": hit.failures})
+	}
+}
+
+// AuthMFA: POST /api/auth/mfa {"mfa_token","code"} ou {"mfa_token","recovery_code"}
+// Segunda etapa do login; devolve o mesmo par de /api/auth/login.
+func AuthMFA(db *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var in struct {
+			MFAToken string `json:"mfa_token"`
+			secondFactor
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.MFAToken) == "" || in.empty() {
+			badRequest(w, "mfa_token and code (or recovery_code) required")
+			return
+		}
+		ctx := r.Context()
+		var uid string
+		err := db.QueryRowContext(ctx, `
+			SELECT user_id::text FROM auth_tokens
+			WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()`,
+			hashAuthToken(in.MFAToken), purposeMFALogin).Scan(&uid)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		hit, ok := mfaReserve(w, r, db, uid)
+		if !ok {
+			return
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer tx.Rollback()
+		method, err := verifySecondFactor(ctx, tx, uid, in.secondFactor)
+		if errors.Is(err, errMFACode) {
+			_ = tx.Rollback()
+			mfaFailed(ctx, db, r, uid, hit)
+			http.Error(w, "invalid code", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		// consome o desafio na mesma transação (duas verificações simultâneas: só uma passa)
+		if _, err := consumeAuthToken(ctx, tx, in.MFAToken, purposeMFALogin); err != nil {
+			if errors.Is(err, errTokenInvalid) {
+				http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			internalErr(w, err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if err := clearLoginThrottle(ctx, db, mfaThrottleKey(uid)); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if method == "recovery" {
+			recordSecurityEvent(ctx, db, r, "recovery_code_used", uid, "", nil)
+		}
+
+		pair, err := startLoginSession(ctx, db, r, uid, true)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		jsonWrite(w, http.StatusOK, pair)
+	}
+}
+
+// MeMFA (somente com JWT):
+//
+//	GET    /api/me/mfa                  -> estado do 2FA
+//	POST   /api/me/mfa/enroll           -> {secret, otpauth_uri} (pendente até confirmar)
+//	POST   /api/me/mfa/confirm          {"code"} -> ativa; {recovery_codes:[...]}
+//	POST   /api/me/mfa/recovery-codes   {"code"} -> gera novos códigos (invalida os antigos)
+//	DELETE /api/me/mfa                  {"code"} ou {"recovery_code"} -> desativa
+func MeMFA(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := GetUserID(r)
+		if uid == "" || AuthKind(r) != "jwt" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ctx := r.Context()
+		action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/mfa"), "/")
+
+		switch {
+		case r.Method == http.MethodGet && action == "":
+			var st struct {
+				Enabled       bool       `json:"enabled"`
+				Pending       bool       `json:"pending"`
+				ConfirmedAt   *time.Time `json:"confirmed_at,omitempty"`
+				RecoveryLeft  int        `json:"recovery_codes_left"`
+				Required      bool       `json:"required"`
+				SessionHasMFA bool       `json:"session_mfa"`
+			}
+			var confirmed sql.NullTime
+			err := db.QueryRowContext(ctx, `SELECT confirmed_at FROM user_totp WHERE user_id = $1`, uid).Scan(&confirmed)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				internalErr(w, err)
+				return
+			}
+			st.Pending = err == nil && !confirmed.Valid
+			if confirmed.Valid {
+				st.Enabled, st.ConfirmedAt = true, &confirmed.Time
+			}
+			if err := db.QueryRowContext(ctx, `
+				SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, uid).Scan(&st.RecoveryLeft); err != nil {
+				internalErr(w, err)
+				return
+			}
+			if st.Required, err = mfaRequiredFor(ctx, db, uid); err != nil {
+				internalErr(w, err)
+				return
+			}
+			st.SessionHasMFA = mfaVerified(r)
+			jsonWrite(w, http.StatusOK, st)
+
+		case r.Method == http.MethodPost && action == "enroll":
+			enabled, err := totpEnabled(ctx, db, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if enabled {
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": "two-factor authentication already enabled"})
+				return
+			}
+			var email string
+			if err := db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1`, uid).Scan(&email); err != nil {
+				internalErr(w, err)
+				return
+			}
+			secret, err := totp.GenerateSecret()
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if _, err := db.ExecContext(ctx, `
+				INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+				ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, created_at = now()
+				WHERE user_totp.confirmed_at IS NULL`, uid, secret); err != nil {
+				internalErr(w, err)
+				return
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			jsonWrite(w, http.StatusOK, map[string]string{
+				"secret":      secret,
+				"otpauth_uri": totp.URI(envDefault("MFA_ISSUER", "Anima"), email, secret),
+			})
+
+		case r.Method == http.MethodPost && action == "confirm":
+			var in struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Code) == "" {
+				badRequest(w, "code required")
+				return
+			}
+			hit, ok := mfaReserve(w, r, db, uid)
+			if !ok {
+				return
+			}
+			var secret string
+			err := db.QueryRowContext(ctx, `
+				SELECT secret FROM user_totp WHERE user_id = $1 AND confirmed_at IS NULL`, uid).Scan(&secret)
+			if errors.Is(err, sql.ErrNoRows) {
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": "no pending two-factor enrollment"})
+				return
+			}
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			step, ok := totp.Validate(secret, in.Code, time.Now(), totpSkew)
+			if !ok {
+				mfaFailed(ctx, db, r, uid, hit)
+				badRequest(w, "invalid code")
+				return
+			}
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			defer tx.Rollback()
+			res, err := tx.ExecContext(ctx, `
+				UPDATE user_totp SET confirmed_at = now(), last_step = $3
+				WHERE user_id = $1 AND secret = $2 AND confirmed_at IS NULL`, uid, secret, step)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": "no pending two-factor enrollment"})
+				return
+			}
+			codes, err := replaceRecoveryCodes(ctx, tx, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			// a sessão atual acabou de provar o segundo fator
+			if sid := GetLoginSessionID(r); sid != "" {
+				if _, err := tx.ExecContext(ctx, `
+					UPDATE login_sessions SET mfa = true WHERE id::text = $1 AND user_id = $2`, sid, uid); err != nil {
+					internalErr(w, err)
+					return
+				}
+			}
+			if err := tx.Commit(); err != nil {
+				internalErr(w, err)
+				return
+			}
+			_ = clearLoginThrottle(ctx, db, mfaThrottleKey(uid))
+			recordSecurityEvent(ctx, db, r, "mfa_enabled", uid, "", nil)
+			w.Header().Set("Cache-Control", "no-store")
+			jsonWrite(w, http.StatusOK, map[string]any{"enabled": true, "recovery_codes": codes})
+
+		case r.Method == http.MethodPost && action == "recovery-codes":
+			var in struct {
+				Code string `json:"code"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Code) == "" {
+				badRequest(w, "code required")
+				return
+			}
+			codes, ok := withSecondFactor(w, r, db, uid, secondFactor{Code: in.Code}, func(tx *sql.Tx) (any, error) {
+				return replaceRecoveryCodes(ctx, tx, uid)
+			})
+			if !ok {
+				return
+			}
+			recordSecurityEvent(ctx, db, r, "recovery_codes_regenerated", uid, "", nil)
+			w.Header().Set("Cache-Control", "no-store")
+			jsonWrite(w, http.StatusOK, map[string]any{"recovery_codes": codes})
+
+		case r.Method == http.MethodDelete && action == "":
+			var in secondFactor
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.empty() {
+				badRequest(w, "code (or recovery_code) required")
+				return
+			}
+			required, err := mfaRequiredFor(ctx, db, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if required {
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": "two-factor authentication is required for your role"})
+				return
+			}
+			_, ok := withSecondFactor(w, r, db, uid, in, func(tx *sql.Tx) (any, error) {
+				for _, q := range []string{
+					`DELETE FROM user_totp WHERE user_id = $1`,
+					`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+					`UPDATE login_sessions SET mfa = false WHERE user_id = $1`,
+				} {
+					if _, err := tx.ExecContext(ctx, q, uid); err != nil {
+						return nil, err
+					}
+				}
+				return nil, nil
+			})
+			if !ok {
+				return
+			}
+			recordSecurityEvent(ctx, db, r, "mfa_disabled", uid, "", nil)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// withSecondFactor confere o fator e roda fn na mesma transação; false = resposta já escrita.
+func withSecondFactor(w http.ResponseWriter, r *http.Request, db *sql.DB, uid string, f secondFactor, fn func(*sql.Tx) (any, error)) (any, bool) {
+	ctx := r.Context()
+	hit, ok := mfaReserve(w, r, db, uid)
+	if !ok {
+		return nil, false
+	}
+	enabled, err := totpEnabled(ctx, db, uid)
+	if err != nil {
+		internalErr(w, err)
+		return nil, false
+	}
+	if !enabled {
+		jsonWrite(w, http.StatusConflict, map[string]string{"error": "two-factor authentication is not enabled"})
+		return nil, false
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		internalErr(w, err)
+		return nil, false
+	}
+	defer tx.Rollback()
+	if _, err := verifySecondFactor(ctx, tx, uid, f); err != nil {
+		if errors.Is(err, errMFACode) {
+			_ = tx.Rollback()
+			mfaFailed(ctx, db, r, uid, hit)
+			badRequest(w, "invalid code")
+			return nil, false
+		}
+		internalErr(w, err)
+		return nil, false
+	}
+	out, err := fn(tx)
+	if err != nil {
+		internalErr(w, err)
+		return nil, false
+	}
+	if err := tx.Commit(); err != nil {
+		internalErr(w, err)
+		return nil, false
+	}
+	_ = clearLoginThrottle(ctx, db, mfaThrottleKey(uid))
+	return out, true
+}
+
+// mfaRequiredFor: o usuário tem algum papel de MFA_REQUIRED_ROLES.
+func mfaRequiredFor(ctx context.Context, db *sql.DB, uid string) (bool, error) {
+	roles, err := loadUserRoles(ctx, db, uid)
+	if err != nil {
+		return false, err
+	}
+	_, withheld := effectiveRoles(roles, false)
+	return len(withheld) > 0, nil
+}
+
+// replaceRecoveryCodes troca todos os códigos de recuperação; devolve os novos em claro.
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, uid string) ([]string, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, uid); err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		c, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+			ON CONFLICT (user_id, code_hash) DO NOTHING`, uid, hashAuthToken(normalizeRecoveryCode(c)))
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			codes = append(codes, c)
+		}
+	}
+	return codes, nil
+}
+
+// newRecoveryCode: "XXXXX-XXXXX" (50 bits, base32).
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := base32.StdEncoding.EncodeToString(b)[:10]
+	return s[:5] + "-" + s[5:], nil
+}
+
+func normalizeRecoveryCode(c string) string {
+	c = strings.ToUpper(strings.TrimSpace(c))
+	return strings.NewReplacer("-", "", " ", "").Replace(c)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"strconv"
+	"strings"
+
+	"anima/internal/i18n"
+)
+
+// Autorregulação intra-sessão: após cada série registrada (reps + RIR),
+// recomenda a próxima (carga/reps) pelo desvio do RIR em relação ao alvo do
+// item do treino. Com top_set_rpe + backoff_pct o item vira "top set + back-offs".
+
+const defaultTargetRIR = 2
+
+// NextSet: recomendação para a próxima série (rationale no idioma da requisição).
+type NextSet struct {
+	Action    string  `json:"action"` // up | same | down | backoff | done
+	SetNumber int     `json:"set_number"`
+	WeightKg  float64 `json:"weight_kg,omitempty"`
+	Reps      int     `json:"reps,omitempty"`
+	TargetRIR int     `json:"target_rir"`
+	Rationale string  `json:"rationale"`
+}
+
+// configuração do item no treino (defaults quando a sessão não tem treino)
+type setPlan struct {
+	series      int
+	repsLo      int
+	repsHi      int
+	targetRIR   int
+	topSetRPE   *float64
+	backoffPct  *float64
+	backoffSets *int
+}
+
+func (p setPlan) topSet() bool { return p.topSetRPE != nil && p.backoffPct != nil }
+
+// total de séries planejadas do exercício
+func (p setPlan) total() int {
+	if p.topSet() {
+		if p.backoffSets != nil {
+			return 1 + *p.backoffSets
+		}
+		return max(p.series, 2)
+	}
+	return p.series
+}
+
+// repRange: "8-12" => (8,12); "10" => (10,10); inválido => (8,12).
+func repRange(s string) (int, int) {
+	s = strings.TrimSpace(s)
+	lo, hi := s, s
+	if i := strings.Index(s, "-"); i >= 0 {
+		lo, hi = s[:i], s[i+1:]
+	}
+	a, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	b, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if err1 != nil || err2 != nil || a <= 0 || b < a {
+		return 8, 12
+	}
+	return a, b
+}
+
+// computeNextSet: regra pura; `pos` é a posição (1-based) da série logada no exercício.
+func computeNextSet(lang i18n.Lang, p setPlan, pos int, weight float64, reps, rir int) NextSet {
+	ns := NextSet{SetNumber: pos + 1, TargetRIR: p.targetRIR}
+	if pos >= p.total() {
+		ns.Action = "done"
+		ns.Rationale = i18n.T(lang, "nextset.done", pos, p.total())
+		return ns
+	}
+
+	// top set => primeira back-off
+	if p.topSet() && pos == 1 {
+		ns.Action = "backoff"
+		ns.WeightKg = roundTo(weight*(1-*p.backoffPct/100), 0.5)
+		ns.Reps = reps
+		rpe := 10 - float64(rir)
+		ns.Rationale = i18n.T(lang, "nextset.backoff", rpe, *p.topSetRPE, *p.backoffPct)
+		if d := rpe - *p.topSetRPE; math.Abs(d) >= 1 {
+			// top set fora do alvo: corrige a back-off em 2.5% por ponto de RPE
+			adj := clampFloat(-d*0.025, -0.05, 0.05)
+			ns.WeightKg = roundTo(ns.WeightKg*(1+adj), 0.5)
+			ns.Rationale += i18n.T(lang, "nextset.backoff_adjust", adj*100)
+		}
+		return ns
+	}
+
+	dev := rir - p.targetRIR
+	target := clampInt(reps, p.repsLo, p.repsHi)
+	switch {
+	case reps < p.repsLo || dev <= -2:
+		// falhou a faixa ou muito perto da falha: reduz ~4% por ponto de RIR (até 15%)
+		cut := math.Min(0.04*math.Max(float64(-dev), 1), 0.15)
+		ns.Action = "down"
+		ns.WeightKg = roundTo(weight*(1-cut), 0.5)
+		ns.Reps = target
+		ns.Rationale = i18n.T(lang, "nextset.down", rir, p.targetRIR, cut*100)
+	case dev >= 2:
+		// sobrou margem: +2.5% por ponto de RIR (até 10%)
+		inc := math.Min(0.025*float64(dev), 0.10)
+		ns.Action = "up"
+		ns.WeightKg = roundTo(weight*(1+inc), 0.5)
+		if ns.WeightKg <= weight {
+			ns.WeightKg = weight + 0.5
+		}
+		ns.Reps = target
+		ns.Rationale = i18n.T(lang, "nextset.up", rir, p.targetRIR, inc*100)
+	case dev == 1:
+		ns.Action = "same"
+		ns.WeightKg = weight
+		ns.Reps = min(reps+1, p.repsHi)
+		ns.Rationale = i18n.T(lang, "nextset.plus_rep", rir, p.targetRIR)
+	case dev == -1:
+		ns.Action = "same"
+		ns.WeightKg = weight
+		ns.Reps = max(reps-1, p.repsLo)
+		ns.Rationale = i18n.T(lang, "nextset.minus_rep", rir, p.targetRIR)
+	default:
+		ns.Action = "same"
+		ns.WeightKg = weight
+		ns.Reps = target
+		ns.Rationale = i18n.T(lang, "nextset.repeat")
+	}
+	return ns
+}
+
+// SetPlan: item do treino para ComputeNextSet (exportado pros testes).
+type SetPlan struct {
+	Series, RepsLo, RepsHi, TargetRIR int
+	TopSetRPE, BackoffPct             *float64
+	BackoffSets                       *int
+}
+
+// ComputeNextSet exportado pros testes
+func ComputeNextSet(lang i18n.Lang, p SetPlan, pos int, weight float64, reps, rir int) NextSet {
+	return computeNextSet(lang, setPlan{
+		series: p.Series, repsLo: p.RepsLo, repsHi: p.RepsHi, targetRIR: p.TargetRIR,
+		topSetRPE: p.TopSetRPE, backoffPct: p.BackoffPct, backoffSets: p.BackoffSets,
+	}, pos, weight, reps, rir)
+}
+
+func clampFloat(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+// nextSetFor carrega a série, o item do treino da sessão e a posição da série.
+// nil quando a série não tem carga/reps/RIR.
+func nextSetFor(ctx context.Context, db *sql.DB, setID int64) (*NextSet, error) {
+	var (
+		exID      int64
+		treinoID  sql.NullInt64
+		weight    sql.NullFloat64
+		reps, rir sql.NullInt64
+		pos       int
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT s.exercicio_id, ws.treino_id, s.weight_kg, s.reps, s.rir,
+		       (SELECT COUNT(*) FROM workout_sets x
+		        WHERE x.session_id = s.session_id AND x.exercicio_id = s.exercicio_id AND x.id <= s.id)
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE s.id = $1
+	`, setID).Scan(&exID, &treinoID, &weight, &reps, &rir, &pos)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !weight.Valid || weight.Float64 <= 0 || !reps.Valid || reps.Int64 <= 0 || !rir.Valid {
+		return nil, nil
+	}
+
+	p := setPlan{series: 3, repsLo: 8, repsHi: 12, targetRIR: defaultTargetRIR}
+	if treinoID.Valid {
+		var (
+			repeticoes string
+			tRIR       sql.NullInt64
+		)
+		err := db.QueryRowContext(ctx, `
+			SELECT series, repeticoes, target_rir, top_set_rpe::float8, backoff_pct::float8, backoff_sets
+			FROM treino_exercicios
+			WHERE treino_id = $1 AND exercicio_id = $2
+			ORDER BY id
+			LIMIT 1
+		`, treinoID.Int64, exID).Scan(&p.series, &repeticoes, &tRIR, &p.topSetRPE, &p.backoffPct, &p.backoffSets)
+		switch {
+		case err == sql.ErrNoRows:
+			// exercício fora do treino: defaults
+		case err != nil:
+			return nil, err
+		default:
+			p.repsLo, p.repsHi = repRange(repeticoes)
+			if tRIR.Valid {
+				p.targetRIR = int(tRIR.Int64)
+			}
+		}
+	}
+
+	ns := computeNextSet(langOf(ctx), p, pos, weight.Float64, int(reps.Int64), int(rir.Int64))
+	return &ns, nil
+}
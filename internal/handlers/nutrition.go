@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"anima/internal/i18n"
+)
+
+// Metas nutricionais a partir do perfil:
+//   BMR: Mifflin-St Jeor (Katch-McArdle quando há % de gordura recente)
+//   TDEE = BMR × fator de atividade
+//   kcal/proteína/gordura/carbo conforme training_goal
+// Recalibração adaptativa: compara a taxa semanal do peso de tendência com a
+// esperada para o objetivo e corrige as kcal (≈7700 kcal por kg).
+
+const (
+	kcalPerKg          = 7700.0
+	nutritionMaxAdjust = 500.0 // kcal/dia
+	bodyfatMaxAgeDays  = 90
+)
+
+// fatores de atividade (PT/EN)
+var activityFactors = map[string]float64{
+	"sedentario": 1.2, "sedentary": 1.2,
+	"leve": 1.375, "light": 1.375,
+	"moderado": 1.55, "moderate": 1.55,
+	"ativo": 1.725, "active": 1.725,
+	"muito_ativo": 1.9, "very_active": 1.9,
+}
+
+// nutritionGoal: ajuste calórico, proteína (g/kg) e taxa semanal esperada (% do peso).
+type nutritionGoal struct {
+	kcalPct    float64
+	proteinGKg float64
+	ratePct    float64
+}
+
+var nutritionGoals = map[string]nutritionGoal{
+	"hipertrofia":   {kcalPct: 0.10, proteinGKg: 2.0, ratePct: 0.25},
+	"emagrecimento": {kcalPct: -0.20, proteinGKg: 2.2, ratePct: -0.5},
+	"forca":         {kcalPct: 0.05, proteinGKg: 1.8, ratePct: 0.1},
+	"resistencia":   {kcalPct: 0, proteinGKg: 1.6, ratePct: 0},
+	"manutencao":    {kcalPct: 0, proteinGKg: 1.6, ratePct: 0},
+}
+
+type nutritionIn struct {
+	HeightCM   *float64
+	WeightKG   *float64
+	Age        *int
+	Gender     string
+	Activity   string
+	Goal       string
+	BodyfatPct *float64
+	Trend      *weightTrend
+}
+
+type nutritionAdjustment struct {
+	ExpectedRateKG float64 `json:"expected_rate_kg"`
+	ObservedRateKG float64 `json:"observed_rate_kg"`
+	DeltaKcal      int     `json:"delta_kcal"`
+	Reason         string  `json:"reason"`
+}
+
+type nutritionTargets struct {
+	WeightKG       float64              `json:"weight_kg"`
+	WeightSource   string               `json:"weight_source"` // trend | profile
+	BMR            int                  `json:"bmr"`
+	BMRFormula     string               `json:"bmr_formula"` // mifflin_st_jeor | katch_mcardle
+	ActivityFactor float64              `json:"activity_factor"`
+	TDEE           int                  `json:"tdee"`
+	Goal           string               `json:"goal"`
+	Calories       int                  `json:"calories"`
+	ProteinG       int                  `json:"protein_g"`
+	FatG           int                  `json:"fat_g"`
+	CarbsG         int                  `json:"carbs_g"`
+	Adjustment     *nutritionAdjustment `json:"adjustment,omitempty"`
+	Notes          []string             `json:"notes,omitempty"`
+}
+
+func normalizeGoal(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	r := strings.NewReplacer("ç", "c", "ê", "e", "ã", "a", "ú", "u")
+	return r.Replace(s)
+}
+
+// computeNutrition: regra pura; sem dados suficientes devolve os campos que faltam.
+// Notas e motivo do ajuste saem no idioma lang.
+func computeNutrition(lang i18n.Lang, in nutritionIn) (*nutritionTargets, []string) {
+	var missing []string
+	if in.WeightKG == nil {
+		missing = append(missing, "weight_kg")
+	}
+	if in.HeightCM == nil && in.BodyfatPct == nil {
+		missing = append(missing, "height_cm")
+	}
+	if in.Age == nil && in.BodyfatPct == nil {
+		missing = append(missing, "birth_date")
+	}
+	if len(missing) > 0 {
+		return nil, missing
+	}
+
+	w := *in.WeightKG
+	out := &nutritionTargets{WeightKG: round2(w), WeightSource: "profile"}
+	if in.Trend != nil {
+		out.WeightSource = "trend"
+	}
+
+	// BMR
+	if in.BodyfatPct != nil {
+		lbm := w * (1 - *in.BodyfatPct/100)
+		out.BMR = int(math.Round(370 + 21.6*lbm))
+		out.BMRFormula = "katch_mcardle"
+	} else {
+		s := -78.0 // média entre os sexos quando não informado
+		switch strings.ToLower(strings.TrimSpace(in.Gender)) {
+		case "male", "m", "masculino":
+			s = 5
+		case "female", "f", "feminino":
+			s = -161
+		default:
+			out.Notes = append(out.Notes, i18n.T(lang, "nutrition.no_gender"))
+		}
+		out.BMR = int(math.Round(10*w + 6.25*(*in.HeightCM) - 5*float64(*in.Age) + s))
+		out.BMRFormula = "mifflin_st_jeor"
+	}
+
+	// TDEE
+	f, ok := activityFactors[strings.ToLower(strings.TrimSpace(in.Activity))]
+	if !ok {
+		f = 1.375
+		out.Notes = append(out.Notes, i18n.T(lang, "nutrition.no_activity"))
+	}
+	out.ActivityFactor = f
+	out.TDEE = int(math.Round(float64(out.BMR) * f))
+
+	// objetivo
+	goal := normalizeGoal(in.Goal)
+	g, ok := nutritionGoals[goal]
+	if !ok {
+		goal, g = "manutencao", nutritionGoals["manutencao"]
+	}
+	out.Goal = goal
+	kcal := float64(out.TDEE) * (1 + g.kcalPct)
+
+	// recalibração pela tendência de peso
+	if in.Trend != nil && in.Trend.WeeklyRateKG != nil {
+		exp := round2(w * g.ratePct / 100)
+		obs := *in.Trend.WeeklyRateKG
+		delta := clampFloat((exp-obs)*kcalPerKg/7, -nutritionMaxAdjust, nutritionMaxAdjust)
+		delta = roundTo(delta, 10)
+		if math.Abs(delta) >= 50 {
+			kcal += delta
+			out.Adjustment = &nutritionAdjustment{
+				ExpectedRateKG: exp,
+				ObservedRateKG: obs,
+				DeltaKcal:      int(delta),
+				Reason:         i18n.T(lang, "nutrition.trend_adjust", obs, exp),
+			}
+		}
+	}
+	// piso: não abaixo do BMR (nem de 1200 kcal)
+	kcal = math.Max(kcal, math.Max(float64(out.BMR), 1200))
+	out.Calories = int(roundTo(kcal, 10))
+
+	// macros: proteína por kg, gordura 25% (mín. 0.6 g/kg), carbo = resto
+	protein := g.proteinGKg * w
+	fat := math.Max(kcal*0.25/9, 0.6*w)
+	carbs := math.Max((kcal-protein*4-fat*9)/4, 0)
+	out.ProteinG = int(math.Round(protein))
+	out.FatG = int(math.Round(fat))
+	out.CarbsG = int(math.Round(carbs))
+	return out, nil
+}
+
+// NutritionIn / NutritionTargets / WeightTrend / ComputeNutrition exportados pros testes
+type (
+	NutritionIn      = nutritionIn
+	NutritionTargets = nutritionTargets
+	WeightTrend      = weightTrend
+)
+
+func ComputeNutrition(lang i18n.Lang, in NutritionIn) (*NutritionTargets, []string) {
+	return computeNutrition(lang, in)
+}
+
+// loadNutritionIn: perfil + % gordura recente + tendência de peso.
+func loadNutritionIn(ctx context.Context, db *sql.DB, uid string) (nutritionIn, error) {
+	var in nutritionIn
+	p, err := loadUserProfile(ctx, db, uid)
+	if err != nil {
+		return in, err
+	}
+	in.HeightCM, in.WeightKG, in.Age = p.HeightCM, p.WeightKG, p.age(time.Now())
+	if p.Gender != nil {
+		in.Gender = *p.Gender
+	}
+	if p.ActivityLevel != nil {
+		in.Activity = *p.ActivityLevel
+	}
+	if p.TrainingGoal != nil {
+		in.Goal = *p.TrainingGoal
+	}
+
+	// % gordura recente e altura (fallback) das medidas
+	var bf, mh sql.NullFloat64
+	err = db.QueryRowContext(ctx, `
+		SELECT
+		  (SELECT bodyfat_pct::float8 FROM user_metrics
+		    WHERE user_id = $1 AND bodyfat_pct IS NOT NULL AND measured_at >= CURRENT_DATE - $2::int
+		    ORDER BY measured_at DESC LIMIT 1),
+		  (SELECT height_cm::float8 FROM user_metrics
+		    WHERE user_id = $1 AND height_cm IS NOT NULL
+		    ORDER BY measured_at DESC LIMIT 1)
+	`, uid, bodyfatMaxAgeDays).Scan(&bf, &mh)
+	if err != nil {
+		return in, err
+	}
+	if bf.Valid {
+		in.BodyfatPct = &bf.Float64
+	}
+	if in.HeightCM == nil && mh.Valid {
+		in.HeightCM = &mh.Float64
+	}
+
+	t, err := loadWeightTrend(ctx, db, uid, 42)
+	if err != nil {
+		return in, err
+	}
+	if t != nil {
+		in.Trend = t
+		in.WeightKG = &t.TrendKG
+	}
+	return in, nil
+}
+
+// GET /api/me/nutrition-targets
+func MeNutritionTargets(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		in, err := loadNutritionIn(r.Context(), db, userID)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		out, missing := computeNutrition(requestLang(r), in)
+		if out == nil {
+			jsonWrite(w, http.StatusConflict, map[string]any{
+				"error":   "profile incomplete",
+				"missing": missing,
+			})
+			return
+		}
+		jsonWrite(w, http.StatusOK, out)
+	})
+}
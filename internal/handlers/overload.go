@@ -5,10 +5,11 @@ import (
 	"encoding/json"
 	"log"
 	"math"
-	"net"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"anima/internal/i18n"
 )
 
 type overloadReq struct {
@@ -17,12 +18,15 @@ type overloadReq struct {
 }
 
 type overloadResp struct {
-	SuggestedCargaKg float64 `json:"suggested_carga_kg"`
-	SuggestedReps    int     `json:"suggested_repeticoes"`
-	Rationale        string  `json:"rationale"`
-	AvgCargaKg       float64 `json:"avg_carga_kg"`
-	AvgRIR           float64 `json:"avg_rir"`
-	SampleCount      int     `json:"sample_count"`
+	SuggestedCargaKg float64  `json:"suggested_carga_kg"`
+	SuggestedReps    int      `json:"suggested_repeticoes"`
+	Rationale        string   `json:"rationale"`
+	AvgCargaKg       float64  `json:"avg_carga_kg"`
+	AvgRIR           float64  `json:"avg_rir"`
+	SampleCount      int      `json:"sample_count"`
+	Deload           bool     `json:"deload,omitempty"` // carga reduzida por deload ativo
+	ReadinessScore   *float64 `json:"readiness_score,omitempty"`
+	ReadinessFactor  *float64 `json:"readiness_factor,omitempty"` // fator de carga do check-in do dia
 }
 
 // POST /api/overload/suggest
@@ -31,6 +35,7 @@ type overloadResp struct {
 func OverloadSuggest(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var in overloadReq
+		lang := requestLang(r)
 
 		switch r.Method {
 		case http.MethodGet:
@@ -63,6 +68,21 @@ func OverloadSuggest(db *sql.DB) http.Handler {
 		}
 
 		userID := strings.TrimSpace(GetUserID(r))
+		// dia local do usuário p/ deload e prontidão (?date ou X-Timezone)
+		day, err := requestDay(r)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+
+		// exercício privado de outro usuário não é exposto
+		if ok, err := exerciseVisible(r.Context(), db, in.ExercicioID, userID); err != nil {
+			internalErr(w, err)
+			return
+		} else if !ok {
+			notFound(w)
+			return
+		}
 
 		var (
 			avgCarga float64
@@ -114,6 +134,7 @@ func OverloadSuggest(db *sql.DB) http.Handler {
 			     FROM workout_sets
 			     WHERE exercicio_id = $1
 			       AND completed = TRUE
+			       AND NOT flagged
 			     ORDER BY id DESC
 			     LIMIT $2
 			   ) s
@@ -129,7 +150,7 @@ func OverloadSuggest(db *sql.DB) http.Handler {
 			resp := overloadResp{
 				SuggestedCargaKg: 0,
 				SuggestedReps:    10,
-				Rationale:        "sem histórico concluído para este exercício",
+				Rationale:        i18n.T(lang, "overload.no_history"),
 				AvgCargaKg:       0,
 				AvgRIR:           1.5,
 				SampleCount:      0,
@@ -142,17 +163,43 @@ func OverloadSuggest(db *sql.DB) http.Handler {
 		// regra adaptativa
 		sugCarga := roundTo(avgCarga, 0.5)
 		sugReps := 10
-		rationale := "RIR moderado, manter carga"
+		rationale := i18n.T(lang, "overload.keep")
 		switch {
 		case avgRIR >= 2.5:
 			sugCarga = roundTo(avgCarga+5.0, 0.5)
-			rationale = "RIR muito alto, sugere +5kg"
+			rationale = i18n.T(lang, "overload.plus5")
 		case avgRIR >= 1.8:
 			sugCarga = roundTo(avgCarga+2.5, 0.5)
-			rationale = "RIR alto, sugere +2.5kg"
+			rationale = i18n.T(lang, "overload.plus2_5")
 		case avgRIR <= 0.5:
 			sugReps = 8
-			rationale = "RIR baixo, manter carga e reduzir reps"
+			rationale = i18n.T(lang, "overload.reduce_reps")
+		}
+
+		// deload ativo (manual, recomendado ou semana do programa): reduz a carga
+		dl, err := activeDeload(r.Context(), db, userID, day)
+		if err != nil {
+			log.Printf("[overload] deload lookup failed: %v", err)
+		}
+		if dl != nil {
+			sugCarga = roundTo(avgCarga*dl.LoadFactor, 0.5)
+			rationale = i18n.T(lang, "overload.deload", dl.Source, dl.EndsOn, dl.LoadFactor*100)
+		}
+
+		// prontidão do dia: escala a carga (o deload já reduz, então só sem deload)
+		var rdScore, rdFactor *float64
+		if dl == nil {
+			rd, err := loadReadiness(r.Context(), db, userID, day)
+			if err != nil {
+				log.Printf("[overload] readiness lookup failed: %v", err)
+			}
+			if rd != nil {
+				rdScore, rdFactor = &rd.Score, &rd.LoadFactor
+				if rd.LoadFactor != 1 {
+					sugCarga = roundTo(sugCarga*rd.LoadFactor, 0.5)
+					rationale += "; " + readinessRationale(lang, rd)
+				}
+			}
 		}
 
 		resp := overloadResp{
@@ -162,6 +209,9 @@ func OverloadSuggest(db *sql.DB) http.Handler {
 			AvgCargaKg:       roundTo(avgCarga, 0.5),
 			AvgRIR:           avgRIR,
 			SampleCount:      n,
+			Deload:           dl != nil,
+			ReadinessScore:   rdScore,
+			ReadinessFactor:  rdFactor,
 		}
 		jsonWrite(w, http.StatusOK, resp)
 		insertOverloadLog(db, r, in, resp)
@@ -231,22 +281,6 @@ func insertOverloadLog(db *sql.DB, r *http.Request, in overloadReq, out overload
 	}
 }
 
-func clientIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		for i := 0; i < len(xff); i++ {
-			if xff[i] == ',' {
-				return xff[:i]
-			}
-		}
-		return xff
-	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return host
-}
-
 // nullString retorna nil para strings vazias (útil para INSERTs opcionais)
 func nullString(s string) any {
 	s = strings.TrimSpace(s)
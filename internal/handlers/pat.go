@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tokens de acesso pessoal (PAT): "anima_pat_<base64url>", enviados como
+// Bearer no lugar do JWT. Valem só para as rotas cobertas pelos escopos
+// (EnforceScopes, tabela patRoutes); rotas fora da tabela recusam PAT.
+// Gerenciar PATs e sessões exige login (JWT): um PAT não cria outro PAT.
+
+const patPrefix = "anima_pat_"
+
+const (
+	ctxKeyAuthKind ctxKey = "auth_kind" // "jwt" | "pat"
+	ctxKeyScopes   ctxKey = "scopes"
+)
+
+// DB para resolver PATs nos middlewares de auth.
+var authDB *sql.DB
+
+func SetAuthDB(db *sql.DB) { authDB = db }
+
+// patScopes: escopos válidos. write:X implica read:X.
+var patScopes = map[string]string{
+	"read:sessions":  "ler sessões, sets e sugestões de carga",
+	"write:sessions": "criar/editar/apagar sessões",
+	"write:sets":     "registrar e editar sets",
+	"read:plans":     "ler treinos, programas e planos",
+	"write:plans":    "gerar e salvar treinos, programas e planos",
+	"read:catalog":   "ler o catálogo de exercícios",
+	"write:catalog":  "criar/editar exercícios próprios",
+	"read:profile":   "ler perfil, métricas, metas e limitações",
+	"write:profile":  "editar perfil, métricas, metas e limitações",
+	"coach":          "conversar com o coach",
+	"admin":          "rotas /api/admin/* (exige papel admin)",
+}
+
+// patRoute: prefixo -> escopo de leitura (GET/HEAD) e de escrita.
+type patRoute struct {
+	prefix      string
+	read, write string
+}
+
+// Mais específico primeiro; write vazio = rota fechada para PAT.
+var patRoutes = []patRoute{
+	{"/api/me/tokens", "", ""},
+	{"/api/me/sessions", "", ""},
+	{"/api/me/mfa", "", ""},
+	{"/api/admin/", "admin", "admin"},
+	{"/api/sets/", "read:sessions", "write:sets"},
+	{"/api/sessions", "read:sessions", "write:sessions"},
+	{"/api/overload/", "read:sessions", "read:sessions"},
+	{"/api/suggestions/", "read:sessions", "read:sessions"},
+	{"/api/treinos", "read:plans", "write:plans"},
+	{"/api/programs", "read:plans", "write:plans"},
+	{"/api/plan/", "read:plans", "write:plans"},
+	{"/api/exercises", "read:catalog", "write:catalog"},
+	{"/api/me/exercises", "read:catalog", "write:catalog"},
+	{"/api/me/plateaus", "read:sessions", "read:sessions"},
+	{"/api/me/", "read:profile", "write:profile"},
+	{"/api/coach/", "coach", "coach"},
+}
+
+// patScopeFor: escopo exigido de um PAT nesta requisição (ok=false: rota fechada).
+func patScopeFor(r *http.Request) (string, bool) {
+	p := r.URL.Path
+	if !strings.HasPrefix(p, "/api/") || strings.HasPrefix(p, "/api/auth/") {
+		return "", true // público / fluxo de login
+	}
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	for _, rt := range patRoutes {
+		if !strings.HasPrefix(p, rt.prefix) {
+			continue
+		}
+		if rt.write == "" {
+			return "", false
+		}
+		// sets de uma sessão: /api/sessions/{id}/sets, /log-text
+		if rt.prefix == "/api/sessions" && !read && (strings.HasSuffix(p, "/sets") || strings.HasSuffix(p, "/log-text")) {
+			return "write:sets", true
+		}
+		if read {
+			return rt.read, true
+		}
+		return rt.write, true
+	}
+	return "", false
+}
+
+func scopeAllows(scopes []string, want string) bool {
+	for _, s := range scopes {
+		if s == want {
+			return true
+		}
+		if res, ok := strings.CutPrefix(want, "read:"); ok && s == "write:"+res {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthKind: "jwt", "pat" ou "" (não autenticado).
+func AuthKind(r *http.Request) string {
+	k, _ := r.Context().Value(ctxKeyAuthKind).(string)
+	return k
+}
+
+func GetScopes(r *http.Request) []string {
+	s, _ := r.Context().Value(ctxKeyScopes).([]string)
+	return s
+}
+
+// EnforceScopes: requisições com PAT só passam com o escopo da rota.
+func EnforceScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if AuthKind(r) != "pat" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scope, ok := patScopeFor(r)
+		if !ok || (scope != "" && !scopeAllows(GetScopes(r), scope)) {
+			jsonWrite(w, http.StatusForbidden, map[string]string{"error": "token scope does not allow this route"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+var errPATInvalid = errors.New("invalid personal access token")
+
+// resolvePAT valida o token e registra o uso.
+func resolvePAT(r *http.Request, token string) (*http.Request, error) {
+	if authDB == nil {
+		return nil, errPATInvalid
+	}
+	var uid string
+	var scopes []string
+	err := authDB.QueryRowContext(r.Context(), `
+		UPDATE personal_access_tokens
+		SET last_used_at = now(), last_used_ip = NULLIF($2, '')
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > now()
+		RETURNING user_id::text, scopes`, hashAuthToken(token), clientIP(r)).Scan(&uid, pq.Array(&scopes))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errPATInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	r = SetUserID(r, uid)
+	ctx := context.WithValue(r.Context(), ctxKeyAuthKind, "pat")
+	ctx = context.WithValue(ctx, ctxKeyScopes, scopes)
+	return r.WithContext(ctx), nil
+}
+
+func newPAT() (token, hash string, err error) {
+	t, _, err := newAuthToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = patPrefix + t
+	return token, hashAuthToken(token), nil
+}
+
+type personalToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"` // só na criação
+}
+
+type patCreateReq struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// MeTokens (somente com JWT):
+//
+//	GET    /api/me/tokens       -> PATs ativos (sem o segredo)
+//	POST   /api/me/tokens       {"name","scopes":[...],"expires_in_days":90} -> 201 com o token (única vez)
+//	DELETE /api/me/tokens/{id}  -> revoga
+func MeTokens(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uid := GetUserID(r)
+		if uid == "" || AuthKind(r) != "jwt" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/me/tokens"), "/")
+
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			rows, err := db.QueryContext(r.Context(), `
+				SELECT id, name, token_prefix, scopes, expires_at, last_used_at, COALESCE(last_used_ip, ''), created_at
+				FROM personal_access_tokens
+				WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+				ORDER BY created_at DESC`, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			defer rows.Close()
+			out := []personalToken{}
+			for rows.Next() {
+				var t personalToken
+				var used sql.NullTime
+				if err := rows.Scan(&t.ID, &t.Name, &t.Prefix, pq.Array(&t.Scopes), &t.ExpiresAt, &used, &t.LastUsedIP, &t.CreatedAt); err != nil {
+					internalErr(w, err)
+					return
+				}
+				if used.Valid {
+					t.LastUsedAt = &used.Time
+				}
+				out = append(out, t)
+			}
+			if err := rows.Err(); err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, map[string]any{"items": out})
+
+		case r.Method == http.MethodPost && id == "":
+			var in patCreateReq
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				badRequest(w, "invalid json")
+				return
+			}
+			name := strings.TrimSpace(in.Name)
+			if name == "" || len(name) > 100 {
+				badRequest(w, "name is required (max 100 chars)")
+				return
+			}
+			scopes, err := normalizeScopes(in.Scopes)
+			if err != nil {
+				badRequest(w, err.Error())
+				return
+			}
+			days := in.ExpiresInDays
+			if days == 0 {
+				days = 90
+			}
+			if max := atoiEnvInt("PAT_MAX_TTL_DAYS", 365); days < 1 || days > max {
+				badRequest(w, "expires_in_days must be between 1 and "+fmtInt(max))
+				return
+			}
+			if scopeAllows(scopes, "admin") {
+				roles, err := loadUserRoles(r.Context(), db, uid)
+				if err != nil {
+					internalErr(w, err)
+					return
+				}
+				if !hasRole(roles, RoleAdmin) {
+					jsonWrite(w, http.StatusForbidden, map[string]string{"error": "admin scope requires the admin role"})
+					return
+				}
+			}
+			var active int
+			if err := db.QueryRowContext(r.Context(), `
+				SELECT COUNT(*) FROM personal_access_tokens
+				WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()`, uid).Scan(&active); err != nil {
+				internalErr(w, err)
+				return
+			}
+			if active >= atoiEnvInt("PAT_MAX_PER_USER", 50) {
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": "too many active tokens"})
+				return
+			}
+
+			token, hash, err := newPAT()
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			t := personalToken{Name: name, Prefix: token[:len(patPrefix)+6], Scopes: scopes, Token: token}
+			err = db.QueryRowContext(r.Context(), `
+				INSERT INTO personal_access_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+				VALUES ($1, $2, $3, $4, $5, now() + $6 * interval '1 day')
+				RETURNING id, expires_at, created_at`,
+				uid, name, hash, t.Prefix, pq.Array(scopes), days).Scan(&t.ID, &t.ExpiresAt, &t.CreatedAt)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			jsonWrite(w, http.StatusCreated, t)
+
+		case r.Method == http.MethodDelete && id != "":
+			res, err := db.ExecContext(r.Context(), `
+				UPDATE personal_access_tokens SET revoked_at = now()
+				WHERE id::text = $1 AND user_id = $2 AND revoked_at IS NULL`, id, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				notFound(w)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// normalizeScopes valida, remove duplicatas e ordena.
+func normalizeScopes(in []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, s := range in {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if _, ok := patScopes[s]; !ok {
+			return nil, errors.New("unknown scope: " + s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	sort.Strings(out)
+	return out, nil
+}
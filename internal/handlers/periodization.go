@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+)
+
+// ====== Periodização (linear, DUP, blocos)
+//
+// Gera parâmetros por sessão do mesociclo: fase, intensidade (% do e1RM),
+// fator de volume sobre as séries do gerador e faixa de reps.
+
+const (
+	modelLinear = "linear"
+	modelDUP    = "dup"
+	modelBlock  = "block"
+)
+
+type sessionParams struct {
+	Week         int
+	Day          int
+	Phase        string
+	Deload       bool
+	IntensityPct float64 // fração do e1RM (0.70 = 70%)
+	VolumeFactor float64 // multiplica as séries do gerador
+	RepTarget    string
+}
+
+// faixa de intensidade por objetivo (início/fim das semanas de carga)
+type intensityBand struct{ lo, hi float64 }
+
+func bandForGoal(goalKey string) intensityBand {
+	switch goalKey {
+	case "forca":
+		return intensityBand{0.75, 0.92}
+	case "emagrecimento", "resistencia":
+		return intensityBand{0.55, 0.72}
+	default:
+		return intensityBand{0.65, 0.82}
+	}
+}
+
+// repsForIntensity: faixa de reps compatível com a intensidade (~2 RIR).
+func repsForIntensity(pct float64) string {
+	switch {
+	case pct >= 0.90:
+		return "2-3"
+	case pct >= 0.85:
+		return "3-5"
+	case pct >= 0.80:
+		return "4-6"
+	case pct >= 0.75:
+		return "6-8"
+	case pct >= 0.70:
+		return "8-10"
+	case pct >= 0.65:
+		return "10-12"
+	case pct >= 0.60:
+		return "12-15"
+	default:
+		return "15-20"
+	}
+}
+
+// isDeloadWeek: a cada `every` semanas (0 = nunca).
+func isDeloadWeek(week, every int) bool {
+	return every > 0 && week%every == 0
+}
+
+// periodize monta weeks x days sessões. Semanas de deload mantêm a faixa de
+// reps da semana anterior com ~90% da intensidade e metade do volume.
+func periodize(model string, weeks, days, deloadEvery int, band intensityBand) ([][]sessionParams, error) {
+	if weeks < 1 || days < 1 {
+		return nil, fmt.Errorf("weeks and days must be positive")
+	}
+
+	loading := 0
+	for w := 1; w <= weeks; w++ {
+		if !isDeloadWeek(w, deloadEvery) {
+			loading++
+		}
+	}
+	if loading == 0 {
+		return nil, fmt.Errorf("no loading weeks")
+	}
+
+	// progresso 0..1 ao longo das semanas de carga
+	progress := func(k int) float64 {
+		if loading == 1 {
+			return 1
+		}
+		return float64(k) / float64(loading-1)
+	}
+
+	// blocos: acumulação ~40%, realização ~20%, intensificação o restante
+	accLen := max(1, int(math.Round(float64(loading)*0.4)))
+	realLen := max(1, int(math.Round(float64(loading)*0.2)))
+	if accLen+realLen > loading {
+		realLen = loading - accLen
+	}
+	accEnd := accLen
+	intEnd := loading - realLen
+
+	out := make([][]sessionParams, 0, weeks)
+	k := 0 // índice da semana de carga
+	var prev []sessionParams
+	for w := 1; w <= weeks; w++ {
+		week := make([]sessionParams, 0, days)
+
+		if isDeloadWeek(w, deloadEvery) && prev != nil {
+			for d := 1; d <= days; d++ {
+				p := prev[(d-1)%len(prev)]
+				week = append(week, sessionParams{
+					Week:         w,
+					Day:          d,
+					Phase:        "deload",
+					Deload:       true,
+					IntensityPct: round2(p.IntensityPct * 0.9),
+					VolumeFactor: 0.5,
+					RepTarget:    p.RepTarget,
+				})
+			}
+			out = append(out, week)
+			continue
+		}
+
+		t := progress(k)
+		for d := 1; d <= days; d++ {
+			var p sessionParams
+			switch model {
+			case modelDUP:
+				// pesado / moderado / leve em rodízio dentro da semana
+				step := 0.02 * float64(k)
+				switch (d - 1) % 3 {
+				case 0:
+					p = sessionParams{Phase: "heavy", IntensityPct: band.hi - 0.04 + step, VolumeFactor: 0.8}
+				case 1:
+					p = sessionParams{Phase: "moderate", IntensityPct: (band.lo+band.hi)/2 + step, VolumeFactor: 1.0}
+				default:
+					p = sessionParams{Phase: "light", IntensityPct: band.lo + step, VolumeFactor: 1.2}
+				}
+				if p.IntensityPct > band.hi+0.05 {
+					p.IntensityPct = band.hi + 0.05
+				}
+			case modelBlock:
+				mid := (band.lo + band.hi) / 2
+				switch {
+				case k < accEnd:
+					p = sessionParams{Phase: "accumulation", IntensityPct: band.lo + 0.05*blockProgress(k, 0, accEnd), VolumeFactor: 1.2}
+				case k < intEnd:
+					p = sessionParams{Phase: "intensification", IntensityPct: mid + 0.05*blockProgress(k, accEnd, intEnd), VolumeFactor: 1.0}
+				default:
+					p = sessionParams{Phase: "realization", IntensityPct: band.hi + 0.03*blockProgress(k, intEnd, loading), VolumeFactor: 0.7}
+				}
+			default: // linear: intensidade sobe, volume cai
+				p = sessionParams{
+					Phase:        "linear",
+					IntensityPct: band.lo + (band.hi-band.lo)*t,
+					VolumeFactor: 1.1 - 0.3*t,
+				}
+			}
+			p.Week, p.Day = w, d
+			p.IntensityPct = round2(p.IntensityPct)
+			p.VolumeFactor = round2(p.VolumeFactor)
+			p.RepTarget = repsForIntensity(p.IntensityPct)
+			week = append(week, p)
+		}
+		out = append(out, week)
+		prev = week
+		k++
+	}
+	return out, nil
+}
+
+// SessionParams / Periodize / DayOffsets exportados pros testes
+type SessionParams = sessionParams
+
+func Periodize(model string, weeks, days, deloadEvery int, goalKey string) ([][]SessionParams, error) {
+	return periodize(model, weeks, days, deloadEvery, bandForGoal(goalKey))
+}
+
+func DayOffsets(days int) []int { return dayOffsets(days) }
+
+func blockProgress(k, start, end int) float64 {
+	if end-start <= 1 {
+		return 0
+	}
+	return float64(k-start) / float64(end-start-1)
+}
+
+// scaleSets aplica o fator de volume às séries (mín. 1, máx. 8).
+func scaleSets(series int, factor float64) int {
+	return clampInt(int(math.Round(float64(series)*factor)), 1, 8)
+}
+
+// dayOffsets distribui os dias de treino na semana (0 = dia de início).
+func dayOffsets(days int) []int {
+	switch days {
+	case 1:
+		return []int{0}
+	case 2:
+		return []int{0, 3}
+	case 3:
+		return []int{0, 2, 4}
+	case 4:
+		return []int{0, 1, 3, 4}
+	case 5:
+		return []int{0, 1, 2, 3, 4}
+	case 6:
+		return []int{0, 1, 2, 3, 4, 5}
+	default:
+		return []int{0, 1, 2, 3, 4, 5, 6}
+	}
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
@@ -7,22 +7,27 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"anima/internal/i18n"
 )
 
 type WeeklyPlanDay struct {
 	DayIndex   int                 `json:"day_index"`
 	Divisao    string              `json:"divisao"`
+	Label      string              `json:"label"` // "Dia 1 · Empurrar" (no idioma da requisição)
 	TreinoID   string              `json:"treino_id"`
 	Exercicios []GeneratedExercise `json:"exercicios"`
 	CoachNotes string              `json:"coach_notes,omitempty"`
+	Explain    *PlanExplain        `json:"explain,omitempty"`
 }
 
 type WeeklyPlanResp struct {
-	Objetivo string          `json:"objetivo"`
-	Nivel    string          `json:"nivel"`
-	Dias     int             `json:"dias"`
-	BaseDiv  string          `json:"divisao_base"`
-	Items    []WeeklyPlanDay `json:"items"`
+	Objetivo    string          `json:"objetivo"`
+	Nivel       string          `json:"nivel"`
+	Dias        int             `json:"dias"`
+	BaseDiv     string          `json:"divisao_base"`
+	RuleVersion int             `json:"rule_version"`
+	Items       []WeeklyPlanDay `json:"items"`
 }
 
 func PlanWeekly(db *sql.DB) http.Handler {
@@ -53,13 +58,28 @@ func PlanWeekly(db *sql.DB) http.Handler {
 			}
 		}
 
-		uid := getUserID(r)
-		prof, _ := loadUserProfile(r.Context(), db, uid)
-		if wkg, _ := latestWeight(r.Context(), db, uid); wkg != nil {
-			prof.WeightKG = wkg
+		explain := strings.EqualFold(r.URL.Query().Get("explain"), "true")
+
+		uid := strings.TrimSpace(GetUserID(r))
+		if uid == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
 		}
+		prof, err := loadUserProfile(r.Context(), db, uid)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		applyWeightTrend(r.Context(), db, uid, &prof)
 
-		seq := divisionSequence(div) // sequência de divisões nos dias
+		env, err := loadPlanEnv(r.Context(), db, uid)
+		if err != nil {
+			http.Error(w, "erro no planner: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		seq := env.rules.sequenceFor(div) // sequência de divisões nos dias
+		lang := requestLang(r)
 		out := make([]WeeklyPlanDay, 0, days)
 
 		for i := 0; i < days; i++ {
@@ -71,59 +91,38 @@ func PlanWeekly(db *sql.DB) http.Handler {
 				Dias:     days,
 				Persist:  ptrBool(false), // preview, não persiste
 				TreinoID: "week-" + time.Now().Format("20060102") + "-d" + strconv.Itoa(i+1),
+				Explain:  explain,
 			}
 			// monta plano v1.1
-			exs, err := buildPlanV11(r.Context(), db, req)
+			exs, dayExplain, err := buildPlanV11(r.Context(), db, env, req)
 			if err != nil {
 				http.Error(w, "erro no planner: "+err.Error(), http.StatusInternalServerError)
 				return
 			}
-			coach := ""
-			if prof.UseAI == nil || *prof.UseAI {
-				coach = buildCoachNotes(req, prof)
-			}
+			coach := buildCoachNotes(r.Context(), db, uid, req, prof, exs)
+			localizeExerciseNames(r.Context(), db, lang, exs)
 			out = append(out, WeeklyPlanDay{
 				DayIndex:   i + 1,
 				Divisao:    dayDiv,
+				Label:      i18n.T(lang, "plan.day", i+1, i18n.Label(lang, "division", dayDiv)),
 				TreinoID:   req.TreinoID,
 				Exercicios: exs,
 				CoachNotes: coach,
+				Explain:    dayExplain,
 			})
 		}
 
 		resp := WeeklyPlanResp{
-			Objetivo: obj,
-			Nivel:    niv,
-			Dias:     days,
-			BaseDiv:  div,
-			Items:    out,
+			Objetivo:    obj,
+			Nivel:       niv,
+			Dias:        days,
+			BaseDiv:     div,
+			RuleVersion: env.rules.Version,
+			Items:       out,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	})
 }
 
-// Mapeia a divisão base para uma sequência de dias
-func divisionSequence(base string) []string {
-	b := strings.ToLower(strings.TrimSpace(base))
-	switch b {
-	case "upperlower":
-		return []string{"upper", "lower"}
-	case "upper":
-		return []string{"upper"}
-	case "lower":
-		return []string{"lower"}
-	case "ppl":
-		return []string{"push", "pull", "legs"}
-	case "push":
-		return []string{"push"}
-	case "pull":
-		return []string{"pull"}
-	case "legs":
-		return []string{"legs"}
-	default: // fullbody
-		return []string{"fullbody"}
-	}
-}
-
 func ptrBool(b bool) *bool { return &b }
@@ -25,11 +25,12 @@ type WeeklySaveItem struct {
 }
 
 type WeeklySaveResp struct {
-	Objetivo string           `json:"objetivo"`
-	Nivel    string           `json:"nivel"`
-	Dias     int              `json:"dias"`
-	BaseDiv  string           `json:"divisao_base"`
-	Items    []WeeklySaveItem `json:"items"`
+	Objetivo    string           `json:"objetivo"`
+	Nivel       string           `json:"nivel"`
+	Dias        int              `json:"dias"`
+	BaseDiv     string           `json:"divisao_base"`
+	RuleVersion int              `json:"rule_version"`
+	Items       []WeeklySaveItem `json:"items"`
 }
 
 func PlanWeeklySave(db *sql.DB) http.Handler {
@@ -67,13 +68,25 @@ func PlanWeeklySave(db *sql.DB) http.Handler {
 			prefix = "week-" + time.Now().Format("20060102")
 		}
 
-		uid := getUserID(r)
-		prof, _ := loadUserProfile(r.Context(), db, uid)
-		if wkg, _ := latestWeight(r.Context(), db, uid); wkg != nil {
-			prof.WeightKG = wkg
+		uid := strings.TrimSpace(GetUserID(r))
+		if uid == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+		prof, err := loadUserProfile(r.Context(), db, uid)
+		if err != nil {
+			internalErr(w, err)
+			return
 		}
+		applyWeightTrend(r.Context(), db, uid, &prof)
 
-		seq := divisionSequence(div)
+		env, err := loadPlanEnv(r.Context(), db, uid)
+		if err != nil {
+			http.Error(w, "erro no planner: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		seq := env.rules.sequenceFor(div)
 		items := make([]WeeklySaveItem, 0, days)
 
 		for i := 0; i < days; i++ {
@@ -90,7 +103,7 @@ func PlanWeeklySave(db *sql.DB) http.Handler {
 			}
 
 			// monta plano (usa v1.1 com descanso)
-			exs, err := buildPlanV11(r.Context(), db, genReq)
+			exs, _, err := buildPlanV11(r.Context(), db, env, genReq)
 			if err != nil {
 				http.Error(w, "erro no planner: "+err.Error(), http.StatusInternalServerError)
 				return
@@ -100,12 +113,9 @@ func PlanWeeklySave(db *sql.DB) http.Handler {
 				return
 			}
 
-			coach := ""
-			if prof.UseAI == nil || *prof.UseAI {
-				coach = buildCoachNotes(genReq, prof)
-			}
+			coach := buildCoachNotes(r.Context(), db, uid, genReq, prof, exs)
 
-			id, err := persistPlanHandleDup(r.Context(), db, key, genReq, coach, exs)
+			id, err := persistPlanHandleDup(r.Context(), db, key, genReq, env.rules.Version, coach, exs)
 			if err != nil {
 				http.Error(w, "falha ao salvar dia "+strconv.Itoa(i+1)+": "+err.Error(), http.StatusInternalServerError)
 				return
@@ -119,26 +129,27 @@ func PlanWeeklySave(db *sql.DB) http.Handler {
 		}
 
 		resp := WeeklySaveResp{
-			Objetivo: obj,
-			Nivel:    niv,
-			Dias:     days,
-			BaseDiv:  div,
-			Items:    items,
+			Objetivo:    obj,
+			Nivel:       niv,
+			Dias:        days,
+			BaseDiv:     div,
+			RuleVersion: env.rules.Version,
+			Items:       items,
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	})
 }
 
-func persistPlanHandleDup(ctx context.Context, db *sql.DB, key string, req GenerateReq, coach string, plan []GeneratedExercise) (int, error) {
-	id, err := persistPlan(ctx, db, key, req, coach, plan)
+func persistPlanHandleDup(ctx context.Context, db *sql.DB, key string, req GenerateReq, ruleVersion int, coach string, plan []GeneratedExercise) (int, error) {
+	id, err := persistPlan(ctx, db, key, req, ruleVersion, coach, plan)
 	if err == nil {
 		return id, nil
 	}
 	// se conflitar por chave lógica, tenta variar
 	if strings.Contains(strings.ToLower(err.Error()), "duplicate key") {
 		key2 := key + "-" + time.Now().Format("150405.000")
-		return persistPlan(ctx, db, key2, req, coach, plan)
+		return persistPlan(ctx, db, key2, req, ruleVersion, coach, plan)
 	}
 	return 0, err
 }
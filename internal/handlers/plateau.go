@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"anima/internal/i18n"
+)
+
+// Platôs: tendência semanal do e1RM por exercício (regressão linear sobre o
+// melhor e1RM de cada semana) + sugestões de intervenção quando estagnado ou
+// regredindo (faixa de reps, variação do catálogo, volume).
+
+const (
+	trendMinWeeks     = 3    // semanas com dados para classificar
+	trendProgressPct  = 0.5  // % e1RM/semana acima disso => progredindo
+	trendRegressPct   = -0.5 // abaixo disso => regredindo
+	trendStallPRWeeks = 3    // semanas sem PR com inclinação fraca => estagnado
+)
+
+type trendPoint struct {
+	Week         string  `json:"week"` // segunda-feira da semana
+	E1RMKg       float64 `json:"e1rm_kg"`
+	BestWeightKg float64 `json:"best_weight_kg"`
+	BestReps     int     `json:"best_reps"`
+	Sets         int     `json:"sets"`
+}
+
+type trendSuggestion struct {
+	Kind        string `json:"kind"` // rep_range | variant_swap | volume
+	Detail      string `json:"detail"`
+	RepRange    string `json:"rep_range,omitempty"`
+	ExercicioID *int64 `json:"exercicio_id,omitempty"`
+	Nome        string `json:"nome,omitempty"`
+	SetsDelta   int    `json:"sets_delta,omitempty"` // séries/semana
+}
+
+type exerciseTrend struct {
+	ExercicioID     int64             `json:"exercicio_id"`
+	Nome            string            `json:"nome"`
+	Status          string            `json:"status"` // progressing | stalled | regressing | insufficient_data
+	SlopePctPerWeek float64           `json:"slope_pct_per_week"`
+	BestE1RMKg      float64           `json:"best_e1rm_kg"`
+	WeeksSincePR    int               `json:"weeks_since_pr"`
+	AvgWeeklySets   float64           `json:"avg_weekly_sets"`
+	Points          []trendPoint      `json:"points"`
+	Suggestions     []trendSuggestion `json:"suggestions"`
+
+	muscleGroup string
+}
+
+// linreg: inclinação de y sobre x (mínimos quadrados).
+func linreg(x, y []float64) float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+	mx, my := mean(x), mean(y)
+	num, den := 0.0, 0.0
+	for i := range x {
+		num += (x[i] - mx) * (y[i] - my)
+		den += (x[i] - mx) * (x[i] - mx)
+	}
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
+
+// classifyTrend preenche status/inclinação/PR a partir dos pontos (ordenados por semana).
+func classifyTrend(t *exerciseTrend, now time.Time) {
+	t.Status = "insufficient_data"
+	if len(t.Points) == 0 {
+		return
+	}
+
+	first, _ := time.Parse("2006-01-02", t.Points[0].Week)
+	var xs, ys, sets []float64
+	prIdx := 0
+	for i, p := range t.Points {
+		wk, _ := time.Parse("2006-01-02", p.Week)
+		xs = append(xs, wk.Sub(first).Hours()/(24*7))
+		ys = append(ys, p.E1RMKg)
+		sets = append(sets, float64(p.Sets))
+		if p.E1RMKg > t.Points[prIdx].E1RMKg {
+			prIdx = i
+		}
+	}
+	t.BestE1RMKg = t.Points[prIdx].E1RMKg
+	prWeek, _ := time.Parse("2006-01-02", t.Points[prIdx].Week)
+	t.WeeksSincePR = int(now.Sub(prWeek).Hours() / (24 * 7))
+	t.AvgWeeklySets = round2(mean(sets))
+
+	if len(t.Points) < trendMinWeeks {
+		return
+	}
+	if m := mean(ys); m > 0 {
+		t.SlopePctPerWeek = round2(linreg(xs, ys) / m * 100)
+	}
+	switch {
+	case t.SlopePctPerWeek <= trendRegressPct:
+		t.Status = "regressing"
+	case t.SlopePctPerWeek >= trendProgressPct && t.WeeksSincePR < trendStallPRWeeks:
+		t.Status = "progressing"
+	default:
+		// inclinação fraca ou sem PR há semanas
+		t.Status = "stalled"
+	}
+}
+
+// repSuggestion: troca de faixa pelo padrão de reps dos melhores sets.
+func repSuggestion(lang i18n.Lang, points []trendPoint) trendSuggestion {
+	reps := make([]float64, 0, len(points))
+	for _, p := range points {
+		reps = append(reps, float64(p.BestReps))
+	}
+	avg := mean(reps)
+	s := trendSuggestion{Kind: "rep_range"}
+	switch {
+	case avg <= 6:
+		s.RepRange = "8-12"
+		s.Detail = i18n.T(lang, "plateau.reps.volume", avg)
+	case avg >= 10:
+		s.RepRange = "4-6"
+		s.Detail = i18n.T(lang, "plateau.reps.heavy", avg)
+	default:
+		s.RepRange = "3-5"
+		s.Detail = i18n.T(lang, "plateau.reps.alternate", avg)
+	}
+	return s
+}
+
+// volumeSuggestion: estagnado com pouco volume => +séries; regredindo ou volume alto => -séries.
+func volumeSuggestion(lang i18n.Lang, t *exerciseTrend) *trendSuggestion {
+	switch {
+	case t.Status == "regressing" || t.AvgWeeklySets >= 16:
+		return &trendSuggestion{Kind: "volume", SetsDelta: -max(2, int(math.Round(t.AvgWeeklySets*0.3))),
+			Detail: i18n.T(lang, "plateau.volume.reduce", t.AvgWeeklySets)}
+	case t.AvgWeeklySets < 10:
+		return &trendSuggestion{Kind: "volume", SetsDelta: 2,
+			Detail: i18n.T(lang, "plateau.volume.add", t.AvgWeeklySets)}
+	}
+	return nil
+}
+
+// suggestFor monta as intervenções (só estagnado/regredindo).
+func suggestFor(ctx context.Context, db *sql.DB, uid string, t *exerciseTrend, lp *limitProfile) error {
+	t.Suggestions = []trendSuggestion{}
+	if t.Status != "stalled" && t.Status != "regressing" {
+		return nil
+	}
+	lang := langOf(ctx)
+	t.Suggestions = append(t.Suggestions, repSuggestion(lang, t.Points))
+
+	src := exRow{id: int(t.ExercicioID), name: t.Nome, muscleGroup: t.muscleGroup}
+	subs, _, err := findSubstitutes(ctx, db, uid, src, lp, 1)
+	if err != nil {
+		return err
+	}
+	for _, s := range subs {
+		id := s.ID
+		t.Suggestions = append(t.Suggestions, trendSuggestion{
+			Kind: "variant_swap", ExercicioID: &id, Nome: s.Nome,
+			Detail: i18n.T(lang, "plateau.swap", s.Nome),
+		})
+	}
+
+	if v := volumeSuggestion(lang, t); v != nil {
+		t.Suggestions = append(t.Suggestions, *v)
+	}
+	return nil
+}
+
+// tipos, ClassifyTrend e TrendRuleSuggestions exportados pros testes
+type (
+	TrendPoint      = trendPoint
+	TrendSuggestion = trendSuggestion
+	ExerciseTrend   = exerciseTrend
+)
+
+func ClassifyTrend(t *ExerciseTrend, now time.Time) { classifyTrend(t, now) }
+
+// TrendRuleSuggestions: faixa de reps + volume (a troca de variante depende do catálogo).
+func TrendRuleSuggestions(lang i18n.Lang, t *ExerciseTrend) []TrendSuggestion {
+	out := []trendSuggestion{repSuggestion(lang, t.Points)}
+	if v := volumeSuggestion(lang, t); v != nil {
+		out = append(out, *v)
+	}
+	return out
+}
+
+// loadTrends: pontos semanais (melhor e1RM/semana) dos últimos `weeks`; exID=0 => todos.
+func loadTrends(ctx context.Context, db *sql.DB, uid string, exID int64, weeks int) ([]*exerciseTrend, error) {
+	args := []any{uid, weeks}
+	q := `
+		SELECT s.exercicio_id, e.name, lower(e.muscle_group),
+		       to_char(date_trunc('week', ws.started_at), 'YYYY-MM-DD') AS wk,
+		       MAX(` + e1rmSQL + `)::float8,
+		       COUNT(*),
+		       ((ARRAY_AGG(s.weight_kg ORDER BY ` + e1rmSQL + ` DESC))[1])::float8,
+		       (ARRAY_AGG(s.reps ORDER BY ` + e1rmSQL + ` DESC))[1]
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		JOIN exercises e ON e.id = s.exercicio_id
+		WHERE ws.user_id = $1
+		  AND s.completed = TRUE
+		  AND NOT s.flagged
+		  AND s.weight_kg > 0
+		  AND s.reps BETWEEN 1 AND 12
+		  AND ws.started_at >= date_trunc('week', NOW()) - make_interval(weeks => $2 - 1)
+	`
+	if exID > 0 {
+		q += ` AND s.exercicio_id = $3`
+		args = append(args, exID)
+	}
+	q += ` GROUP BY 1, 2, 3, 4 ORDER BY 1, 4`
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*exerciseTrend
+	var cur *exerciseTrend
+	for rows.Next() {
+		var (
+			id       int64
+			name, mg string
+			p        trendPoint
+		)
+		if err := rows.Scan(&id, &name, &mg, &p.Week, &p.E1RMKg, &p.Sets, &p.BestWeightKg, &p.BestReps); err != nil {
+			return nil, err
+		}
+		p.E1RMKg = math.Round(p.E1RMKg*10) / 10
+		if cur == nil || cur.ExercicioID != id {
+			cur = &exerciseTrend{ExercicioID: id, Nome: name, muscleGroup: mg, Points: []trendPoint{}}
+			out = append(out, cur)
+		}
+		cur.Points = append(cur.Points, p)
+	}
+	return out, rows.Err()
+}
+
+func trendWeeks(r *http.Request) int {
+	return clampInt(parseInt(r.URL.Query().Get("weeks"), 8), 4, 26)
+}
+
+// exerciseTrendHandler: GET /api/me/exercises/{id}/trend?weeks=8
+func exerciseTrendHandler(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string, id int64) {
+	var name, mg string
+	err := db.QueryRowContext(r.Context(), `
+		SELECT name, lower(muscle_group) FROM exercises
+		WHERE id = $1 AND `+exerciseVisibleSQL("owner_user_id", 2),
+		id, userID).Scan(&name, &mg)
+	if err == sql.ErrNoRows {
+		notFound(w)
+		return
+	}
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+
+	trends, err := loadTrends(r.Context(), db, userID, id, trendWeeks(r))
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	t := &exerciseTrend{ExercicioID: id, Nome: name, muscleGroup: mg, Points: []trendPoint{}}
+	if len(trends) > 0 {
+		t = trends[0]
+	}
+	classifyTrend(t, time.Now())
+
+	lp, err := loadLimits(r.Context(), db, userID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	if err := suggestFor(r.Context(), db, userID, t, lp); err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusOK, t)
+}
+
+// MePlateaus: resumo de tendência de todos os exercícios treinados no período.
+// GET /api/me/plateaus?weeks=8[&all=true]  (default: só estagnados/regredindo)
+func MePlateaus(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+		weeks := trendWeeks(r)
+		all := r.URL.Query().Get("all") == "true"
+
+		trends, err := loadTrends(r.Context(), db, userID, 0, weeks)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		lp, err := loadLimits(r.Context(), db, userID)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+
+		now := time.Now()
+		summary := map[string]int{"progressing": 0, "stalled": 0, "regressing": 0, "insufficient_data": 0}
+		items := []*exerciseTrend{}
+		for _, t := range trends {
+			classifyTrend(t, now)
+			summary[t.Status]++
+			if !all && t.Status != "stalled" && t.Status != "regressing" {
+				continue
+			}
+			if err := suggestFor(r.Context(), db, userID, t, lp); err != nil {
+				internalErr(w, err)
+				return
+			}
+			items = append(items, t)
+		}
+		// regredindo primeiro, depois pior inclinação
+		sort.SliceStable(items, func(i, j int) bool {
+			if (items[i].Status == "regressing") != (items[j].Status == "regressing") {
+				return items[i].Status == "regressing"
+			}
+			return items[i].SlopePctPerWeek < items[j].SlopePctPerWeek
+		})
+
+		jsonWrite(w, http.StatusOK, map[string]any{
+			"weeks":   weeks,
+			"summary": summary,
+			"items":   items,
+		})
+	})
+}
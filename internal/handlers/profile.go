@@ -1,221 +1,329 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
+
+	"anima/internal/i18n"
+
+	"github.com/lib/pq"
 )
 
-type userProfileOut struct {
-	HeightCM        *int     `json:"height_cm,omitempty"`
-	WeightKG        *float64 `json:"weight_kg,omitempty"`
-	BirthDate       *string  `json:"birth_date,omitempty"` // YYYY-MM-DD
-	TrainingGoal    *string  `json:"training_goal,omitempty"`
-	ExperienceLevel *string  `json:"experience_level,omitempty"`
-	ActivityLevel   *string  `json:"activity_level,omitempty"`
-	UseAI           *bool    `json:"use_ai,omitempty"`
-	Notes           *string  `json:"notes,omitempty"`
-	UpdatedAt       *string  `json:"updated_at,omitempty"` // RFC3339
+// Perfil único do usuário (user_profiles, migração 043). Lido pelo gerador,
+// notas do coach, nutrição e /api/me/profile — todos veem os mesmos valores.
+type userProfile struct {
+	HeightCM        *float64
+	WeightKG        *float64
+	WeeklyRateKG    *float64 // taxa da tendência de peso (user_metrics)
+	BirthDate       *time.Time
+	BirthYear       *int
+	Gender          *string
+	TrainingGoal    *string
+	ExperienceLevel *string
+	ActivityLevel   *string
+	UseAI           *bool
+	Language        *string // pt-BR | en (idioma das respostas, ver i18n.go)
+	Notes           *string
+	UpdatedAt       *time.Time
+}
+
+// age: pela data de nascimento; senão pelo ano.
+func (p userProfile) age(now time.Time) *int {
+	switch {
+	case p.BirthDate != nil:
+		a := computeAge(*p.BirthDate, now)
+		return &a
+	case p.BirthYear != nil:
+		a := now.Year() - *p.BirthYear
+		return &a
+	}
+	return nil
+}
+
+// Carrega perfil por user_id (vazio se ainda não existe)
+func loadUserProfile(ctx context.Context, db *sql.DB, userID string) (userProfile, error) {
+	var p userProfile
+	err := db.QueryRowContext(ctx, `
+		SELECT height_cm::float8, weight_kg::float8, birth_date, birth_year, gender,
+		       training_goal, experience_level, activity_level, use_ai, language, notes, updated_at
+		FROM user_profiles
+		WHERE user_id = $1
+	`, userID).Scan(&p.HeightCM, &p.WeightKG, &p.BirthDate, &p.BirthYear, &p.Gender,
+		&p.TrainingGoal, &p.ExperienceLevel, &p.ActivityLevel, &p.UseAI, &p.Language, &p.Notes, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return userProfile{}, nil
+	}
+	return p, err
 }
 
-type userProfileIn struct {
-	HeightCM        *int     `json:"height_cm"`
+type profileOut struct {
+	UserID          string     `json:"user_id"`
+	HeightCM        *float64   `json:"height_cm,omitempty"`
+	WeightKG        *float64   `json:"weight_kg,omitempty"`
+	BirthDate       *string    `json:"birth_date,omitempty"` // YYYY-MM-DD
+	BirthYear       *int       `json:"birth_year,omitempty"`
+	Age             *int       `json:"age,omitempty"`
+	Gender          *string    `json:"gender,omitempty"`
+	TrainingGoal    *string    `json:"training_goal,omitempty"`
+	ExperienceLevel *string    `json:"experience_level,omitempty"`
+	ActivityLevel   *string    `json:"activity_level,omitempty"`
+	UseAI           *bool      `json:"use_ai,omitempty"`
+	Language        *string    `json:"language,omitempty"`
+	Notes           *string    `json:"notes,omitempty"`
+	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
+
+	// aliases legados (027): mesmo valor de training_goal/experience_level
+	Goal  *string `json:"goal,omitempty"`
+	Level *string `json:"level,omitempty"`
+}
+
+func toProfileOut(userID string, p userProfile) profileOut {
+	out := profileOut{
+		UserID:          userID,
+		HeightCM:        p.HeightCM,
+		WeightKG:        p.WeightKG,
+		BirthYear:       p.BirthYear,
+		Age:             p.age(time.Now()),
+		Gender:          p.Gender,
+		TrainingGoal:    p.TrainingGoal,
+		ExperienceLevel: p.ExperienceLevel,
+		ActivityLevel:   p.ActivityLevel,
+		UseAI:           p.UseAI,
+		Language:        p.Language,
+		Notes:           p.Notes,
+		UpdatedAt:       p.UpdatedAt,
+		Goal:            p.TrainingGoal,
+		Level:           p.ExperienceLevel,
+	}
+	if p.BirthDate != nil {
+		s := p.BirthDate.Format("2006-01-02")
+		out.BirthDate = &s
+	}
+	return out
+}
+
+// profileIn: campos ausentes/null são mantidos; "" limpa textos.
+type profileIn struct {
+	HeightCM        *float64 `json:"height_cm"`
 	WeightKG        *float64 `json:"weight_kg"`
 	BirthDate       *string  `json:"birth_date"` // YYYY-MM-DD
+	BirthYear       *int     `json:"birth_year"`
+	Gender          *string  `json:"gender"`
 	TrainingGoal    *string  `json:"training_goal"`
 	ExperienceLevel *string  `json:"experience_level"`
 	ActivityLevel   *string  `json:"activity_level"`
 	UseAI           *bool    `json:"use_ai"`
+	Language        *string  `json:"language"` // pt-BR | en; "" limpa (volta ao Accept-Language)
 	Notes           *string  `json:"notes"`
+
+	Goal  *string `json:"goal"`  // alias legado de training_goal
+	Level *string `json:"level"` // alias legado de experience_level
 }
 
-// GET: /api/me/profile
-// PUT: /api/me/profile  (merge + upsert)
-func UserProfile(db *sql.DB) http.Handler {
+// MeProfile: GET (ler) e PATCH/PUT (merge + upsert) do perfil do usuário atual.
+// GET        /api/me/profile
+// PATCH|PUT  /api/me/profile   (profileIn; aceita goal/level legados)
+func MeProfile(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
-			getProfile(db, w, r)
-		case http.MethodPut:
-			putProfile(db, w, r)
+			p, err := loadUserProfile(r.Context(), db, userID)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, toProfileOut(userID, p))
+		case http.MethodPatch, http.MethodPut:
+			patchProfile(db, w, r, userID)
 		default:
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
 }
 
-func getProfile(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	uid := getUserID(r)
-
-	var h sql.NullInt64
-	var wkg sql.NullFloat64
-	var bd sql.NullTime
-	var goal, lvl, notes, act sql.NullString
-	var use sql.NullBool
-	var upd sql.NullTime
-
-	err := db.QueryRow(`
-		SELECT height_cm, weight_kg, birth_date, training_goal, experience_level,
-		       activity_level, use_ai, notes, updated_at
-		FROM user_profiles WHERE user_id=$1
-	`, uid).Scan(&h, &wkg, &bd, &goal, &lvl, &act, &use, &notes, &upd)
-
-	out := userProfileOut{}
-	switch err {
-	case nil:
-		if h.Valid {
-			v := int(h.Int64)
-			out.HeightCM = &v
-		}
-		if wkg.Valid {
-			v := wkg.Float64
-			out.WeightKG = &v
-		}
-		if bd.Valid {
-			s := bd.Time.Format("2006-01-02")
-			out.BirthDate = &s
-		}
-		if goal.Valid {
-			s := goal.String
-			out.TrainingGoal = &s
-		}
-		if lvl.Valid {
-			s := lvl.String
-			out.ExperienceLevel = &s
-		}
-		if act.Valid {
-			s := act.String
-			out.ActivityLevel = &s
-		}
-		if use.Valid {
-			v := use.Bool
-			out.UseAI = &v
-		}
-		if notes.Valid {
-			s := notes.String
-			out.Notes = &s
-		}
-		if upd.Valid {
-			s := upd.Time.UTC().Format(time.RFC3339)
-			out.UpdatedAt = &s
-		}
-	case sql.ErrNoRows:
-		// retorna objeto vazio
-	default:
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// setText: nil mantém, "" limpa.
+func setText(dst **string, v *string) {
+	if v == nil {
 		return
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(out)
+	if s := strings.TrimSpace(*v); s != "" {
+		*dst = &s
+	} else {
+		*dst = nil
+	}
 }
 
-func putProfile(db *sql.DB, w http.ResponseWriter, r *http.Request) {
-	uid := getUserID(r)
-	var in userProfileIn
+func patchProfile(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	var in profileIn
 	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		http.Error(w, "json inválido", http.StatusBadRequest)
+		badRequest(w, "invalid json")
 		return
 	}
+	if in.TrainingGoal == nil {
+		in.TrainingGoal = in.Goal
+	}
+	if in.ExperienceLevel == nil {
+		in.ExperienceLevel = in.Level
+	}
 
-	// Carrega atual
-	var curH sql.NullInt64
-	var curW sql.NullFloat64
-	var curBD sql.NullTime
-	var curGoal, curLvl, curAct, curNotes sql.NullString
-	var curUse sql.NullBool
+	// Validações leves (aplicadas somente se o campo veio no payload)
+	now := time.Now()
+	if in.HeightCM != nil && (*in.HeightCM < 50 || *in.HeightCM > 250) {
+		badRequest(w, "height_cm out of range (50..250)")
+		return
+	}
+	if in.WeightKG != nil && (*in.WeightKG < 20 || *in.WeightKG > 500) {
+		badRequest(w, "weight_kg out of range (20..500)")
+		return
+	}
+	if in.BirthYear != nil && (*in.BirthYear < 1900 || *in.BirthYear > now.Year()) {
+		badRequest(w, "birth_year out of range (1900..current year)")
+		return
+	}
 
-	err := db.QueryRow(`
-		SELECT height_cm, weight_kg, birth_date, training_goal, experience_level,
-		       activity_level, use_ai, notes
-		FROM user_profiles WHERE user_id=$1
-	`, uid).Scan(&curH, &curW, &curBD, &curGoal, &curLvl, &curAct, &curUse, &curNotes)
+	if in.Language != nil && strings.TrimSpace(*in.Language) != "" {
+		l, ok := i18n.Parse(*in.Language)
+		if !ok {
+			badRequest(w, "language must be pt-BR or en")
+			return
+		}
+		s := string(l)
+		in.Language = &s
+	}
 
-	if err != nil && err != sql.ErrNoRows {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	p, err := loadUserProfile(r.Context(), db, userID)
+	if err != nil {
+		internalErr(w, err)
 		return
 	}
 
 	// Merge
 	if in.HeightCM != nil {
-		if *in.HeightCM <= 0 {
-			curH = sql.NullInt64{}
-		} else {
-			curH = sql.NullInt64{Int64: int64(*in.HeightCM), Valid: true}
-		}
+		p.HeightCM = in.HeightCM
 	}
 	if in.WeightKG != nil {
-		if *in.WeightKG <= 0 {
-			curW = sql.NullFloat64{}
-		} else {
-			curW = sql.NullFloat64{Float64: *in.WeightKG, Valid: true}
+		p.WeightKG = in.WeightKG
+	}
+	if in.BirthYear != nil {
+		p.BirthYear = in.BirthYear
+		if p.BirthDate != nil && p.BirthDate.Year() != *in.BirthYear {
+			p.BirthDate = nil
 		}
 	}
 	if in.BirthDate != nil {
 		if *in.BirthDate == "" {
-			curBD = sql.NullTime{}
+			p.BirthDate, p.BirthYear = nil, nil
 		} else {
-			t, perr := time.Parse("2006-01-02", *in.BirthDate)
-			if perr != nil {
-				http.Error(w, "birth_date inválido (YYYY-MM-DD)", http.StatusBadRequest)
+			t, err := time.Parse("2006-01-02", *in.BirthDate)
+			if err != nil || t.Year() < 1900 || t.After(now) {
+				badRequest(w, "invalid birth_date (YYYY-MM-DD)")
 				return
 			}
-			curBD = sql.NullTime{Time: t, Valid: true}
-		}
-	}
-	if in.TrainingGoal != nil {
-		if *in.TrainingGoal == "" {
-			curGoal = sql.NullString{}
-		} else {
-			curGoal = sql.NullString{String: *in.TrainingGoal, Valid: true}
-		}
-	}
-	if in.ExperienceLevel != nil {
-		if *in.ExperienceLevel == "" {
-			curLvl = sql.NullString{}
-		} else {
-			curLvl = sql.NullString{String: *in.ExperienceLevel, Valid: true}
-		}
-	}
-	if in.ActivityLevel != nil {
-		if *in.ActivityLevel == "" {
-			curAct = sql.NullString{}
-		} else {
-			curAct = sql.NullString{String: *in.ActivityLevel, Valid: true}
+			y := t.Year()
+			p.BirthDate, p.BirthYear = &t, &y
 		}
 	}
+	setText(&p.Gender, in.Gender)
+	setText(&p.TrainingGoal, in.TrainingGoal)
+	setText(&p.ExperienceLevel, in.ExperienceLevel)
+	setText(&p.ActivityLevel, in.ActivityLevel)
+	setText(&p.Notes, in.Notes)
+	setText(&p.Language, in.Language)
 	if in.UseAI != nil {
-		curUse = sql.NullBool{Bool: *in.UseAI, Valid: true}
+		p.UseAI = in.UseAI
 	}
-	if in.Notes != nil {
-		if *in.Notes == "" {
-			curNotes = sql.NullString{}
-		} else {
-			curNotes = sql.NullString{String: *in.Notes, Valid: true}
-		}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		internalErr(w, err)
+		return
 	}
+	defer tx.Rollback()
 
-	// Upsert
-	_, err = db.Exec(`
+	_, err = tx.ExecContext(r.Context(), `
 		INSERT INTO user_profiles
-			(user_id, height_cm, weight_kg, birth_date, training_goal, experience_level, activity_level, use_ai, notes, updated_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9, now())
+			(user_id, height_cm, weight_kg, birth_date, birth_year, gender, training_goal,
+			 experience_level, activity_level, use_ai, notes, language, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,COALESCE($10, TRUE),$11,$12, NOW())
 		ON CONFLICT (user_id) DO UPDATE SET
-			height_cm = EXCLUDED.height_cm,
-			weight_kg = EXCLUDED.weight_kg,
-			birth_date = EXCLUDED.birth_date,
-			training_goal = EXCLUDED.training_goal,
+			height_cm        = EXCLUDED.height_cm,
+			weight_kg        = EXCLUDED.weight_kg,
+			birth_date       = EXCLUDED.birth_date,
+			birth_year       = EXCLUDED.birth_year,
+			gender           = EXCLUDED.gender,
+			training_goal    = EXCLUDED.training_goal,
 			experience_level = EXCLUDED.experience_level,
-			activity_level = EXCLUDED.activity_level,
-			use_ai = EXCLUDED.use_ai,
-			notes = EXCLUDED.notes,
-			updated_at = now()
-	`, uid, curH, curW, curBD, curGoal, curLvl, curAct, curUse, curNotes)
+			activity_level   = EXCLUDED.activity_level,
+			use_ai           = EXCLUDED.use_ai,
+			notes            = EXCLUDED.notes,
+			language         = EXCLUDED.language,
+			updated_at       = NOW()
+	`, userID, p.HeightCM, p.WeightKG, p.BirthDate, p.BirthYear, p.Gender, p.TrainingGoal,
+		p.ExperienceLevel, p.ActivityLevel, p.UseAI, p.Notes, p.Language)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Se algum processo antigo/cliente bypassar o handler, traduz o CHECK do Postgres para 400
+		if pqErr, ok := err.(*pq.Error); ok && string(pqErr.Code) == "23514" {
+			translateCheckViolation(w, pqErr)
+			return
+		}
+		internalErr(w, err)
+		return
+	}
+
+	// peso informado no perfil também entra na série de medidas (tendência)
+	if in.WeightKG != nil {
+		if _, err := tx.ExecContext(r.Context(), `
+			INSERT INTO user_metrics (user_id, measured_at, weight_kg)
+			VALUES ($1, CURRENT_DATE, $2)
+			ON CONFLICT (user_id, measured_at) DO UPDATE SET weight_kg = EXCLUDED.weight_kg
+		`, userID, *in.WeightKG); err != nil {
+			internalErr(w, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		internalErr(w, err)
 		return
 	}
 
-	getProfile(db, w, r)
+	p, err = loadUserProfile(r.Context(), db, userID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusOK, toProfileOut(userID, p))
+}
+
+// traduz 23514 (check_violation) em uma mensagem amigável (400)
+func translateCheckViolation(w http.ResponseWriter, pqErr *pq.Error) {
+	field := "unknown"
+	msg := "validation failed"
+
+	switch pqErr.Constraint {
+	case "ck_user_profiles_height_cm":
+		field = "height_cm"
+		msg = "height_cm out of range (50..250)"
+	case "ck_user_profiles_weight_kg":
+		field = "weight_kg"
+		msg = "weight_kg out of range (20..500)"
+	case "ck_user_profiles_birth_year":
+		field = "birth_year"
+		msg = "birth_year out of range (1900..current year)"
+	}
+
+	jsonWrite(w, http.StatusBadRequest, map[string]any{
+		"error":   "validation_failed",
+		"field":   field,
+		"message": msg,
+	})
 }
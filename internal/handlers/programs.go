@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"anima/internal/i18n"
+)
+
+// Programas periodizados: mesociclo de 4–16 semanas com treinos datados.
+// Cada sessão vira um treino (treinos/treino_exercicios) com séries e reps já
+// ajustadas à semana; program_workouts guarda data, fase e intensidade-alvo,
+// consumidos por /api/sessions/prefill.
+
+type programIn struct {
+	Name        string `json:"name,omitempty"`
+	Objetivo    string `json:"objetivo"`
+	Nivel       string `json:"nivel"`
+	Divisao     string `json:"divisao"`
+	Model       string `json:"model"`                  // linear | dup | block (default linear)
+	Weeks       int    `json:"weeks"`                  // 4..16
+	DaysPerWeek int    `json:"days_per_week"`          // 1..7 (default 3)
+	DeloadEvery *int   `json:"deload_every,omitempty"` // default 4; 0 = sem deload
+	StartDate   string `json:"start_date,omitempty"`   // YYYY-MM-DD (default hoje)
+}
+
+type Program struct {
+	ID          int64            `json:"id"`
+	Name        *string          `json:"name,omitempty"`
+	Model       string           `json:"model"`
+	Objetivo    string           `json:"objetivo"`
+	Nivel       string           `json:"nivel"`
+	Divisao     string           `json:"divisao"`
+	Weeks       int              `json:"weeks"`
+	DaysPerWeek int              `json:"days_per_week"`
+	DeloadEvery int              `json:"deload_every"`
+	StartDate   string           `json:"start_date"`
+	RuleVersion *int             `json:"rule_version,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Workouts    []ProgramWorkout `json:"workouts,omitempty"`
+}
+
+type ProgramWorkout struct {
+	ID            int64   `json:"id"`
+	Week          int     `json:"week"`
+	Day           int     `json:"day"`
+	ScheduledDate string  `json:"scheduled_date"`
+	Divisao       string  `json:"divisao"`
+	TreinoID      *int64  `json:"treino_id,omitempty"`
+	Phase         string  `json:"phase"`
+	Deload        bool    `json:"deload"`
+	IntensityPct  float64 `json:"intensity_pct"` // % do e1RM
+	VolumeFactor  float64 `json:"volume_factor"`
+	RepTarget     string  `json:"rep_target"`
+	SetsTarget    int     `json:"sets_target"`
+}
+
+// Programs
+// GET    /api/programs
+// POST   /api/programs       {objetivo, nivel, divisao, model, weeks, days_per_week, deload_every, start_date}
+// GET    /api/programs/{id}  (com treinos datados)
+// DELETE /api/programs/{id}
+func Programs(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/programs"), "/")
+		if rest == "" {
+			switch r.Method {
+			case http.MethodGet:
+				listPrograms(db, w, r, userID)
+			case http.MethodPost:
+				createProgram(db, w, r, userID)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		id, err := strconv.ParseInt(rest, 10, 64)
+		if err != nil || id <= 0 {
+			badRequest(w, "invalid program id")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			p, err := fetchProgram(r.Context(), db, userID, id)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if p == nil {
+				notFound(w)
+				return
+			}
+			jsonWrite(w, http.StatusOK, p)
+		case http.MethodDelete:
+			deleteProgram(db, w, r, userID, id)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func createProgram(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	var in programIn
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		badRequest(w, "invalid json")
+		return
+	}
+
+	obj := strings.TrimSpace(strings.ToLower(in.Objetivo))
+	niv := strings.TrimSpace(strings.ToLower(in.Nivel))
+	div := strings.TrimSpace(strings.ToLower(in.Divisao))
+	model := strings.TrimSpace(strings.ToLower(in.Model))
+	if obj == "" {
+		obj = "hipertrofia"
+	}
+	if niv == "" {
+		niv = "iniciante"
+	}
+	if div == "" {
+		div = "fullbody"
+	}
+	if model == "" {
+		model = modelLinear
+	}
+	if model != modelLinear && model != modelDUP && model != modelBlock {
+		badRequest(w, "model must be linear, dup or block")
+		return
+	}
+	if in.Weeks < 4 || in.Weeks > 16 {
+		badRequest(w, "weeks must be between 4 and 16")
+		return
+	}
+	days := in.DaysPerWeek
+	if days == 0 {
+		days = 3
+	}
+	if days < 1 || days > 7 {
+		badRequest(w, "days_per_week must be between 1 and 7")
+		return
+	}
+	deloadEvery := 4
+	if in.DeloadEvery != nil {
+		deloadEvery = *in.DeloadEvery
+	}
+	if deloadEvery != 0 && (deloadEvery < 3 || deloadEvery > 8) {
+		badRequest(w, "deload_every must be 0 or between 3 and 8")
+		return
+	}
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	if s := strings.TrimSpace(in.StartDate); s != "" {
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			badRequest(w, "start_date must be YYYY-MM-DD")
+			return
+		}
+		start = t
+	}
+
+	env, err := loadPlanEnv(r.Context(), db, userID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	rules := env.rules
+
+	params, err := periodize(model, in.Weeks, days, deloadEvery, bandForGoal(rules.goalKey(obj)))
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+
+	// seleção de exercícios é a mesma por divisão; a semana só muda séries/reps
+	seq := rules.sequenceFor(div)
+	plans := map[string][]GeneratedExercise{}
+	for _, d := range seq {
+		if _, ok := plans[d]; ok {
+			continue
+		}
+		exs, _, err := buildPlanV11(r.Context(), db, env, GenerateReq{Objetivo: obj, Nivel: niv, Divisao: d, Dias: days})
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if len(exs) == 0 {
+			http.Error(w, "catálogo vazio para gerar programa", http.StatusConflict)
+			return
+		}
+		plans[d] = exs
+	}
+	baseSets := rules.seriesFor(obj, niv, 0)
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var programID int64
+	err = tx.QueryRowContext(r.Context(), `
+		INSERT INTO programs (user_id, name, model, objetivo, nivel, divisao, weeks, days_per_week, deload_every, start_date, rule_version)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		RETURNING id
+	`, userID, nullIfEmpty(in.Name), model, obj, niv, div, in.Weeks, days, deloadEvery, start, rules.Version).Scan(&programID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+
+	offsets := dayOffsets(days)
+	lang := requestLang(r)
+	for _, week := range params {
+		for _, p := range week {
+			dayDiv := seq[(p.Day-1)%len(seq)]
+			base := plans[dayDiv]
+			plan := make([]GeneratedExercise, len(base))
+			for i, ex := range base {
+				ex.Series = scaleSets(ex.Series, p.VolumeFactor)
+				ex.Repeticoes = p.RepTarget
+				plan[i] = ex
+			}
+
+			req := GenerateReq{Objetivo: obj, Nivel: niv, Divisao: dayDiv, Dias: days}
+			key := fmt.Sprintf("prog-%d-w%02dd%d", programID, p.Week, p.Day)
+			note := i18n.T(lang, "program.note", p.Week, in.Weeks, p.Day, p.Phase, p.IntensityPct*100, p.RepTarget)
+			if p.Deload {
+				note += " " + i18n.T(lang, "program.note_deload")
+			}
+			treinoID, err := insertTreinoTx(r.Context(), tx, key, req, rules.Version, note, plan)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+
+			date := start.AddDate(0, 0, (p.Week-1)*7+offsets[p.Day-1])
+			_, err = tx.ExecContext(r.Context(), `
+				INSERT INTO program_workouts
+				  (program_id, week, day, scheduled_date, divisao, treino_id, phase, deload,
+				   intensity_pct, volume_factor, rep_target, sets_target)
+				VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+			`, programID, p.Week, p.Day, date, dayDiv, treinoID, p.Phase, p.Deload,
+				round2(p.IntensityPct*100), p.VolumeFactor, p.RepTarget, scaleSets(baseSets, p.VolumeFactor))
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		internalErr(w, err)
+		return
+	}
+
+	p, err := fetchProgram(r.Context(), db, userID, programID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusCreated, p)
+}
+
+func listPrograms(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string) {
+	rows, err := db.QueryContext(r.Context(), `
+		SELECT id, name, model, objetivo, nivel, divisao, weeks, days_per_week, deload_every,
+		       to_char(start_date, 'YYYY-MM-DD'), rule_version, created_at
+		FROM programs
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 100
+	`, userID)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	defer rows.Close()
+
+	items := []Program{}
+	for rows.Next() {
+		var p Program
+		if err := rows.Scan(&p.ID, &p.Name, &p.Model, &p.Objetivo, &p.Nivel, &p.Divisao, &p.Weeks,
+			&p.DaysPerWeek, &p.DeloadEvery, &p.StartDate, &p.RuleVersion, &p.CreatedAt); err != nil {
+			internalErr(w, err)
+			return
+		}
+		items = append(items, p)
+	}
+	if err := rows.Err(); err != nil {
+		internalErr(w, err)
+		return
+	}
+	jsonWrite(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func fetchProgram(ctx context.Context, db *sql.DB, userID string, id int64) (*Program, error) {
+	var p Program
+	err := db.QueryRowContext(ctx, `
+		SELECT id, name, model, objetivo, nivel, divisao, weeks, days_per_week, deload_every,
+		       to_char(start_date, 'YYYY-MM-DD'), rule_version, created_at
+		FROM programs
+		WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&p.ID, &p.Name, &p.Model, &p.Objetivo, &p.Nivel, &p.Divisao, &p.Weeks,
+		&p.DaysPerWeek, &p.DeloadEvery, &p.StartDate, &p.RuleVersion, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, week, day, to_char(scheduled_date, 'YYYY-MM-DD'), divisao, treino_id, phase, deload,
+		       intensity_pct::float8, volume_factor::float8, rep_target, sets_target
+		FROM program_workouts
+		WHERE program_id = $1
+		ORDER BY week, day
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	p.Workouts = []ProgramWorkout{}
+	for rows.Next() {
+		var pw ProgramWorkout
+		if err := rows.Scan(&pw.ID, &pw.Week, &pw.Day, &pw.ScheduledDate, &pw.Divisao, &pw.TreinoID, &pw.Phase,
+			&pw.Deload, &pw.IntensityPct, &pw.VolumeFactor, &pw.RepTarget, &pw.SetsTarget); err != nil {
+			return nil, err
+		}
+		p.Workouts = append(p.Workouts, pw)
+	}
+	return &p, rows.Err()
+}
+
+// remove o programa e os treinos gerados que ainda não foram usados em sessões
+func deleteProgram(db *sql.DB, w http.ResponseWriter, r *http.Request, userID string, id int64) {
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		internalErr(w, err)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists bool
+	if err := tx.QueryRowContext(r.Context(),
+		`SELECT EXISTS(SELECT 1 FROM programs WHERE id = $1 AND user_id = $2)`, id, userID).Scan(&exists); err != nil {
+		internalErr(w, err)
+		return
+	}
+	if !exists {
+		notFound(w)
+		return
+	}
+
+	if _, err := tx.ExecContext(r.Context(), `
+		DELETE FROM treinos t
+		USING program_workouts pw
+		WHERE pw.program_id = $1
+		  AND t.id = pw.treino_id
+		  AND NOT EXISTS (SELECT 1 FROM workout_sessions s WHERE s.treino_id = t.id)
+	`, id); err != nil {
+		internalErr(w, err)
+		return
+	}
+	if _, err := tx.ExecContext(r.Context(), `DELETE FROM programs WHERE id = $1`, id); err != nil {
+		internalErr(w, err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		internalErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -7,7 +7,8 @@ import (
 	"time"
 )
 
-// RateLimit aplica token-bucket por "chave" (X-User-ID ou IP).
+// RateLimit aplica token-bucket por "chave" (usuário autenticado ou IP).
+// X-User-ID não serve de chave: qualquer cliente escolhe o valor e fugiria do limite.
 // maxPerMin = número de requisições por minuto concedidas.
 func RateLimit(maxPerMin int) func(http.Handler) http.Handler {
 	if maxPerMin <= 0 {
@@ -40,9 +41,9 @@ func RateLimit(maxPerMin int) func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			key := r.Header.Get("X-User-ID")
-			if key == "" {
-				key = clientIP(r) // helper já existe em overload.go; ou duplique aqui se preferir.
+			key := clientIP(r)
+			if AuthKind(r) != "" {
+				key = "user:" + GetUserID(r)
 			}
 			if key == "" {
 				key = "anon"
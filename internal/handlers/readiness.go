@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"anima/internal/i18n"
+)
+
+// ====== Prontidão diária (readiness)
+//
+// Check-in por usuário/dia (sono, dor muscular, estresse, energia, FC de repouso).
+// O score bruto (0–100) é comparado com a linha de base do próprio usuário
+// (28 dias) e vira um fator de carga/volume aplicado no prefill e no overload.
+
+const (
+	readinessBaselineDays = 28
+	readinessMinBaseline  = 7 // abaixo disso usa linha de base populacional
+	readinessPopMean      = 70.0
+	readinessPopSD        = 10.0
+)
+
+type readinessCheckin struct {
+	Day        string  `json:"date"`
+	SleepHours float64 `json:"sleep_hours"`
+	Soreness   int     `json:"soreness"` // 1 (nenhuma) .. 5 (muita)
+	Stress     int     `json:"stress"`   // 1 (baixo) .. 5 (alto)
+	Energy     int     `json:"energy"`   // 1 (baixa) .. 5 (alta)
+	RestingHR  *int    `json:"resting_hr,omitempty"`
+	Notes      *string `json:"notes,omitempty"`
+}
+
+type readiness struct {
+	Day          string           `json:"date"`
+	Score        float64          `json:"score"` // 0–100
+	BaselineMean float64          `json:"baseline_mean"`
+	BaselineDays int              `json:"baseline_days"`
+	Z            float64          `json:"z"`
+	Band         string           `json:"band"` // low | below | normal | high
+	LoadFactor   float64          `json:"load_factor"`
+	VolumeFactor float64          `json:"volume_factor"`
+	Checkin      readinessCheckin `json:"checkin"`
+}
+
+// readinessRawScore: média ponderada dos componentes (0–100), com penalidade
+// de FC de repouso acima da média do usuário (-5 pts a cada +5%).
+func readinessRawScore(c readinessCheckin, baselineHR float64) float64 {
+	sleep := math.Min(c.SleepHours/8.0, 1.1)
+	sore := float64(5-c.Soreness) / 4
+	stress := float64(5-c.Stress) / 4
+	energy := float64(c.Energy-1) / 4
+
+	score := (0.30*sleep + 0.25*sore + 0.15*stress + 0.30*energy) * 100
+	if c.RestingHR != nil && baselineHR > 0 {
+		delta := (float64(*c.RestingHR) - baselineHR) / baselineHR
+		if delta > 0 {
+			score -= delta * 100
+		}
+	}
+	return math.Max(0, math.Min(100, score))
+}
+
+// readinessAdjust compara o score com a linha de base e define os fatores.
+func readinessAdjust(score float64, hist []float64) (base, z float64, band string, load, volume float64) {
+	base, sd := readinessPopMean, readinessPopSD
+	if len(hist) >= readinessMinBaseline {
+		base = mean(hist)
+		sd = math.Max(stddev(hist, base), 5)
+	}
+	z = (score - base) / sd
+
+	switch {
+	case z <= -1.5:
+		return base, z, "low", 0.90, 0.80
+	case z <= -0.75:
+		return base, z, "below", 0.95, 0.90
+	case z >= 1.0:
+		return base, z, "high", 1.025, 1.0
+	default:
+		return base, z, "normal", 1.0, 1.0
+	}
+}
+
+func stddev(v []float64, m float64) float64 {
+	if len(v) < 2 {
+		return 0
+	}
+	s := 0.0
+	for _, x := range v {
+		s += (x - m) * (x - m)
+	}
+	return math.Sqrt(s / float64(len(v)-1))
+}
+
+// loadReadiness: prontidão do dia (nil se não houve check-in).
+func loadReadiness(ctx context.Context, db *sql.DB, uid, day string) (*readiness, error) {
+	if uid == "" {
+		return nil, nil
+	}
+	var c readinessCheckin
+	err := db.QueryRowContext(ctx, `
+		SELECT to_char(day, 'YYYY-MM-DD'), sleep_hours::float8, soreness, stress, energy, resting_hr, notes
+		FROM readiness_checkins
+		WHERE user_id = $1 AND day = $2::date
+	`, uid, day).Scan(&c.Day, &c.SleepHours, &c.Soreness, &c.Stress, &c.Energy, &c.RestingHR, &c.Notes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// linha de base: check-ins dos 28 dias anteriores
+	rows, err := db.QueryContext(ctx, `
+		SELECT sleep_hours::float8, soreness, stress, energy, resting_hr
+		FROM readiness_checkins
+		WHERE user_id = $1
+		  AND day <  $2::date
+		  AND day >= $2::date - $3::int
+	`, uid, day, readinessBaselineDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hist []readinessCheckin
+	var hrs []float64
+	for rows.Next() {
+		var h readinessCheckin
+		if err := rows.Scan(&h.SleepHours, &h.Soreness, &h.Stress, &h.Energy, &h.RestingHR); err != nil {
+			return nil, err
+		}
+		hist = append(hist, h)
+		if h.RestingHR != nil {
+			hrs = append(hrs, float64(*h.RestingHR))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	baseHR := 0.0
+	if len(hrs) >= readinessMinBaseline {
+		baseHR = mean(hrs)
+	}
+	scores := make([]float64, 0, len(hist))
+	for _, h := range hist {
+		scores = append(scores, readinessRawScore(h, baseHR))
+	}
+
+	score := readinessRawScore(c, baseHR)
+	m, z, band, lf, vf := readinessAdjust(score, scores)
+	return &readiness{
+		Day:          c.Day,
+		Score:        math.Round(score),
+		BaselineMean: math.Round(m),
+		BaselineDays: len(scores),
+		Z:            round2(z),
+		Band:         band,
+		LoadFactor:   lf,
+		VolumeFactor: vf,
+		Checkin:      c,
+	}, nil
+}
+
+func readinessRationale(l i18n.Lang, rd *readiness) string {
+	return i18n.T(l, "readiness.adjust",
+		i18n.Label(l, "readiness.band", rd.Band), rd.Score, rd.BaselineMean, rd.LoadFactor, rd.VolumeFactor)
+}
+
+// MeReadiness
+// GET  /api/me/readiness?date=YYYY-MM-DD  (default hoje)
+// POST /api/me/readiness  {sleep_hours, soreness, stress, energy, resting_hr?, notes?, date?} (upsert do dia)
+// "hoje" é a data local do cliente (date ou X-Timezone); UTC sem nenhum dos dois.
+func MeReadiness(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			day, err := requestDay(r)
+			if err != nil {
+				badRequest(w, err.Error())
+				return
+			}
+			rd, err := loadReadiness(r.Context(), db, userID, day)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if rd == nil {
+				notFound(w)
+				return
+			}
+			jsonWrite(w, http.StatusOK, rd)
+
+		case http.MethodPost:
+			var in readinessCheckin
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				badRequest(w, "invalid json")
+				return
+			}
+			now := time.Now()
+			day := localDay(now, r.Header.Get("X-Timezone"))
+			if v := strings.TrimSpace(in.Day); v != "" {
+				if _, err := time.Parse("2006-01-02", v); err != nil {
+					badRequest(w, "date must be YYYY-MM-DD")
+					return
+				}
+				// fusos à frente de UTC já estão no dia seguinte
+				if v > latestLocalDay(now) {
+					badRequest(w, "date cannot be in the future")
+					return
+				}
+				day = v
+			}
+			if in.SleepHours < 0 || in.SleepHours > 16 {
+				badRequest(w, "sleep_hours must be between 0 and 16")
+				return
+			}
+			for name, v := range map[string]int{"soreness": in.Soreness, "stress": in.Stress, "energy": in.Energy} {
+				if v < 1 || v > 5 {
+					badRequest(w, name+" must be between 1 and 5")
+					return
+				}
+			}
+			if in.RestingHR != nil && (*in.RestingHR < 25 || *in.RestingHR > 150) {
+				badRequest(w, "resting_hr must be between 25 and 150")
+				return
+			}
+
+			_, err := db.ExecContext(r.Context(), `
+				INSERT INTO readiness_checkins (user_id, day, sleep_hours, soreness, stress, energy, resting_hr, notes)
+				VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8)
+				ON CONFLICT (user_id, day) DO UPDATE SET
+				  sleep_hours = EXCLUDED.sleep_hours,
+				  soreness    = EXCLUDED.soreness,
+				  stress      = EXCLUDED.stress,
+				  energy      = EXCLUDED.energy,
+				  resting_hr  = EXCLUDED.resting_hr,
+				  notes       = EXCLUDED.notes,
+				  updated_at  = NOW()
+			`, userID, day, in.SleepHours, in.Soreness, in.Stress, in.Energy, in.RestingHR, in.Notes)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			rd, err := loadReadiness(r.Context(), db, userID, day)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, rd)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
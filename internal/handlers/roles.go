@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Papéis: athlete (todo usuário, implícito), coach e admin (em user_roles).
+// Vão no JWT ("roles") na emissão; RequireRole confere o token e, como o
+// token pode ter até ACCESS_TOKEN_TTL_MIN de idade, confirma no banco para
+// que uma revogação valha na hora. admin satisfaz qualquer papel.
+// Papéis em MFA_REQUIRED_ROLES exigem sessão com segundo fator (mfa.go).
+
+const (
+	RoleAthlete = "athlete"
+	RoleCoach   = "coach"
+	RoleAdmin   = "admin"
+)
+
+const ctxKeyRoles ctxKey = "roles"
+
+var grantableRoles = map[string]bool{RoleCoach: true, RoleAdmin: true}
+
+// loadUserRoles: athlete + papéis gravados, em ordem estável.
+func loadUserRoles(ctx context.Context, db *sql.DB, userID string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	roles := []string{RoleAthlete}
+	for rows.Next() {
+		var r string
+		if err := rows.Scan(&r); err != nil {
+			return nil, err
+		}
+		if r != RoleAthlete {
+			roles = append(roles, r)
+		}
+	}
+	return roles, rows.Err()
+}
+
+func hasRole(roles []string, want string) bool {
+	for _, r := range roles {
+		if r == want || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRoles: papéis do access token da requisição.
+func GetRoles(r *http.Request) []string {
+	roles, _ := r.Context().Value(ctxKeyRoles).([]string)
+	return roles
+}
+
+// RequireRole exige JWT válido com o papel (401 sem token, 403 sem papel).
+func RequireRole(db *sql.DB, role string, next http.Handler) http.Handler {
+	return RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// token sem o papel: recusa sem ir ao banco, salvo quando o papel pode
+		// estar retido por falta de segundo fator (resposta mais útil abaixo)
+		jwtLacks := AuthKind(r) == "jwt" && !hasRole(GetRoles(r), role)
+		if jwtLacks && (mfaVerified(r) || !mfaRequiredRoles()[role]) {
+			jsonWrite(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		current, err := loadUserRoles(r.Context(), db, GetUserID(r))
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		// papéis em MFA_REQUIRED_ROLES só valem em sessão com segundo fator
+		current, withheld := effectiveRoles(current, mfaVerified(r))
+		if !hasRole(current, role) && hasRole(withheld, role) {
+			jsonWrite(w, http.StatusForbidden, map[string]string{"error": "two-factor authentication required"})
+			return
+		}
+		// PAT não carrega papéis (o escopo já foi checado em EnforceScopes); vale o banco
+		if jwtLacks || !hasRole(current, role) {
+			jsonWrite(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// grantRole grava o papel e a auditoria; false se o usuário já o tinha.
+func grantRole(ctx context.Context, db *sql.DB, userID, role, actor, reason string) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role, granted_by)
+		VALUES ($1, $2, NULLIF($3, '')::uuid)
+		ON CONFLICT (user_id, role) DO NOTHING`, userID, role, actor)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO role_audit (user_id, role, action, actor_user_id, reason)
+		VALUES ($1, $2, 'grant', NULLIF($3, '')::uuid, NULLIF($4, ''))`, userID, role, actor, reason); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+var errLastAdmin = errors.New("cannot revoke the last admin")
+
+// revokeRole remove o papel (nunca o último admin) e audita; false se não tinha.
+func revokeRole(ctx context.Context, db *sql.DB, userID, role, actor, reason string) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if role == RoleAdmin {
+		// serializa revogações de admin concorrentes
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE user_roles IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return false, err
+		}
+		var admins int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_roles WHERE role = 'admin'`).Scan(&admins); err != nil {
+			return false, err
+		}
+		var held bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM user_roles WHERE user_id = $1 AND role = 'admin')`, userID).Scan(&held); err != nil {
+			return false, err
+		}
+		if held && admins <= 1 {
+			return false, errLastAdmin
+		}
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`, userID, role)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO role_audit (user_id, role, action, actor_user_id, reason)
+		VALUES ($1, $2, 'revoke', NULLIF($3, '')::uuid, NULLIF($4, ''))`, userID, role, actor, reason); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// BootstrapAdmin garante o papel admin para o e-mail dado (BOOTSTRAP_ADMIN_EMAIL).
+func BootstrapAdmin(ctx context.Context, db *sql.DB, email string) error {
+	var uid string
+	err := db.QueryRowContext(ctx, `SELECT id::text FROM users WHERE email = $1`, strings.ToLower(strings.TrimSpace(email))).Scan(&uid)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[roles] bootstrap: usuário %s não existe (ainda)", email)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	granted, err := grantRole(ctx, db, uid, RoleAdmin, "", "bootstrap")
+	if granted {
+		log.Printf("[roles] bootstrap: %s agora é admin", email)
+	}
+	return err
+}
+
+// AdminUserRoles:
+//
+//	GET    /api/admin/users/{id}/roles
+//	POST   /api/admin/users/{id}/roles          {"role":"coach","reason":"..."}
+//	DELETE /api/admin/users/{id}/roles/{role}?reason=...
+func AdminUserRoles(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// /api/admin/users/{id}/roles[/{role}]
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/"), "/")
+		if len(parts) < 2 || len(parts) > 3 || parts[1] != "roles" || parts[0] == "" {
+			notFound(w)
+			return
+		}
+		uid := parts[0]
+		var exists bool
+		if err := db.QueryRowContext(r.Context(), `SELECT EXISTS (SELECT 1 FROM users WHERE id::text = $1)`, uid).Scan(&exists); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if !exists {
+			notFound(w)
+			return
+		}
+		actor := GetUserID(r)
+
+		switch {
+		case r.Method == http.MethodGet && len(parts) == 2:
+			roles, err := loadUserRoles(r.Context(), db, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, map[string]any{"user_id": uid, "roles": roles})
+
+		case r.Method == http.MethodPost && len(parts) == 2:
+			var in struct {
+				Role   string `json:"role"`
+				Reason string `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				badRequest(w, "invalid json")
+				return
+			}
+			role := strings.ToLower(strings.TrimSpace(in.Role))
+			if !grantableRoles[role] {
+				badRequest(w, "role must be coach or admin")
+				return
+			}
+			granted, err := grantRole(r.Context(), db, uid, role, actor, strings.TrimSpace(in.Reason))
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			roles, err := loadUserRoles(r.Context(), db, uid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			code := http.StatusOK
+			if granted {
+				code = http.StatusCreated
+			}
+			jsonWrite(w, code, map[string]any{"user_id": uid, "roles": roles})
+
+		case r.Method == http.MethodDelete && len(parts) == 3:
+			role := strings.ToLower(parts[2])
+			if !grantableRoles[role] {
+				badRequest(w, "role must be coach or admin")
+				return
+			}
+			revoked, err := revokeRole(r.Context(), db, uid, role, actor, strings.TrimSpace(r.URL.Query().Get("reason")))
+			if errors.Is(err, errLastAdmin) {
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if !revoked {
+				notFound(w)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// AdminRoleAudit: GET /api/admin/roles/audit?user_id=...&limit=50
+func AdminRoleAudit(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		limit := clampInt(parseInt(r.URL.Query().Get("limit"), 50), 1, 500)
+		user := strings.TrimSpace(r.URL.Query().Get("user_id"))
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT id, user_id::text, role, action, COALESCE(actor_user_id::text, ''), COALESCE(reason, ''), created_at
+			FROM role_audit
+			WHERE ($1 = '' OR user_id::text = $1)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2`, user, limit)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer rows.Close()
+
+		type item struct {
+			ID        int64     `json:"id"`
+			UserID    string    `json:"user_id"`
+			Role      string    `json:"role"`
+			Action    string    `json:"action"`
+			ActorID   string    `json:"actor_user_id,omitempty"`
+			Reason    string    `json:"reason,omitempty"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+		items := []item{}
+		for rows.Next() {
+			var it item
+			if err := rows.Scan(&it.ID, &it.UserID, &it.Role, &it.Action, &it.ActorID, &it.Reason, &it.CreatedAt); err != nil {
+				internalErr(w, err)
+				return
+			}
+			items = append(items, it)
+		}
+		if err := rows.Err(); err != nil {
+			internalErr(w, err)
+			return
+		}
+		jsonWrite(w, http.StatusOK, map[string]any{"items": items})
+	})
+}
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ====== Regras de programação (versionadas)
+//
+// As decisões do gerador (divisões, grupos por dia, reps/descanso/séries por
+// objetivo e nível) vivem em generation_rule_sets (JSONB). A versão ativa é a
+// maior publicada; sem nenhuma publicada, vale o conjunto embutido (versão 0).
+
+type ruleSet struct {
+	Version          int                 `json:"version"`
+	TargetPerSession int                 `json:"target_per_session"`         // nº-alvo de exercícios por sessão
+	DefaultGoal      string              `json:"default_goal"`               // regra usada p/ objetivo desconhecido
+	GoalAliases      map[string]string   `json:"goal_aliases,omitempty"`     // "força" -> "forca"
+	DefaultDivision  string              `json:"default_division"`           // divisão p/ valor desconhecido
+	DivisionAliases  map[string]string   `json:"division_aliases,omitempty"` // "ppl" -> "push"
+	Divisions        map[string][]string `json:"divisions"`                  // divisão do dia -> grupos musculares
+	Sequences        map[string][]string `json:"sequences"`                  // divisão base -> sequência semanal
+	Goals            map[string]goalRule `json:"goals"`
+	Rest             restModifiers       `json:"rest"`
+}
+
+type goalRule struct {
+	Reps   string               `json:"reps"`
+	Rest   restRange            `json:"rest"`
+	Series seriesRule           `json:"series"`
+	Levels map[string]levelRule `json:"levels,omitempty"`
+}
+
+type restRange struct {
+	Base int `json:"base"`
+	Min  int `json:"min"`
+	Max  int `json:"max"`
+}
+
+// Alt substitui Base a cada AltEvery exercícios (posições 2, 4, ... quando AltEvery=2).
+type seriesRule struct {
+	Base     int `json:"base"`
+	Alt      int `json:"alt,omitempty"`
+	AltEvery int `json:"alt_every,omitempty"`
+}
+
+type levelRule struct {
+	Reps      string      `json:"reps,omitempty"`
+	Series    *seriesRule `json:"series,omitempty"`
+	RestCap   int         `json:"rest_cap,omitempty"`   // teto antes do clamp
+	RestBonus int         `json:"rest_bonus,omitempty"` // soma antes do clamp
+}
+
+type restModifiers struct {
+	CompoundBonus      int `json:"compound_bonus"`
+	IsolationDiscount  int `json:"isolation_discount"`
+	BodyweightDiscount int `json:"bodyweight_discount"`
+}
+
+// defaultRuleSet reproduz as heurísticas originais do gerador v1.1.
+func defaultRuleSet() *ruleSet {
+	upper := []string{"peito", "costas", "ombros", "biceps", "triceps", "core"}
+	lower := []string{"quadriceps", "posterior", "gluteos", "panturrilhas", "lombar", "core"}
+	noviceCap := map[string]levelRule{"iniciante": {RestCap: 90}}
+	return &ruleSet{
+		Version:          0,
+		TargetPerSession: 6,
+		DefaultGoal:      "geral",
+		GoalAliases: map[string]string{
+			"força":       "forca",
+			"resistência": "resistencia",
+		},
+		DefaultDivision: "fullbody",
+		DivisionAliases: map[string]string{
+			"upperlower": "upper",
+			"ppl":        "push",
+		},
+		Divisions: map[string][]string{
+			"fullbody": {"peito", "costas", "pernas", "ombros", "core", "biceps", "triceps"},
+			"upper":    upper,
+			"lower":    lower,
+			"push":     {"peito", "ombros", "triceps", "core", "quadriceps", "panturrilhas"},
+			"pull":     {"costas", "lombar", "biceps", "posterior", "core", "trapézio"},
+			"legs":     lower,
+		},
+		Sequences: map[string][]string{
+			"fullbody":   {"fullbody"},
+			"upperlower": {"upper", "lower"},
+			"upper":      {"upper"},
+			"lower":      {"lower"},
+			"ppl":        {"push", "pull", "legs"},
+			"push":       {"push"},
+			"pull":       {"pull"},
+			"legs":       {"legs"},
+		},
+		Goals: map[string]goalRule{
+			"geral": {
+				Reps: "8-12", Rest: restRange{Base: 75, Min: 45, Max: 105}, Series: seriesRule{Base: 3},
+			},
+			"hipertrofia": {
+				Reps: "8-12", Rest: restRange{Base: 75, Min: 60, Max: 105},
+				Series: seriesRule{Base: 3, Alt: 4, AltEvery: 2},
+				Levels: noviceCap,
+			},
+			"emagrecimento": {
+				Reps: "12-15", Rest: restRange{Base: 45, Min: 30, Max: 75}, Series: seriesRule{Base: 3},
+				Levels: noviceCap,
+			},
+			"resistencia": {
+				Reps: "12-20", Rest: restRange{Base: 45, Min: 30, Max: 75}, Series: seriesRule{Base: 3},
+				Levels: noviceCap,
+			},
+			"forca": {
+				Reps: "4-6", Rest: restRange{Base: 150, Min: 120, Max: 180}, Series: seriesRule{Base: 3},
+				Levels: map[string]levelRule{"avancado": {RestBonus: 15}},
+			},
+		},
+		Rest: restModifiers{CompoundBonus: 30, IsolationDiscount: 10, BodyweightDiscount: 10},
+	}
+}
+
+var repsPattern = regexp.MustCompile(`^\d{1,2}(-\d{1,2})?$`)
+
+func (rs *ruleSet) validate() error {
+	if rs.TargetPerSession < 1 || rs.TargetPerSession > 20 {
+		return errors.New("target_per_session must be between 1 and 20")
+	}
+	if len(rs.Divisions) == 0 {
+		return errors.New("divisions is required")
+	}
+	for name, groups := range rs.Divisions {
+		if len(groups) == 0 {
+			return fmt.Errorf("division %q has no muscle groups", name)
+		}
+	}
+	if _, ok := rs.Divisions[rs.DefaultDivision]; !ok {
+		return fmt.Errorf("default_division %q not in divisions", rs.DefaultDivision)
+	}
+	for alias, div := range rs.DivisionAliases {
+		if _, ok := rs.Divisions[div]; !ok {
+			return fmt.Errorf("division alias %q points to unknown division %q", alias, div)
+		}
+	}
+	for base, seq := range rs.Sequences {
+		if len(seq) == 0 {
+			return fmt.Errorf("sequence %q is empty", base)
+		}
+		for _, d := range seq {
+			if _, ok := rs.Divisions[d]; !ok {
+				return fmt.Errorf("sequence %q references unknown division %q", base, d)
+			}
+		}
+	}
+	if _, ok := rs.Goals[rs.DefaultGoal]; !ok {
+		return fmt.Errorf("default_goal %q not in goals", rs.DefaultGoal)
+	}
+	for alias, g := range rs.GoalAliases {
+		if _, ok := rs.Goals[g]; !ok {
+			return fmt.Errorf("goal alias %q points to unknown goal %q", alias, g)
+		}
+	}
+	for name, g := range rs.Goals {
+		if !repsPattern.MatchString(g.Reps) {
+			return fmt.Errorf("goal %q: invalid reps %q", name, g.Reps)
+		}
+		if g.Rest.Min <= 0 || g.Rest.Min > g.Rest.Max || g.Rest.Max > 600 {
+			return fmt.Errorf("goal %q: invalid rest range", name)
+		}
+		if err := g.Series.validate(); err != nil {
+			return fmt.Errorf("goal %q: %w", name, err)
+		}
+		for lvl, lr := range g.Levels {
+			if lr.Reps != "" && !repsPattern.MatchString(lr.Reps) {
+				return fmt.Errorf("goal %q level %q: invalid reps %q", name, lvl, lr.Reps)
+			}
+			if lr.Series != nil {
+				if err := lr.Series.validate(); err != nil {
+					return fmt.Errorf("goal %q level %q: %w", name, lvl, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s seriesRule) validate() error {
+	if s.Base < 1 || s.Base > 10 || s.Alt < 0 || s.Alt > 10 || s.AltEvery < 0 {
+		return errors.New("invalid series")
+	}
+	return nil
+}
+
+// goalKey normaliza o objetivo (aliases, fallback p/ default_goal).
+func (rs *ruleSet) goalKey(goal string) string {
+	g := strings.ToLower(strings.TrimSpace(goal))
+	if a, ok := rs.GoalAliases[g]; ok {
+		g = a
+	}
+	if _, ok := rs.Goals[g]; ok {
+		return g
+	}
+	return rs.DefaultGoal
+}
+
+func (rs *ruleSet) goal(goal string) goalRule {
+	return rs.Goals[rs.goalKey(goal)]
+}
+
+func (rs *ruleSet) level(goal, nivel string) levelRule {
+	return rs.goal(goal).Levels[strings.ToLower(strings.TrimSpace(nivel))]
+}
+
+func (rs *ruleSet) repsFor(goal, nivel string) string {
+	if lr := rs.level(goal, nivel); lr.Reps != "" {
+		return lr.Reps
+	}
+	return rs.goal(goal).Reps
+}
+
+// seriesFor: i é a posição (0-based) do exercício na sessão.
+func (rs *ruleSet) seriesFor(goal, nivel string, i int) int {
+	s := rs.goal(goal).Series
+	if lr := rs.level(goal, nivel); lr.Series != nil {
+		s = *lr.Series
+	}
+	if s.Alt > 0 && s.AltEvery > 0 && i%s.AltEvery == s.AltEvery-1 {
+		return s.Alt
+	}
+	return s.Base
+}
+
+// restTrace registra cada etapa do cálculo de descanso (usado pelo explain).
+type restTrace struct {
+	Goal               string `json:"goal"`
+	Base               int    `json:"base"`
+	Compound           bool   `json:"compound"`
+	CompoundBonus      int    `json:"compound_bonus,omitempty"`
+	IsolationDiscount  int    `json:"isolation_discount,omitempty"`
+	BodyweightDiscount int    `json:"bodyweight_discount,omitempty"`
+	LevelCap           int    `json:"level_cap,omitempty"`
+	LevelBonus         int    `json:"level_bonus,omitempty"`
+	Min                int    `json:"min"`
+	Max                int    `json:"max"`
+	Clamped            bool   `json:"clamped"`
+	Final              int    `json:"final"`
+}
+
+// restFor: base por objetivo, ajusta por composto/isolado, peso corporal e nível; clamp no range.
+func (rs *ruleSet) restFor(goal, nivel string, ex exRow) int {
+	return rs.traceRest(goal, nivel, ex).Final
+}
+
+func (rs *ruleSet) traceRest(goal, nivel string, ex exRow) restTrace {
+	g := rs.goal(goal)
+	t := restTrace{Goal: rs.goalKey(goal), Base: g.Rest.Base, Min: g.Rest.Min, Max: g.Rest.Max}
+	v := g.Rest.Base
+
+	if isCompound(ex.name, ex.muscleGroup) {
+		t.Compound = true
+		t.CompoundBonus = rs.Rest.CompoundBonus
+		v += rs.Rest.CompoundBonus
+	} else {
+		t.IsolationDiscount = rs.Rest.IsolationDiscount
+		v -= rs.Rest.IsolationDiscount
+	}
+	if ex.isBodyweight {
+		t.BodyweightDiscount = rs.Rest.BodyweightDiscount
+		v -= rs.Rest.BodyweightDiscount
+	}
+
+	lr := rs.level(goal, nivel)
+	if lr.RestCap > 0 && v > lr.RestCap {
+		t.LevelCap = lr.RestCap
+		v = lr.RestCap
+	}
+	if lr.RestBonus != 0 {
+		t.LevelBonus = lr.RestBonus
+		v += lr.RestBonus
+	}
+
+	t.Final = clampRest(v, g.Rest.Min, g.Rest.Max)
+	t.Clamped = t.Final != v
+	return t
+}
+
+// seriesSource/repsSource descrevem de onde veio o valor (explain).
+func (rs *ruleSet) seriesSource(goal, nivel string, i int) string {
+	src := "goal:" + rs.goalKey(goal)
+	s := rs.goal(goal).Series
+	if lr := rs.level(goal, nivel); lr.Series != nil {
+		s = *lr.Series
+		src += " level:" + strings.ToLower(strings.TrimSpace(nivel))
+	}
+	if s.Alt > 0 && s.AltEvery > 0 && i%s.AltEvery == s.AltEvery-1 {
+		return src + fmt.Sprintf(" alt (a cada %d)", s.AltEvery)
+	}
+	return src + " base"
+}
+
+func (rs *ruleSet) repsSource(goal, nivel string) string {
+	src := "goal:" + rs.goalKey(goal)
+	if lr := rs.level(goal, nivel); lr.Reps != "" {
+		src += " level:" + strings.ToLower(strings.TrimSpace(nivel))
+	}
+	return src
+}
+
+func (rs *ruleSet) divisionKey(div string) string {
+	d := strings.ToLower(strings.TrimSpace(div))
+	if a, ok := rs.DivisionAliases[d]; ok {
+		d = a
+	}
+	if _, ok := rs.Divisions[d]; ok {
+		return d
+	}
+	return rs.DefaultDivision
+}
+
+func (rs *ruleSet) groupsFor(div string) []string {
+	return rs.Divisions[rs.divisionKey(div)]
+}
+
+// sequenceFor mapeia a divisão base para a sequência de dias.
+func (rs *ruleSet) sequenceFor(base string) []string {
+	b := strings.ToLower(strings.TrimSpace(base))
+	if seq, ok := rs.Sequences[b]; ok && len(seq) > 0 {
+		return seq
+	}
+	if _, ok := rs.Divisions[b]; ok {
+		return []string{b}
+	}
+	return []string{rs.DefaultDivision}
+}
+
+// ====== Carga (cache em memória, invalidado ao publicar)
+
+var rulesCache struct {
+	mu       sync.Mutex
+	rs       *ruleSet
+	loadedAt time.Time
+}
+
+func rulesCacheTTL() time.Duration {
+	return time.Duration(atoiEnvInt("RULES_CACHE_SEC", 60)) * time.Second
+}
+
+func invalidateRulesCache() {
+	rulesCache.mu.Lock()
+	rulesCache.rs = nil
+	rulesCache.mu.Unlock()
+}
+
+// loadActiveRules retorna a versão publicada mais recente (ou a embutida).
+func loadActiveRules(ctx context.Context, db *sql.DB) (*ruleSet, error) {
+	rulesCache.mu.Lock()
+	defer rulesCache.mu.Unlock()
+	if rulesCache.rs != nil && time.Since(rulesCache.loadedAt) < rulesCacheTTL() {
+		return rulesCache.rs, nil
+	}
+
+	rs, err := loadRuleVersion(ctx, db, 0)
+	if err != nil {
+		return nil, err
+	}
+	rulesCache.rs = rs
+	rulesCache.loadedAt = time.Now()
+	return rs, nil
+}
+
+// loadRuleVersion: version <= 0 => mais recente; sem linhas => conjunto embutido.
+func loadRuleVersion(ctx context.Context, db *sql.DB, version int) (*ruleSet, error) {
+	var (
+		v   int
+		raw []byte
+	)
+	q := `SELECT version, rules FROM generation_rule_sets ORDER BY version DESC LIMIT 1`
+	args := []any{}
+	if version > 0 {
+		q = `SELECT version, rules FROM generation_rule_sets WHERE version = $1`
+		args = append(args, version)
+	}
+	err := db.QueryRowContext(ctx, q, args...).Scan(&v, &raw)
+	if err == sql.ErrNoRows {
+		if version > 0 {
+			return nil, sql.ErrNoRows
+		}
+		return defaultRuleSet(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rs ruleSet
+	if err := json.Unmarshal(raw, &rs); err != nil {
+		return nil, fmt.Errorf("rule set v%d: %w", v, err)
+	}
+	rs.Version = v
+	return &rs, nil
+}
+
+// ====== Admin
+
+type ruleVersionInfo struct {
+	Version     int       `json:"version"`
+	Notes       *string   `json:"notes,omitempty"`
+	PublishedBy *string   `json:"published_by,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+type rulePublishReq struct {
+	Rules *ruleSet `json:"rules"`
+	Notes string   `json:"notes,omitempty"`
+}
+
+// AdminRules publica e consulta versões das regras do gerador.
+// Auth: papel admin (RequireRole em main.go).
+// GET  /api/admin/rules            -> ativa + histórico
+// GET  /api/admin/rules?version=3  -> versão específica
+// POST /api/admin/rules            {"rules":{...},"notes":"..."} -> publica nova versão
+func AdminRules(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if v := parseInt(r.URL.Query().Get("version"), 0); v > 0 {
+				rs, err := loadRuleVersion(r.Context(), db, v)
+				if err == sql.ErrNoRows {
+					notFound(w)
+					return
+				}
+				if err != nil {
+					internalErr(w, err)
+					return
+				}
+				jsonWrite(w, http.StatusOK, rs)
+				return
+			}
+
+			active, err := loadRuleVersion(r.Context(), db, 0)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			rows, err := db.QueryContext(r.Context(), `
+				SELECT version, notes, published_by, published_at
+				FROM generation_rule_sets
+				ORDER BY version DESC
+				LIMIT 50
+			`)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			defer rows.Close()
+			versions := []ruleVersionInfo{}
+			for rows.Next() {
+				var it ruleVersionInfo
+				if err := rows.Scan(&it.Version, &it.Notes, &it.PublishedBy, &it.PublishedAt); err != nil {
+					internalErr(w, err)
+					return
+				}
+				versions = append(versions, it)
+			}
+			if err := rows.Err(); err != nil {
+				internalErr(w, err)
+				return
+			}
+			jsonWrite(w, http.StatusOK, map[string]any{
+				"active":   active,
+				"versions": versions,
+			})
+
+		case http.MethodPost:
+			var in rulePublishReq
+			if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+				badRequest(w, "invalid json")
+				return
+			}
+			if in.Rules == nil {
+				badRequest(w, "rules is required")
+				return
+			}
+			if err := in.Rules.validate(); err != nil {
+				badRequest(w, err.Error())
+				return
+			}
+			in.Rules.Version = 0 // definido pelo banco
+			raw, err := json.Marshal(in.Rules)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+
+			var info ruleVersionInfo
+			err = db.QueryRowContext(r.Context(), `
+				INSERT INTO generation_rule_sets (version, rules, notes, published_by)
+				SELECT COALESCE(MAX(version), 0) + 1, $1::jsonb, $2, $3
+				FROM generation_rule_sets
+				RETURNING version, notes, published_by, published_at
+			`, raw, nullIfEmpty(in.Notes), nullIfEmpty(GetUserID(r))).
+				Scan(&info.Version, &info.Notes, &info.PublishedBy, &info.PublishedAt)
+			if err != nil {
+				if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+					http.Error(w, "concurrent publish, retry", http.StatusConflict)
+					return
+				}
+				internalErr(w, err)
+				return
+			}
+			invalidateRulesCache()
+			jsonWrite(w, http.StatusCreated, info)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
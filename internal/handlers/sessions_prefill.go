@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"anima/internal/i18n"
+)
+
+type prefillItem struct {
+	ExercicioID    int64    `json:"exercicio_id"`
+	Nome           string   `json:"nome"`
+	Series         int      `json:"series"`
+	Repeticoes     string   `json:"repeticoes"`
+	TargetWeightKg *float64 `json:"target_weight_kg,omitempty"` // sem histórico => ausente
+	E1RMKg         *float64 `json:"e1rm_kg,omitempty"`
+	TargetRIR      *int     `json:"target_rir,omitempty"`
+	TopSetRPE      *float64 `json:"top_set_rpe,omitempty"`
+	BackoffPct     *float64 `json:"backoff_pct,omitempty"`
+	BackoffSets    *int     `json:"backoff_sets,omitempty"`
+}
+
+type prefillResp struct {
+	Date             string        `json:"date,omitempty"`
+	TreinoID         int64         `json:"treino_id"`
+	ProgramID        *int64        `json:"program_id,omitempty"`
+	ProgramWorkoutID *int64        `json:"program_workout_id,omitempty"`
+	Week             *int          `json:"week,omitempty"`
+	Day              *int          `json:"day,omitempty"`
+	Phase            string        `json:"phase,omitempty"`
+	Deload           bool          `json:"deload"`
+	Readiness        *readiness    `json:"readiness,omitempty"`
+	IntensityPct     *float64      `json:"intensity_pct,omitempty"`
+	Rationale        []string      `json:"rationale"`
+	Items            []prefillItem `json:"items"`
+}
+
+// dia planejado (programa) para preencher a sessão
+type plannedWorkout struct {
+	id           int64
+	programID    int64
+	week, day    int
+	date         string
+	treinoID     int64
+	phase        string
+	deload       bool
+	intensityPct float64
+}
+
+// SessionsPrefill: séries/reps/carga-alvo para a sessão de hoje.
+// GET /api/sessions/prefill?date=YYYY-MM-DD  (treino do programa agendado na data; default hoje)
+// GET /api/sessions/prefill?treino_id=123    (treino avulso ou de programa)
+// "hoje", deload e prontidão seguem a data local (?date ou X-Timezone, ver userday.go).
+func SessionsPrefill(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID := strings.TrimSpace(GetUserID(r))
+		if userID == "" {
+			http.Error(w, "unauthorized (missing user id)", http.StatusUnauthorized)
+			return
+		}
+
+		var (
+			pw  *plannedWorkout
+			err error
+		)
+		resp := prefillResp{Rationale: []string{}, Items: []prefillItem{}}
+		lang := requestLang(r)
+		day, err := requestDay(r)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+
+		if v := strings.TrimSpace(r.URL.Query().Get("treino_id")); v != "" {
+			tid, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || tid <= 0 {
+				badRequest(w, "invalid treino_id")
+				return
+			}
+			resp.TreinoID = tid
+			pw, err = plannedWorkoutByTreino(r.Context(), db, userID, tid)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+		} else {
+			resp.Date = day
+			pw, err = plannedWorkoutByDate(r.Context(), db, userID, day)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if pw == nil {
+				notFound(w)
+				return
+			}
+			resp.TreinoID = pw.treinoID
+		}
+
+		if pw != nil {
+			resp.Date = pw.date
+			resp.ProgramID = &pw.programID
+			resp.ProgramWorkoutID = &pw.id
+			resp.Week, resp.Day = &pw.week, &pw.day
+			resp.Phase = pw.phase
+			resp.Deload = pw.deload
+			resp.IntensityPct = &pw.intensityPct
+			resp.Rationale = append(resp.Rationale,
+				i18n.T(lang, "prefill.program", pw.week, pw.phase, pw.intensityPct))
+		}
+
+		rows, err := db.QueryContext(r.Context(), `
+			SELECT te.exercicio_id, e.name, te.series, te.repeticoes,
+			       te.target_rir, te.top_set_rpe::float8, te.backoff_pct::float8, te.backoff_sets
+			FROM treino_exercicios te
+			JOIN exercises e ON e.id = te.exercicio_id
+			WHERE te.treino_id = $1
+			  AND `+exerciseVisibleSQL("e.owner_user_id", 2)+`
+			ORDER BY te.id
+		`, resp.TreinoID, userID)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer rows.Close()
+		ids := []int64{}
+		for rows.Next() {
+			var it prefillItem
+			if err := rows.Scan(&it.ExercicioID, &it.Nome, &it.Series, &it.Repeticoes,
+				&it.TargetRIR, &it.TopSetRPE, &it.BackoffPct, &it.BackoffSets); err != nil {
+				internalErr(w, err)
+				return
+			}
+			resp.Items = append(resp.Items, it)
+			ids = append(ids, it.ExercicioID)
+		}
+		if err := rows.Err(); err != nil {
+			internalErr(w, err)
+			return
+		}
+		if len(resp.Items) == 0 {
+			notFound(w)
+			return
+		}
+
+		// carga-alvo: % do e1RM do programa, ou carga p/ topo da faixa com ~2 RIR
+		e1rm, err := recentE1RM(r.Context(), db, userID, ids, 56)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		for i := range resp.Items {
+			it := &resp.Items[i]
+			est, ok := e1rm[it.ExercicioID]
+			if !ok || est <= 0 {
+				continue
+			}
+			v := est
+			it.E1RMKg = &v
+			var target float64
+			if pw != nil {
+				target = est * pw.intensityPct / 100
+			} else {
+				target = loadForReps(est, repRangeTop(it.Repeticoes)+2)
+			}
+			target = roundTo(target, 0.5)
+			it.TargetWeightKg = &target
+		}
+		if pw == nil {
+			resp.Rationale = append(resp.Rationale, i18n.T(lang, "prefill.e1rm"))
+		}
+
+		// deload manual/recomendado (o do programa já está embutido no treino)
+		if !resp.Deload {
+			dl, err := activeDeload(r.Context(), db, userID, day)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if dl != nil && dl.Source != "program" {
+				resp.Deload = true
+				for i := range resp.Items {
+					it := &resp.Items[i]
+					it.Series = scaleSets(it.Series, dl.VolumeFactor)
+					if it.TargetWeightKg != nil {
+						v := roundTo(*it.TargetWeightKg*dl.LoadFactor, 0.5)
+						it.TargetWeightKg = &v
+					}
+				}
+				resp.Rationale = append(resp.Rationale, i18n.T(lang, "prefill.deload", dl.EndsOn, dl.LoadFactor*100, dl.VolumeFactor*100))
+			}
+		}
+
+		// prontidão do dia (check-in): ajusta carga/volume fora de deload
+		rd, err := loadReadiness(r.Context(), db, userID, day)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if rd != nil {
+			resp.Readiness = rd
+			if resp.Deload {
+				resp.Rationale = append(resp.Rationale, i18n.T(lang, "readiness.ignored", i18n.Label(lang, "readiness.band", rd.Band), rd.Score))
+			} else if rd.LoadFactor != 1 || rd.VolumeFactor != 1 {
+				for i := range resp.Items {
+					it := &resp.Items[i]
+					it.Series = scaleSets(it.Series, rd.VolumeFactor)
+					if it.TargetWeightKg != nil {
+						v := roundTo(*it.TargetWeightKg*rd.LoadFactor, 0.5)
+						it.TargetWeightKg = &v
+					}
+				}
+				resp.Rationale = append(resp.Rationale, readinessRationale(lang, rd))
+			}
+		}
+
+		jsonWrite(w, http.StatusOK, resp)
+	})
+}
+
+// repRangeTop: "8-12" => 12; "5" => 5; inválido => 10.
+func repRangeTop(s string) int {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "-"); i >= 0 {
+		s = s[i+1:]
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && n > 0 {
+		return n
+	}
+	return 10
+}
+
+const plannedWorkoutCols = `
+	SELECT pw.id, pw.program_id, pw.week, pw.day, to_char(pw.scheduled_date, 'YYYY-MM-DD'),
+	       pw.treino_id, pw.phase, pw.deload, pw.intensity_pct::float8
+	FROM program_workouts pw
+	JOIN programs p ON p.id = pw.program_id
+`
+
+func scanPlannedWorkout(row *sql.Row) (*plannedWorkout, error) {
+	var pw plannedWorkout
+	err := row.Scan(&pw.id, &pw.programID, &pw.week, &pw.day, &pw.date,
+		&pw.treinoID, &pw.phase, &pw.deload, &pw.intensityPct)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pw, nil
+}
+
+// programa mais recente com treino agendado na data
+func plannedWorkoutByDate(ctx context.Context, db *sql.DB, userID, day string) (*plannedWorkout, error) {
+	return scanPlannedWorkout(db.QueryRowContext(ctx, plannedWorkoutCols+`
+		WHERE p.user_id = $1 AND pw.scheduled_date = $2::date AND pw.treino_id IS NOT NULL
+		ORDER BY p.created_at DESC, pw.day
+		LIMIT 1
+	`, userID, day))
+}
+
+func plannedWorkoutByTreino(ctx context.Context, db *sql.DB, userID string, treinoID int64) (*plannedWorkout, error) {
+	return scanPlannedWorkout(db.QueryRowContext(ctx, plannedWorkoutCols+`
+		WHERE p.user_id = $1 AND pw.treino_id = $2
+		LIMIT 1
+	`, userID, treinoID))
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"anima/internal/i18n"
+)
+
+// Anomalias em séries registradas (ex.: 600 kg em vez de 60 kg).
+// Cada escrita é comparada com o histórico do usuário no exercício (IQR +
+// razão sobre a mediana); séries suspeitas ficam com flagged = TRUE e saem das
+// views/MV de overload e das análises até serem confirmadas ({"confirm": true}).
+//
+// SETS_ANOMALY_MODE=confirm exige a confirmação já na criação (409 sem ela);
+// o default ("flag") grava e devolve o aviso.
+
+const (
+	anomalyHistory    = 30  // últimas séries válidas consideradas
+	anomalyMinHistory = 5   // mínimo p/ IQR
+	anomalyIQRK       = 3.0 // cerca de Tukey "extrema"
+	anomalyRatio      = 2.5 // ≥2.5x ou ≤1/2.5 da mediana => suspeito
+	anomalyMaxWeight  = 400.0
+	anomalyMaxReps    = 50
+)
+
+func anomalyConfirmMode() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("SETS_ANOMALY_MODE")), "confirm")
+}
+
+// quartis por interpolação linear (v ordenado)
+func quantile(v []float64, q float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	pos := q * float64(len(v)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return v[lo] + (v[hi]-v[lo])*(pos-float64(lo))
+}
+
+// outlierReason: "" se x é compatível com o histórico. field já vem traduzido.
+func outlierReason(lang i18n.Lang, field string, x float64, hist []float64, minSpread float64) string {
+	if len(hist) == 0 {
+		return ""
+	}
+	v := append([]float64(nil), hist...)
+	sort.Float64s(v)
+	med := quantile(v, 0.5)
+
+	if med > 0 && (x >= med*anomalyRatio || x <= med/anomalyRatio) {
+		return i18n.T(lang, "anomaly.far_median", field, x, med)
+	}
+	if len(v) < anomalyMinHistory {
+		return ""
+	}
+	q1, q3 := quantile(v, 0.25), quantile(v, 0.75)
+	iqr := math.Max(q3-q1, minSpread)
+	if x > q3+anomalyIQRK*iqr || x < q1-anomalyIQRK*iqr {
+		return i18n.T(lang, "anomaly.out_of_range", field, x, q1, q3)
+	}
+	return ""
+}
+
+// setAnomalies: motivos de suspeita para a série (vazio = ok), no idioma da
+// requisição. excludeID ignora a própria série.
+func setAnomalies(ctx context.Context, db *sql.DB, uid string, exID int64, weight *float64, reps, rir *int, excludeID int64) ([]string, error) {
+	lang := langOf(ctx)
+	reasons := []string{}
+	if rir != nil && (*rir < 0 || *rir > 10) {
+		reasons = append(reasons, i18n.T(lang, "anomaly.rir", *rir))
+	}
+	if weight != nil && *weight > anomalyMaxWeight {
+		reasons = append(reasons, i18n.T(lang, "anomaly.weight_max", *weight))
+	}
+	if reps != nil && *reps > anomalyMaxReps {
+		reasons = append(reasons, i18n.T(lang, "anomaly.reps_max", *reps))
+	}
+	if uid == "" || (weight == nil && reps == nil) {
+		return reasons, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT s.weight_kg::float8, s.reps
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE ws.user_id = $1
+		  AND s.exercicio_id = $2
+		  AND s.id <> $3
+		  AND s.completed = TRUE
+		  AND NOT s.flagged
+		ORDER BY s.id DESC
+		LIMIT $4
+	`, uid, exID, excludeID, anomalyHistory)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ws, rs []float64
+	for rows.Next() {
+		var (
+			w sql.NullFloat64
+			r sql.NullInt64
+		)
+		if err := rows.Scan(&w, &r); err != nil {
+			return nil, err
+		}
+		if w.Valid && w.Float64 > 0 {
+			ws = append(ws, w.Float64)
+		}
+		if r.Valid && r.Int64 > 0 {
+			rs = append(rs, float64(r.Int64))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if weight != nil && *weight > 0 {
+		if why := outlierReason(lang, i18n.T(lang, "anomaly.field.load"), *weight, ws, 2.5); why != "" {
+			reasons = append(reasons, why)
+		}
+	}
+	if reps != nil && *reps > 0 {
+		if why := outlierReason(lang, i18n.T(lang, "anomaly.field.reps"), float64(*reps), rs, 2); why != "" {
+			reasons = append(reasons, why)
+		}
+	}
+	return reasons, nil
+}
+
+// setFlagState decide flagged/confirmada após uma escrita. A confirmação vale
+// enquanto carga, reps e rir não mudam; editar só notas/descanso/dor mantém a
+// série liberada.
+func setFlagState(suspect, confirm, confirmed, changed bool) (flagged, stillConfirmed bool) {
+	if changed {
+		confirmed = false
+	}
+	if confirm && suspect {
+		confirmed = true
+	}
+	return suspect && !confirmed, confirmed
+}
+
+// SetFlagState exportado pros testes
+func SetFlagState(suspect, confirm, confirmed, changed bool) (bool, bool) {
+	return setFlagState(suspect, confirm, confirmed, changed)
+}
+
+// applySetFlags grava o resultado da checagem; confirm libera a série mesmo
+// suspeita e confirmed_at só é zerado quando os valores mudaram.
+func applySetFlags(ctx context.Context, db *sql.DB, setID int64, reasons []string, flagged, confirmed bool) error {
+	_, err := db.ExecContext(ctx, `
+		UPDATE workout_sets
+		SET flagged      = $2,
+		    flag_reasons = $3,
+		    confirmed_at = CASE WHEN NOT $4 THEN NULL ELSE COALESCE(confirmed_at, NOW()) END
+		WHERE id = $1
+	`, setID, flagged, pq.Array(reasons), confirmed)
+	return err
+}
+
+// setValuesChanged: expressão SQL p/ RETURNING de um UPDATE workout_sets s
+// com self-join "old" (linha antes da escrita).
+const setValuesChanged = `(s.weight_kg, s.reps, s.rir) IS DISTINCT FROM (old.weight_kg, old.reps, old.rir)`
+
+// recheckSet reavalia a série com os valores gravados (PATCH/batch). changed
+// indica se carga/reps/rir mudaram nesta escrita.
+func recheckSet(ctx context.Context, db *sql.DB, setID int64, confirm, changed bool) ([]string, bool, error) {
+	var (
+		uid       sql.NullString
+		exID      int64
+		weight    sql.NullFloat64
+		reps, rir sql.NullInt64
+		confirmed bool
+	)
+	err := db.QueryRowContext(ctx, `
+		SELECT ws.user_id, s.exercicio_id, s.weight_kg::float8, s.reps, s.rir, s.confirmed_at IS NOT NULL
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE s.id = $1
+	`, setID).Scan(&uid, &exID, &weight, &reps, &rir, &confirmed)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var (
+		wp      *float64
+		rp, rrp *int
+	)
+	if weight.Valid {
+		wp = &weight.Float64
+	}
+	if reps.Valid {
+		v := int(reps.Int64)
+		rp = &v
+	}
+	if rir.Valid {
+		v := int(rir.Int64)
+		rrp = &v
+	}
+	reasons, err := setAnomalies(ctx, db, uid.String, exID, wp, rp, rrp, setID)
+	if err != nil {
+		return nil, false, err
+	}
+	flagged, confirmed := setFlagState(len(reasons) > 0, confirm, confirmed, changed)
+	if err := applySetFlags(ctx, db, setID, reasons, flagged, confirmed); err != nil {
+		return nil, false, err
+	}
+	return reasons, flagged, nil
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -11,11 +12,12 @@ import (
 //	{
 //	  "items": [
 //	    { "id": 4, "weight_kg": 45, "reps": 9, "rir": 1, "completed": true, "notes": "opcional" },
-//	    { "id": 5, "carga_kg": 47.5, "repeticoes": 8 }  // suporte legado
+//	    { "id": 5, "carga_kg": 47.5, "repeticoes": 8 },  // suporte legado
+//	    { "id": 6, "confirm": true }                      // confirma série suspeita
 //	  ]
 //	}
 //
-// Resposta: { "updated": N, "failed": [ids...], "total": M }
+// Resposta: { "updated": N, "failed": [ids...], "flagged": [{id, warnings}], "total": M }
 func SetsBatch(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPatch {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -39,6 +41,7 @@ func SetsBatch(w http.ResponseWriter, r *http.Request) {
 
 	updated := 0
 	failed := make([]int64, 0, len(in.Items))
+	flagged := make([]map[string]any, 0)
 
 	for _, it := range in.Items {
 		// id obrigatório
@@ -54,10 +57,11 @@ func SetsBatch(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// permitir nomes novos e legados
-		// mapeia para colunas atuais: weight_kg, reps, rir, completed, notes, rest_sec
+		// mapeia para colunas atuais: weight_kg, reps, rir, completed, notes, rest_sec, pain
 		setParts := []string{}
 		args := []any{}
 		argIdx := 1
+		confirm := false
 
 		for k, v := range it {
 			switch k {
@@ -108,6 +112,22 @@ func SetsBatch(w http.ResponseWriter, r *http.Request) {
 				setParts = append(setParts, "notes = $"+itoa(argIdx))
 				args = append(args, s)
 				argIdx++
+			case "pain":
+				iv, ok := toInt64(v)
+				if !ok || iv < 0 || iv > 10 {
+					badRequest(w, "pain must be int 0..10")
+					return
+				}
+				setParts = append(setParts, "pain = $"+itoa(argIdx))
+				args = append(args, iv)
+				argIdx++
+			case "confirm":
+				b, ok := v.(bool)
+				if !ok {
+					badRequest(w, "confirm must be bool")
+					return
+				}
+				confirm = b
 			case "rest_sec":
 				iv, ok := toInt64(v)
 				if !ok {
@@ -123,37 +143,54 @@ func SetsBatch(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if len(setParts) == 0 {
-			// nada pra atualizar nesse item
-			failed = append(failed, id)
-			continue
+			if !confirm {
+				// nada pra atualizar nesse item
+				failed = append(failed, id)
+				continue
+			}
+			// só confirmação: UPDATE no-op para validar posse
+			setParts = append(setParts, "flagged = s.flagged")
 		}
 
 		q := `
 UPDATE workout_sets AS s
 SET ` + strings.Join(setParts, ", ") + `
-FROM workout_sessions AS ws
+FROM workout_sessions AS ws, workout_sets AS old
 WHERE s.id = $` + itoa(argIdx) + `
   AND ws.id = s.session_id
+  AND old.id = s.id
   AND ($` + itoa(argIdx+1) + ` = '' OR ws.user_id IS NULL OR ws.user_id = $` + itoa(argIdx+1) + `)
+RETURNING ` + setValuesChanged + `
 `
 		args = append(args, id, userID)
 
-		res, err := db.Exec(q, args...)
+		// old = linha antes do UPDATE: confirmação só cai se carga/reps/rir mudaram
+		var changed bool
+		err := db.QueryRow(q, args...).Scan(&changed)
+		if err == sql.ErrNoRows {
+			failed = append(failed, id)
+			continue
+		}
 		if err != nil {
 			internalErr(w, err)
 			return
 		}
-		aff, _ := res.RowsAffected()
-		if aff == 0 {
-			failed = append(failed, id)
-		} else {
-			updated++
+		updated++
+
+		warnings, isFlagged, err := recheckSet(r.Context(), db, id, confirm, changed)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		if isFlagged {
+			flagged = append(flagged, map[string]any{"id": id, "warnings": warnings})
 		}
 	}
 
 	jsonWrite(w, http.StatusOK, map[string]any{
 		"updated": updated,
 		"failed":  failed,
+		"flagged": flagged,
 		"total":   len(in.Items),
 	})
 }
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+
+	"github.com/lib/pq"
+)
+
+// epley1RM estima 1RM (Epley). reps=1 => a própria carga.
+func epley1RM(weightKg float64, reps int) float64 {
+	if weightKg <= 0 || reps <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return weightKg
+	}
+	return weightKg * (1 + float64(reps)/30.0)
+}
+
+// loadForReps inverte Epley: carga p/ `reps` a partir do 1RM.
+func loadForReps(e1rm float64, reps int) float64 {
+	if e1rm <= 0 || reps <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return e1rm
+	}
+	return e1rm / (1 + float64(reps)/30.0)
+}
+
+// e1rmSQL: Epley em SQL sobre workout_sets (alias s).
+const e1rmSQL = `(CASE WHEN s.reps = 1 THEN s.weight_kg ELSE s.weight_kg * (1 + s.reps / 30.0) END)`
+
+// recentE1RM: melhor e1RM por exercício do usuário nos últimos `days` dias
+// (sets concluídos, 1..12 reps; séries mais longas estimam mal o 1RM).
+func recentE1RM(ctx context.Context, db *sql.DB, uid string, ids []int64, days int) (map[int64]float64, error) {
+	out := map[int64]float64{}
+	if uid == "" || len(ids) == 0 {
+		return out, nil
+	}
+	rows, err := db.QueryContext(ctx, `
+		SELECT s.exercicio_id, MAX(`+e1rmSQL+`)::float8
+		FROM workout_sets s
+		JOIN workout_sessions ws ON ws.id = s.session_id
+		WHERE ws.user_id = $1
+		  AND s.exercicio_id = ANY($2)
+		  AND s.completed = TRUE
+		  AND NOT s.flagged
+		  AND s.weight_kg > 0
+		  AND s.reps BETWEEN 1 AND 12
+		  AND ws.started_at >= NOW() - make_interval(days => $3)
+		GROUP BY s.exercicio_id
+	`, uid, pq.Array(ids), days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id int64
+			v  float64
+		)
+		if err := rows.Scan(&id, &v); err != nil {
+			return nil, err
+		}
+		out[id] = math.Round(v*10) / 10
+	}
+	return out, rows.Err()
+}
@@ -7,13 +7,14 @@ import (
 )
 
 type TreinoListItem struct {
-	ID         int64   `json:"id"`
-	Nivel      *string `json:"nivel,omitempty"`
-	Objetivo   *string `json:"objetivo,omitempty"`
-	Divisao    *string `json:"divisao,omitempty"`
-	Dias       *int    `json:"dias,omitempty"`
-	CoachNotes *string `json:"coach_notes,omitempty"`
-	TreinoKey  *string `json:"treino_key,omitempty"`
+	ID          int64   `json:"id"`
+	Nivel       *string `json:"nivel,omitempty"`
+	Objetivo    *string `json:"objetivo,omitempty"`
+	Divisao     *string `json:"divisao,omitempty"`
+	Dias        *int    `json:"dias,omitempty"`
+	CoachNotes  *string `json:"coach_notes,omitempty"`
+	TreinoKey   *string `json:"treino_key,omitempty"`
+	RuleVersion *int    `json:"rule_version,omitempty"`
 }
 
 // GET /api/treinos?page=&page_size=&nivel=&objetivo=&divisao=
@@ -60,7 +61,7 @@ func TreinosCollection(db *sql.DB) http.Handler {
 		// lista (ordem recente)
 		argsList := append(append([]any{}, args...), pageSize, offset)
 		rows, err := db.Query(`
-			SELECT id, nivel, objetivo, divisao, dias, coach_notes, treino_key
+			SELECT id, nivel, objetivo, divisao, dias, coach_notes, treino_key, rule_version
 			FROM treinos `+where+`
 			ORDER BY id DESC
 			LIMIT $`+fmtInt(i)+` OFFSET $`+fmtInt(i+1), argsList...)
@@ -73,7 +74,7 @@ func TreinosCollection(db *sql.DB) http.Handler {
 		items := []TreinoListItem{}
 		for rows.Next() {
 			var it TreinoListItem
-			if err := rows.Scan(&it.ID, &it.Nivel, &it.Objetivo, &it.Divisao, &it.Dias, &it.CoachNotes, &it.TreinoKey); err != nil {
+			if err := rows.Scan(&it.ID, &it.Nivel, &it.Objetivo, &it.Divisao, &it.Dias, &it.CoachNotes, &it.TreinoKey, &it.RuleVersion); err != nil {
 				internalErr(w, err)
 				return
 			}
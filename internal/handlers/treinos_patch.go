@@ -15,6 +15,11 @@ type treinoPatch struct {
 	Divisao    *string `json:"divisao,omitempty"`
 	Dias       *int    `json:"dias,omitempty"`
 	TreinoKey  *string `json:"treino_key,omitempty"`
+
+	// exercícios já no treino: series/repeticoes se enviados; o bloco de
+	// autorregulação (target_rir, top_set_rpe, backoff_pct, backoff_sets) é
+	// substituído inteiro (ausente = default)
+	Exercicios []SaveTreinoItemReq `json:"exercicios,omitempty"`
 }
 
 // PATCH /api/treinos/{id}
@@ -83,17 +88,61 @@ func TreinosUpdate(db *sql.DB) http.Handler {
 			setCount++
 		}
 
-		if setCount == 0 {
+		if setCount == 0 && len(in.Exercicios) == 0 {
 			badRequest(w, "no fields to update")
 			return
 		}
+		for _, it := range in.Exercicios {
+			if it.ExercicioID <= 0 || it.Series < 0 {
+				badRequest(w, "invalid item in exercicios")
+				return
+			}
+			if msg := validateAutoreg(it); msg != "" {
+				badRequest(w, msg)
+				return
+			}
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			internalErr(w, err)
+			return
+		}
+		defer func() { _ = tx.Rollback() }()
 
-		// remove vírgula final
-		q = strings.TrimSuffix(q, ", ")
-		q += " WHERE id = $" + fmtInt(i)
-		args = append(args, id)
+		if setCount > 0 {
+			// remove vírgula final
+			q = strings.TrimSuffix(q, ", ")
+			q += " WHERE id = $" + fmtInt(i)
+			args = append(args, id)
 
-		if _, err := db.Exec(q, args...); err != nil {
+			if _, err := tx.ExecContext(r.Context(), q, args...); err != nil {
+				internalErr(w, err)
+				return
+			}
+		}
+		for _, it := range in.Exercicios {
+			res, err := tx.ExecContext(r.Context(), `
+				UPDATE treino_exercicios
+				SET series       = COALESCE(NULLIF($3, 0), series),
+				    repeticoes   = COALESCE(NULLIF($4, ''), repeticoes),
+				    target_rir   = $5,
+				    top_set_rpe  = $6,
+				    backoff_pct  = $7,
+				    backoff_sets = $8
+				WHERE treino_id = $1 AND exercicio_id = $2
+			`, id, it.ExercicioID, it.Series, it.Repeticoes,
+				it.TargetRIR, it.TopSetRPE, it.BackoffPct, it.BackoffSets)
+			if err != nil {
+				internalErr(w, err)
+				return
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				notFound(w)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
 			internalErr(w, err)
 			return
 		}
@@ -22,6 +22,12 @@ type SaveTreinoItemReq struct {
 	ExercicioID int64  `json:"exercicio_id"`
 	Series      int    `json:"series"`
 	Repeticoes  string `json:"repeticoes"`
+
+	// autorregulação (opcionais)
+	TargetRIR   *int     `json:"target_rir,omitempty"`   // 0..5 (default 2)
+	TopSetRPE   *float64 `json:"top_set_rpe,omitempty"`  // 6..10: 1ª série é top set
+	BackoffPct  *float64 `json:"backoff_pct,omitempty"`  // 1..30: redução das back-offs
+	BackoffSets *int     `json:"backoff_sets,omitempty"` // default series-1
 }
 
 type SaveTreinoResp struct {
@@ -29,26 +35,27 @@ type SaveTreinoResp struct {
 	TreinoID string `json:"treino_id"`
 }
 
+// POST /api/treinos: treino manual, com autorregulação opcional por exercício
 func SaveTreino(db *sql.DB) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			w.WriteHeader(http.StatusMethodNotAllowed)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		var req SaveTreinoReq
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "json inválido", http.StatusBadRequest)
+			badRequest(w, "invalid json")
 			return
 		}
 		if req.Objetivo == "" || req.Nivel == "" || req.Dias <= 0 || req.Divisao == "" || len(req.Exercicios) == 0 {
-			http.Error(w, "campos obrigatórios ausentes", http.StatusBadRequest)
+			badRequest(w, "missing required fields")
 			return
 		}
 
 		tx, err := db.Begin()
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalErr(w, err)
 			return
 		}
 		defer func() { _ = tx.Rollback() }()
@@ -62,45 +69,72 @@ func SaveTreino(db *sql.DB) http.Handler {
 		if err != nil {
 			// se for violação de unicidade (treino_key único), responder 409
 			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
-				http.Error(w, "treino_id já existe", http.StatusConflict)
+				jsonWrite(w, http.StatusConflict, map[string]string{"error": "treino_id already exists"})
 				return
 			}
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalErr(w, err)
 			return
 		}
 
 		stmt, err := tx.Prepare(`
-			INSERT INTO treino_exercicios (treino_id, exercicio_id, series, repeticoes)
-			VALUES ($1, $2, $3, $4)
+			INSERT INTO treino_exercicios (treino_id, exercicio_id, series, repeticoes,
+			                               target_rir, top_set_rpe, backoff_pct, backoff_sets)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		`)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalErr(w, err)
 			return
 		}
 		defer stmt.Close()
 
 		for _, it := range req.Exercicios {
 			if it.ExercicioID <= 0 || it.Series <= 0 || it.Repeticoes == "" {
-				http.Error(w, "item inválido em exercicios", http.StatusBadRequest)
+				badRequest(w, "invalid item in exercicios")
+				return
+			}
+			if msg := validateAutoreg(it); msg != "" {
+				badRequest(w, msg)
 				return
 			}
-			if _, err := stmt.Exec(treinoDBID, it.ExercicioID, it.Series, it.Repeticoes); err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			if _, err := stmt.Exec(treinoDBID, it.ExercicioID, it.Series, it.Repeticoes,
+				it.TargetRIR, it.TopSetRPE, it.BackoffPct, it.BackoffSets); err != nil {
+				internalErr(w, err)
 				return
 			}
 		}
 
 		if err := tx.Commit(); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			internalErr(w, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		_ = json.NewEncoder(w).Encode(SaveTreinoResp{ID: treinoDBID, TreinoID: req.TreinoID})
+		jsonWrite(w, http.StatusCreated, SaveTreinoResp{ID: treinoDBID, TreinoID: req.TreinoID})
 	})
 }
 
+// validateAutoreg: "" se ok; top_set_rpe e backoff_pct andam juntos
+func validateAutoreg(it SaveTreinoItemReq) string {
+	if it.TargetRIR != nil && (*it.TargetRIR < 0 || *it.TargetRIR > 5) {
+		return "target_rir must be between 0 and 5"
+	}
+	if (it.TopSetRPE == nil) != (it.BackoffPct == nil) {
+		return "top_set_rpe and backoff_pct must be set together"
+	}
+	if it.TopSetRPE != nil && (*it.TopSetRPE < 6 || *it.TopSetRPE > 10) {
+		return "top_set_rpe must be between 6 and 10"
+	}
+	if it.BackoffPct != nil && (*it.BackoffPct < 1 || *it.BackoffPct > 30) {
+		return "backoff_pct must be between 1 and 30"
+	}
+	if it.BackoffSets != nil && (*it.BackoffSets < 1 || *it.BackoffSets > 6) {
+		return "backoff_sets must be between 1 and 6"
+	}
+	return ""
+}
+
+// ValidateAutoreg exportado pros testes
+func ValidateAutoreg(it SaveTreinoItemReq) string { return validateAutoreg(it) }
+
 // retorna nil se ponteiro vazio/nil – pra evitar gravar ""
 func optStr(p *string) any {
 	if p == nil || *p == "" {
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// "Hoje" do usuário. O servidor trabalha em UTC, mas check-in, prefill, overload
+// e deload seguem o calendário local: em pt-BR (UTC-3) um treino depois das 21h
+// já cairia no dia seguinte. O cliente manda a data local (?date=YYYY-MM-DD) ou
+// o fuso (header X-Timezone, IANA, ex.: America/Sao_Paulo); sem nenhum, UTC.
+
+var errBadDate = errors.New("date must be YYYY-MM-DD")
+
+// localDay: data (YYYY-MM-DD) de t no fuso tz; vazio/inválido => UTC.
+func localDay(t time.Time, tz string) string {
+	tz = strings.TrimSpace(tz)
+	if tz == "" || tz == "Local" {
+		return utcDay(t)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return utcDay(t)
+	}
+	return t.In(loc).Format("2006-01-02")
+}
+
+// LocalDay exportado pros testes
+func LocalDay(t time.Time, tz string) string { return localDay(t, tz) }
+
+// requestDay: "hoje" da requisição — ?date= quando informado, senão a data
+// local pelo X-Timezone.
+func requestDay(r *http.Request) (string, error) {
+	if v := strings.TrimSpace(r.URL.Query().Get("date")); v != "" {
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return "", errBadDate
+		}
+		return v, nil
+	}
+	return localDay(time.Now(), r.Header.Get("X-Timezone")), nil
+}
+
+// latestLocalDay: o dia mais adiantado em algum fuso (UTC+14); datas além
+// disso estão no futuro para qualquer cliente.
+func latestLocalDay(now time.Time) string {
+	return utcDay(now.Add(14 * time.Hour))
+}
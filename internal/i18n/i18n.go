@@ -0,0 +1,98 @@
+// Package i18n: idiomas da API (pt-BR e en), catálogos de mensagens e seleção
+// por Accept-Language.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	PT Lang = "pt-BR"
+	EN Lang = "en"
+
+	Default = PT
+)
+
+// Supported: idiomas com catálogo.
+var Supported = []Lang{PT, EN}
+
+// Parse normaliza uma tag ("pt", "pt_br", "en-US"...) para um idioma suportado.
+func Parse(tag string) (Lang, bool) {
+	t := strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, "_", "-")))
+	switch {
+	case t == "pt" || strings.HasPrefix(t, "pt-"):
+		return PT, true
+	case t == "en" || strings.HasPrefix(t, "en-"):
+		return EN, true
+	}
+	return "", false
+}
+
+// FromAcceptLanguage escolhe o idioma suportado de maior q no header
+// ("en-US,en;q=0.9,pt;q=0.8"). ok=false se nenhum for suportado.
+func FromAcceptLanguage(header string) (Lang, bool) {
+	type cand struct {
+		lang Lang
+		q    float64
+		pos  int
+	}
+	var cs []cand
+	for i, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		l, ok := Parse(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			cs = append(cs, cand{l, q, i})
+		}
+	}
+	if len(cs) == 0 {
+		return "", false
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].q > cs[j].q })
+	return cs[0].lang, true
+}
+
+type ctxKey struct{}
+
+// WithLang / FromContext: idioma da requisição no context.
+func WithLang(ctx context.Context, l Lang) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+func FromContext(ctx context.Context) Lang {
+	if l, ok := ctx.Value(ctxKey{}).(Lang); ok && l != "" {
+		return l
+	}
+	return Default
+}
+
+// T devolve a mensagem key no idioma l (fmt.Sprintf com args). Sem tradução,
+// cai para pt-BR e, por fim, para a própria chave.
+func T(l Lang, key string, args ...any) string {
+	m, ok := messages[key]
+	if !ok {
+		return key
+	}
+	s := m.pt
+	if l == EN && m.en != "" {
+		s = m.en
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
@@ -0,0 +1,295 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+)
+
+type msg struct{ pt, en string }
+
+// messages: catálogo por chave. Chaves "err.*" também são reconhecidas pelo
+// texto (pt ou en) em Translate, o que cobre as mensagens já espalhadas nos handlers.
+var messages = map[string]msg{
+	// ===== erros
+	"err.method_not_allowed": {"método não permitido", "method not allowed"},
+	"err.invalid_json":       {"JSON inválido", "invalid JSON"},
+	"err.unauthorized":       {"não autorizado", "unauthorized"},
+	"err.missing_user":       {"não autorizado (usuário ausente)", "unauthorized (missing user id)"},
+	"err.not_found":          {"não encontrado", "not found"},
+	"err.bad_request":        {"requisição inválida", "bad request"},
+	"err.missing_id":         {"id ausente", "missing id"},
+	"err.invalid_id":         {"id inválido", "invalid id"},
+	"err.invalid_path":       {"caminho inválido", "invalid path"},
+	"err.no_fields":          {"nenhum campo para atualizar", "no fields to update"},
+	"err.no_updatable":       {"nenhum campo atualizável", "no updatable fields"},
+	"err.empty_body":         {"corpo vazio", "empty body"},
+	"err.rate_limited":       {"limite de requisições excedido", "rate limit exceeded"},
+	"err.media_type":         {"tipo de conteúdo não suportado (use application/json)", "unsupported media type (use application/json)"},
+	"err.invalid_creds":      {"credenciais inválidas", "invalid credentials"},
+	"err.invalid_token":      {"token inválido", "invalid token"},
+	"err.internal":           {"erro interno", "internal error"},
+	"err.required_fields":    {"campos obrigatórios ausentes", "missing required fields"},
+	"err.plan_fields":        {"campos obrigatórios: objetivo, nivel, divisao", "required fields: objetivo, nivel, divisao"},
+	"err.empty_catalog":      {"não há exercícios cadastrados no catálogo", "the exercise catalog is empty"},
+	"err.empty_catalog_plan": {"catálogo vazio para gerar plano", "empty catalog, cannot build a plan"},
+	"err.empty_catalog_prog": {"catálogo vazio para gerar programa", "empty catalog, cannot build a program"},
+	"err.treino_exists":      {"treino_id já existe", "treino_id already exists"},
+	"err.bad_item":           {"item inválido em exercicios", "invalid item in exercicios"},
+	"err.user_not_found":     {"usuário não encontrado", "user not found"},
+	"err.wrong_password":     {"senha incorreta", "wrong password"},
+	"err.global_name_taken":  {"já existe um exercício global com este nome", "a global exercise with this name already exists"},
+	"err.email_taken":        {"e-mail já cadastrado", "email already registered"},
+	"err.invalid_email":      {"e-mail inválido", "invalid email"},
+	"err.weak_password":      {"a senha deve ter ao menos 8 caracteres", "password must be at least 8 characters"},
+	"err.invalid_or_expired": {"token inválido ou expirado", "invalid or expired token"},
+	"err.email_unverified":   {"e-mail não verificado", "email not verified"},
+	"err.forbidden":          {"acesso negado", "forbidden"},
+	"err.last_admin":         {"não é possível revogar o último admin", "cannot revoke the last admin"},
+	"err.role_invalid":       {"papel deve ser coach ou admin", "role must be coach or admin"},
+	"err.pat_scope":          {"o escopo do token não permite esta rota", "token scope does not allow this route"},
+	"err.too_many_attempts":  {"tentativas de login demais; tente mais tarde", "too many login attempts"},
+	"err.too_many_mails":     {"e-mails demais solicitados; tente mais tarde", "too many emails requested"},
+	"err.mfa_required":       {"autenticação em dois fatores obrigatória", "two-factor authentication required"},
+	"err.mfa_code":           {"código inválido", "invalid code"},
+	"err.mfa_enabled":        {"autenticação em dois fatores já está ativa", "two-factor authentication already enabled"},
+	"err.mfa_not_enabled":    {"autenticação em dois fatores não está ativa", "two-factor authentication is not enabled"},
+	"err.mfa_no_pending":     {"nenhum cadastro de dois fatores pendente", "no pending two-factor enrollment"},
+	"err.mfa_role":           {"seu papel exige autenticação em dois fatores", "two-factor authentication is required for your role"},
+	"err.save_treino":        {"erro ao salvar treino", "failed to save workout"},
+	"err.register":           {"erro ao registrar usuário", "failed to register user"},
+	"err.issue_token":        {"erro ao gerar token", "failed to issue token"},
+	"err.load_goals":         {"erro ao buscar objetivos", "failed to load goals"},
+	"err.load_groups":        {"erro ao buscar grupos musculares", "failed to load muscle groups"},
+	"err.build_plan":         {"falha ao montar plano", "failed to build plan"},
+	"err.load_rules":         {"falha ao carregar regras", "failed to load rules"},
+	"err.planner":            {"erro no planner", "planner error"},
+	"err.deload_active":      {"deload já ativo", "deload already active"},
+	"err.date_future":        {"a data não pode estar no futuro", "date cannot be in the future"},
+	"err.deadline_future":    {"o prazo deve estar no futuro", "deadline must be in the future"},
+	"err.email_password":     {"e-mail e senha são obrigatórios", "email and password required"},
+	"err.quota_messages":     {"quota diária de mensagens esgotada", "daily message quota exceeded"},
+	"err.quota_tokens":       {"quota diária de tokens esgotada", "daily token quota exceeded"},
+	"err.topset_pair":        {"top_set_rpe e backoff_pct devem ser informados juntos", "top_set_rpe and backoff_pct must be set together"},
+	"err.validation":         {"validação falhou", "validation failed"},
+
+	// ===== overload / prefill
+	"overload.no_history":   {"sem histórico concluído para este exercício", "no completed history for this exercise"},
+	"overload.keep":         {"RIR moderado, manter carga", "moderate RIR, keep the load"},
+	"overload.plus5":        {"RIR muito alto, sugere +5kg", "RIR very high, suggests +5kg"},
+	"overload.plus2_5":      {"RIR alto, sugere +2.5kg", "RIR high, suggests +2.5kg"},
+	"overload.reduce_reps":  {"RIR baixo, manter carga e reduzir reps", "RIR low, keep the load and reduce reps"},
+	"overload.deload":       {"deload ativo (%s) até %s: carga em %.0f%% da média recente", "active deload (%s) until %s: load at %.0f%% of the recent average"},
+	"readiness.adjust":      {"prontidão %s (%.0f vs. base %.0f): carga x%.3g, volume x%.2g", "readiness %s (%.0f vs. baseline %.0f): load x%.3g, volume x%.2g"},
+	"readiness.ignored":     {"prontidão %s (%.0f) ignorada: deload já reduz a sessão", "readiness %s (%.0f) ignored: deload already reduces the session"},
+	"prefill.program":       {"programa: semana %d, fase %s, %.0f%% do e1RM", "program: week %d, phase %s, %.0f%% of e1RM"},
+	"prefill.e1rm":          {"carga-alvo estimada do e1RM recente para o topo da faixa com ~2 RIR", "target load estimated from recent e1RM for the top of the range at ~2 RIR"},
+	"prefill.deload":        {"deload ativo até %s: %.0f%% da carga e %.0f%% das séries", "active deload until %s: %.0f%% of the load and %.0f%% of the sets"},
+	"readiness.band.low":    {"baixa", "low"},
+	"readiness.band.below":  {"abaixo do normal", "below normal"},
+	"readiness.band.normal": {"normal", "normal"},
+	"readiness.band.high":   {"alta", "high"},
+
+	// ===== próxima série (autorregulação)
+	"nextset.done":           {"%d/%d séries concluídas", "%d/%d sets done"},
+	"nextset.backoff":        {"top set a RPE %.0f (alvo %.1f); back-off -%.0f%%", "top set at RPE %.0f (target %.1f); back-off -%.0f%%"},
+	"nextset.backoff_adjust": {", ajuste %+.1f%% pelo RPE", ", %+.1f%% adjustment for RPE"},
+	"nextset.down":           {"RIR %d vs alvo %d: reduzir %.0f%% da carga", "RIR %d vs target %d: reduce the load by %.0f%%"},
+	"nextset.up":             {"RIR %d vs alvo %d: subir %.1f%% da carga", "RIR %d vs target %d: increase the load by %.1f%%"},
+	"nextset.plus_rep":       {"RIR %d vs alvo %d: manter carga, buscar +1 rep", "RIR %d vs target %d: keep the load, aim for +1 rep"},
+	"nextset.minus_rep":      {"RIR %d vs alvo %d: manter carga, 1 rep a menos", "RIR %d vs target %d: keep the load, 1 rep fewer"},
+	"nextset.repeat":         {"no alvo de RIR: repetir a série", "on the RIR target: repeat the set"},
+
+	// ===== anomalias em séries
+	"anomaly.rir":          {"RIR %d impossível (0–10)", "RIR %d impossible (0–10)"},
+	"anomaly.weight_max":   {"carga %.1f kg acima do limite plausível", "load %.1f kg above the plausible limit"},
+	"anomaly.reps_max":     {"%d reps acima do limite plausível", "%d reps above the plausible limit"},
+	"anomaly.far_median":   {"%s %.1f muito distante da mediana %.1f", "%s %.1f too far from the median %.1f"},
+	"anomaly.out_of_range": {"%s %.1f fora da faixa usual (%.1f–%.1f)", "%s %.1f outside the usual range (%.1f–%.1f)"},
+	"anomaly.field.load":   {"carga", "load"},
+	"anomaly.field.reps":   {"reps", "reps"},
+
+	// ===== fadiga / deload
+	"fatigue.e1rm_decline": {"e1RM caiu em %d sessões seguidas", "e1RM dropped for %d sessions in a row"},
+	"fatigue.rir_collapse": {"RIR médio caiu de %.1f para %.1f nas últimas 2 semanas", "average RIR dropped from %.1f to %.1f over the last 2 weeks"},
+	"fatigue.rpe_rise":     {"RPE da sessão subiu de %.1f para %.1f sem aumento de tonelagem", "session RPE rose from %.1f to %.1f with no increase in tonnage"},
+	"fatigue.rec.deload":   {"fadiga acumulada: faça uma semana de deload (~%.0f%% da carga, metade das séries)", "accumulated fatigue: take a deload week (~%.0f%% of the load, half the sets)"},
+	"fatigue.rec.monitor":  {"sinal isolado de fadiga: mantenha a carga e monitore sono/recuperação", "isolated fatigue signal: keep the load and watch sleep/recovery"},
+	"fatigue.rec.none":     {"sem sinais de fadiga relevantes", "no relevant fatigue signals"},
+
+	// ===== platôs
+	"plateau.reps.volume":    {"melhores séries com ~%.0f reps: bloco de 4–6 semanas em 8-12 para acumular volume", "best sets at ~%.0f reps: 4–6 week block at 8-12 to build volume"},
+	"plateau.reps.heavy":     {"melhores séries com ~%.0f reps: bloco de 4–6 semanas em 4-6 com mais carga", "best sets at ~%.0f reps: 4–6 week block at 4-6 with more load"},
+	"plateau.reps.alternate": {"melhores séries com ~%.0f reps: alterne para 3-5 (força) ou 12-15 (volume)", "best sets at ~%.0f reps: switch to 3-5 (strength) or 12-15 (volume)"},
+	"plateau.volume.reduce":  {"%.1f séries/semana: reduzir volume (ou uma semana de deload) para recuperar", "%.1f sets/week: reduce volume (or take a deload week) to recover"},
+	"plateau.volume.add":     {"%.1f séries/semana: adicionar 2 séries semanais", "%.1f sets/week: add 2 weekly sets"},
+	"plateau.swap":           {"trocar por %s por 4–6 semanas (mesmo grupo)", "swap for %s for 4–6 weeks (same group)"},
+
+	// ===== nutrição
+	"nutrition.no_gender":    {"sexo não informado: BMR pela média entre as fórmulas", "sex not set: BMR from the average of the formulas"},
+	"nutrition.no_activity":  {"activity_level ausente/desconhecido: fator 1.375 (leve)", "activity_level missing/unknown: factor 1.375 (light)"},
+	"nutrition.trend_adjust": {"peso de tendência variando %+.2f kg/semana vs %+.2f esperado", "trend weight changing %+.2f kg/week vs %+.2f expected"},
+
+	// ===== limitações / dor
+	"limit.pain":    {"dor %d/10 registrada nos últimos %d dias", "pain %d/10 logged in the last %d days"},
+	"limit.pattern": {"limitação (%s): evitar %s", "limitation (%s): avoid %s"},
+
+	// ===== registro por texto
+	"logtext.unitless_load": {"carga sem unidade: %.1f assumida em kg", "load without unit: %.1f taken as kg"},
+
+	// ===== plano gerado
+	"plan.day":             {"Dia %d · %s", "Day %d · %s"},
+	"plan.motive.replaced": {"grupo-alvo %s; substitui %s (contraindicado)", "target group %s; replaces %s (contraindicated)"},
+	"plan.motive.fill_lvl": {"complemento do catálogo (nível %s)", "catalog filler (level %s)"},
+	"plan.motive.fill":     {"complemento do catálogo", "catalog filler"},
+	"plan.motive.match":    {"grupo-alvo %s, nível %s", "target group %s, level %s"},
+	"plan.motive.fallback": {"grupo-alvo %s; sem opção no nível %s, usado o primeiro do grupo", "target group %s; nothing at level %s, used the first of the group"},
+	"plan.motive.group":    {"grupo-alvo %s", "target group %s"},
+	"program.note":         {"Semana %d/%d, dia %d — fase %s: %.0f%% do e1RM, %s reps.", "Week %d/%d, day %d — %s phase: %.0f%% of e1RM, %s reps."},
+	"program.note_deload":  {"Semana de deload: volume reduzido, foque em técnica e recuperação.", "Deload week: reduced volume, focus on technique and recovery."},
+	"division.fullbody":    {"Corpo inteiro", "Full body"},
+	"division.upper":       {"Membros superiores", "Upper body"},
+	"division.lower":       {"Membros inferiores", "Lower body"},
+	"division.push":        {"Empurrar", "Push"},
+	"division.pull":        {"Puxar", "Pull"},
+	"division.legs":        {"Pernas", "Legs"},
+	"division.upperlower":  {"Superiores/inferiores", "Upper/lower"},
+	"division.ppl":         {"Empurrar/puxar/pernas", "Push/pull/legs"},
+	"goal.hipertrofia":     {"hipertrofia", "hypertrophy"},
+	"goal.emagrecimento":   {"emagrecimento", "fat loss"},
+	"goal.forca":           {"força", "strength"},
+	"goal.resistencia":     {"resistência", "endurance"},
+	"goal.manutencao":      {"manutenção", "maintenance"},
+	"level.iniciante":      {"iniciante", "beginner"},
+	"level.intermediario":  {"intermediário", "intermediate"},
+	"level.avancado":       {"avançado", "advanced"},
+
+	// ===== notas do coach (heurística)
+	"coach.plan":          {"Plano %s (%s), divisão %s. ", "%s plan (%s), %s split. "},
+	"coach.goal_general":  {"geral", "general"},
+	"coach.level_unset":   {"nível indefinido", "level not set"},
+	"coach.split_unset":   {"indefinida", "not set"},
+	"coach.height":        {"Altura %.0fcm. ", "Height %.0fcm. "},
+	"coach.weight_trend":  {"Peso (tendência) %.1fkg, %+.2f kg/semana. ", "Weight (trend) %.1fkg, %+.2f kg/week. "},
+	"coach.weight":        {"Peso %.1fkg. ", "Weight %.1fkg. "},
+	"coach.age":           {"Idade %d. ", "Age %d. "},
+	"coach.bmi":           {"IMC=%.2f. ", "BMI=%.2f. "},
+	"coach.tip.hyper":     {"Foque em progressão de carga com técnica sólida; 8–12 reps nos compostos; sono ≥ 7h.", "Focus on load progression with solid technique; 8–12 reps on compound lifts; sleep ≥ 7h."},
+	"coach.tip.fatloss":   {"Aumente densidade do treino (descanso curto) e mantenha leve déficit calórico.", "Increase training density (short rests) and keep a mild calorie deficit."},
+	"coach.tip.strength":  {"Priorize compostos pesados; séries curtas e descanso maior; monitore a técnica.", "Prioritize heavy compound lifts; short sets and longer rests; watch your technique."},
+	"coach.tip.endurance": {"Volume moderado/alto, cadência controlada e constância semanal.", "Moderate/high volume, controlled tempo and weekly consistency."},
+	"coach.tip.default":   {"Mantenha técnica perfeita, aquecimento e progressão gradual.", "Keep perfect technique, warm up and progress gradually."},
+	"coach.tip.age40":     {" Aqueça bem ombros/quadril; evite picos de carga abruptos.", " Warm up shoulders/hips well; avoid abrupt load spikes."},
+
+	// ===== chat (heurística)
+	"chat.diet":            {"Veja /api/me/nutrition-targets para calorias e macros calculados para o seu objetivo.", "See /api/me/nutrition-targets for calories and macros computed for your goal."},
+	"chat.diet_protein":    {" Como referência, 1,6–2,2 g/kg de proteína dá %.0f–%.0f g/dia para %.1f kg.", " As a reference, 1.6–2.2 g/kg of protein is %.0f–%.0f g/day at %.1f kg."},
+	"chat.volume_none":     {"Não encontrei séries registradas nos últimos 7 dias. Registre seus treinos para eu acompanhar o volume por grupo muscular.", "I found no sets logged in the last 7 days. Log your workouts so I can track volume per muscle group."},
+	"chat.volume":          {"Séries válidas nos últimos 7 dias: %s.", "Working sets in the last 7 days: %s."},
+	"chat.volume_low":      {" Para hipertrofia, 10–20 séries semanais por grupo costumam funcionar; abaixo disso: %s.", " For hypertrophy, 10–20 weekly sets per group usually work; below that: %s."},
+	"chat.overload_none":   {"Ainda não tenho sugestões de progressão para você. Registre séries com carga, reps e RIR; quando o RIR ficar ≥ 2 com a técnica boa, suba 2,5–5%.", "I have no progression suggestions for you yet. Log sets with load, reps and RIR; when RIR stays ≥ 2 with good technique, add 2.5–5%."},
+	"chat.overload_keep":   {"Mantenha as cargas atuais até completar as reps com RIR ≥ 2; depois suba 2,5–5%.", "Keep the current loads until you complete the reps at RIR ≥ 2; then add 2.5–5%."},
+	"chat.overload":        {"Últimas sugestões de progressão: %s. Só suba se a técnica se mantiver.", "Latest progression suggestions: %s. Only go up if your technique holds."},
+	"chat.recovery":        {"Recuperação: durma 7–9h, mantenha proteína adequada e deixe 48h entre estímulos fortes do mesmo grupo.", "Recovery: sleep 7–9h, keep protein adequate and leave 48h between hard sessions for the same group."},
+	"chat.recovery_deload": {" Você treinou %d vezes recentemente; se o desempenho cair em várias sessões seguidas, considere uma semana de deload.", " You trained %d times recently; if performance drops for several sessions in a row, consider a deload week."},
+	"chat.recovery_pain":   {" Dor aguda ou persistente não é normal: procure um profissional de saúde.", " Sharp or persistent pain is not normal: see a health professional."},
+	"chat.last_session":    {" Última sessão em %s: %d séries, %.0f kg de volume.", " Last session on %s: %d sets, %.0f kg of volume."},
+	"chat.ask_more":        {" Pergunte sobre volume, progressão de carga, recuperação ou dieta.", " Ask about volume, load progression, recovery or diet."},
+	"chat.respond_in":      {"Responda em português do Brasil.", "Respond in English."},
+
+	// ===== e-mails de conta
+	"mail.verify_subject": {"Confirme seu e-mail", "Confirm your email"},
+	"mail.verify_body":    {"Olá%s,\n\nConfirme seu e-mail abrindo o link abaixo (válido por %d horas):\n\n%s\n\nSe você não criou uma conta, ignore esta mensagem.\n", "Hi%s,\n\nConfirm your email by opening the link below (valid for %d hours):\n\n%s\n\nIf you did not create an account, ignore this message.\n"},
+	"mail.reset_subject":  {"Redefinição de senha", "Password reset"},
+	"mail.reset_body":     {"Olá%s,\n\nPara redefinir sua senha, abra o link abaixo (válido por %d minutos):\n\n%s\n\nSe você não pediu a redefinição, ignore esta mensagem.\n", "Hi%s,\n\nTo reset your password, open the link below (valid for %d minutes):\n\n%s\n\nIf you did not request a reset, ignore this message.\n"},
+}
+
+// índice texto (minúsculo) -> chave, para Translate
+var byText = func() map[string]string {
+	idx := map[string]string{}
+	for k, m := range messages {
+		if !strings.HasPrefix(k, "err.") {
+			continue
+		}
+		idx[strings.ToLower(m.pt)] = k
+		idx[strings.ToLower(m.en)] = k
+	}
+	// variantes já usadas nos handlers
+	idx["json inválido"] = "err.invalid_json"
+	idx["invalid json or token missing"] = "err.invalid_json"
+	idx["validation_failed"] = "err.validation"
+	return idx
+}()
+
+type rewrite struct {
+	re  *regexp.Regexp
+	out string
+}
+
+// padrões de validação (campo + regra); o nome do campo não é traduzido.
+var toPT = []rewrite{
+	{regexp.MustCompile(`^(\S+) must be between (\S+) and (\S+)$`), "$1 deve estar entre $2 e $3"},
+	{regexp.MustCompile(`^(\S+) out of range \((.+)\)$`), "$1 fora do intervalo ($2)"},
+	{regexp.MustCompile(`^(\S+) must not be before (\S+)$`), "$1 não pode ser anterior a $2"},
+	{regexp.MustCompile(`^(\S+) must not be empty$`), "$1 não pode ser vazio"},
+	{regexp.MustCompile(`^(\S+) must be one of (.+)$`), "$1 deve ser um de $2"},
+	{regexp.MustCompile(`^(\S+) must be (\S+) or null$`), "$1 deve ser $2 ou null"},
+	{regexp.MustCompile(`^(\S+) must be (\S+)$`), "$1 deve ser $2"},
+	{regexp.MustCompile(`^(\S+) (?:is )?required$`), "$1 é obrigatório"},
+	{regexp.MustCompile(`^(\S+) not found$`), "$1 não encontrado"},
+	{regexp.MustCompile(`^missing (\S+)$`), "$1 ausente"},
+	{regexp.MustCompile(`^invalid (\S+)( \(.+\))?$`), "$1 inválido$2"},
+	{regexp.MustCompile(`^unsupported field: (.+)$`), "campo não suportado: $1"},
+	{regexp.MustCompile(`^(\S+) too long \(max (\d+)(.*)\)$`), "$1 longo demais (máx. $2$3)"},
+	{regexp.MustCompile(`^too many (\S+) \(max (\d+)\)$`), "$1 demais (máx. $2)"},
+}
+
+var toEN = []rewrite{
+	{regexp.MustCompile(`^(\S+) inválido(.*)$`), "invalid $1$2"},
+	{regexp.MustCompile(`^falha ao salvar dia (\d+)$`), "failed to save day $1"},
+	{regexp.MustCompile(`^falha ao salvar treino$`), "failed to save workout"},
+}
+
+// Translate traduz uma mensagem já escrita (pt ou en) para l: texto exato do
+// catálogo, "prefixo: detalhe" com prefixo conhecido ou padrão de validação.
+// Sem correspondência devolve o texto original.
+func Translate(l Lang, text string) string {
+	t := strings.TrimSpace(text)
+	if t == "" {
+		return text
+	}
+	if k, ok := byText[strings.ToLower(t)]; ok {
+		return T(l, k)
+	}
+	if head, rest, ok := strings.Cut(t, ": "); ok {
+		if k, ok := byText[strings.ToLower(head)]; ok {
+			return T(l, k) + ": " + rest
+		}
+		if h := rewriteText(l, head); h != head {
+			return h + ": " + rest
+		}
+	}
+	return rewriteText(l, t)
+}
+
+func rewriteText(l Lang, t string) string {
+	rules := toPT
+	if l == EN {
+		rules = toEN
+	}
+	for _, rw := range rules {
+		if rw.re.MatchString(t) {
+			return rw.re.ReplaceAllString(t, rw.out)
+		}
+	}
+	return t
+}
+
+// Label: rótulo de objetivo/nível/divisão (ex.: Label(EN, "goal", "forca") = "strength").
+func Label(l Lang, kind, value string) string {
+	v := strings.ToLower(strings.TrimSpace(value))
+	v = strings.NewReplacer("ç", "c", "ê", "e", "ã", "a", "á", "a", "é", "e", "í", "i").Replace(v)
+	if _, ok := messages[kind+"."+v]; ok {
+		return T(l, kind+"."+v)
+	}
+	return value
+}
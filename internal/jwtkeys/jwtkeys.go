@@ -0,0 +1,236 @@
+// Package jwtkeys: chaves assimétricas (RS256 / EdDSA) para assinar e validar
+// JWTs, carregadas de um diretório de PEMs, e o JWKS público correspondente.
+//
+// Cada arquivo <kid>.pem vira uma chave com kid = nome do arquivo:
+//
+//	PRIVATE KEY / RSA PRIVATE KEY -> assina e valida
+//	PUBLIC KEY                    -> só valida (chave aposentada ou de outro emissor)
+//
+// Assina com JWT_SIGNING_KID ou, sem ele, com a chave privada de maior kid
+// (nomear por data, ex. 2026-10.pem, faz a rotação seguir a ordem).
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey = errors.New("jwtkeys: unknown kid")
+	ErrSignature  = errors.New("jwtkeys: bad signature")
+)
+
+type Key struct {
+	ID      string
+	Alg     string
+	Public  crypto.PublicKey
+	private crypto.Signer // nil = só validação
+}
+
+func (k *Key) CanSign() bool { return k.private != nil }
+
+type Set struct {
+	keys    map[string]*Key
+	signing *Key
+}
+
+// LoadDir carrega todos os *.pem de dir. signingKID vazio = maior kid com chave privada.
+func LoadDir(dir, signingKID string) (*Set, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("jwtkeys: no .pem files in %s", dir)
+	}
+	var keys []*Key
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParsePEM(strings.TrimSuffix(filepath.Base(p), ".pem"), b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		keys = append(keys, k)
+	}
+	return NewSet(signingKID, keys...)
+}
+
+// NewSet monta o keyset; exige ao menos uma chave capaz de assinar.
+func NewSet(signingKID string, keys ...*Key) (*Set, error) {
+	s := &Set{keys: map[string]*Key{}}
+	var signers []*Key
+	for _, k := range keys {
+		if _, dup := s.keys[k.ID]; dup {
+			return nil, fmt.Errorf("jwtkeys: duplicate kid %q", k.ID)
+		}
+		s.keys[k.ID] = k
+		if k.CanSign() {
+			signers = append(signers, k)
+		}
+	}
+	if signingKID != "" {
+		k, ok := s.keys[signingKID]
+		if !ok || !k.CanSign() {
+			return nil, fmt.Errorf("jwtkeys: signing kid %q has no private key", signingKID)
+		}
+		s.signing = k
+		return s, nil
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("jwtkeys: no private key to sign with")
+	}
+	sort.Slice(signers, func(i, j int) bool { return signers[i].ID > signers[j].ID })
+	s.signing = signers[0]
+	return s, nil
+}
+
+// ParsePEM lê uma chave RSA (>= 2048 bits) ou Ed25519, privada ou pública.
+func ParsePEM(kid string, data []byte) (*Key, error) {
+	blk, _ := pem.Decode(data)
+	if blk == nil {
+		return nil, errors.New("jwtkeys: no PEM block")
+	}
+	var parsed any
+	var err error
+	switch blk.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(blk.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(blk.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(blk.Bytes)
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported PEM type %q", blk.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	k := &Key{ID: kid}
+	switch v := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.Alg, k.Public, k.private = RS256, &v.PublicKey, v
+	case *rsa.PublicKey:
+		k.Alg, k.Public = RS256, v
+	case ed25519.PrivateKey:
+		k.Alg, k.Public, k.private = EdDSA, v.Public(), v
+	case ed25519.PublicKey:
+		k.Alg, k.Public = EdDSA, v
+	default:
+		return nil, fmt.Errorf("jwtkeys: unsupported key type %T", parsed)
+	}
+	if pub, ok := k.Public.(*rsa.PublicKey); ok && pub.N.BitLen() < 2048 {
+		return nil, errors.New("jwtkeys: RSA key must be at least 2048 bits")
+	}
+	return k, nil
+}
+
+// SigningKey: chave usada para emitir tokens.
+func (s *Set) SigningKey() *Key { return s.signing }
+
+func (s *Set) Key(kid string) (*Key, bool) {
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+// Sign assina signingInput ("header.payload" em base64url) com a chave k.
+func (k *Key) Sign(signingInput string) ([]byte, error) {
+	if k.private == nil {
+		return nil, fmt.Errorf("jwtkeys: kid %q cannot sign", k.ID)
+	}
+	switch k.Alg {
+	case RS256:
+		h := sha256.Sum256([]byte(signingInput))
+		return k.private.Sign(rand.Reader, h[:], crypto.SHA256)
+	case EdDSA:
+		return k.private.Sign(rand.Reader, []byte(signingInput), crypto.Hash(0))
+	}
+	return nil, fmt.Errorf("jwtkeys: unsupported alg %q", k.Alg)
+}
+
+// Verify confere a assinatura; alg vem do header do token e precisa bater com a chave.
+func (s *Set) Verify(kid, alg, signingInput string, sig []byte) error {
+	k, ok := s.keys[kid]
+	if !ok {
+		return ErrUnknownKey
+	}
+	if alg != k.Alg {
+		return ErrSignature
+	}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		h := sha256.Sum256([]byte(signingInput))
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], sig) != nil {
+			return ErrSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, []byte(signingInput), sig) {
+			return ErrSignature
+		}
+	default:
+		return ErrSignature
+	}
+	return nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS: documento público com todas as chaves (RFC 7517), ordenado por kid.
+func (s *Set) JWKS() ([]byte, error) {
+	enc := base64.RawURLEncoding
+	out := struct {
+		Keys []jwk `json:"keys"`
+	}{Keys: []jwk{}}
+	if s != nil {
+		ids := make([]string, 0, len(s.keys))
+		for id := range s.keys {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			k := s.keys[id]
+			j := jwk{Use: "sig", Alg: k.Alg, Kid: k.ID}
+			switch pub := k.Public.(type) {
+			case *rsa.PublicKey:
+				j.Kty = "RSA"
+				j.N = enc.EncodeToString(pub.N.Bytes())
+				j.E = enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+			case ed25519.PublicKey:
+				j.Kty, j.Crv = "OKP", "Ed25519"
+				j.X = enc.EncodeToString(pub)
+			}
+			out.Keys = append(out.Keys, j)
+		}
+	}
+	return json.Marshal(out)
+}
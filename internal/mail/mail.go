@@ -0,0 +1,163 @@
+// Package mail: envio de e-mails transacionais (verificação de e-mail, reset de senha).
+//
+//	MAIL_DRIVER=smtp  -> SMTP (SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS)
+//	MAIL_DRIVER=file  -> grava .eml em MAIL_OUTBOX_DIR (desenvolvimento)
+//	default           -> só loga (desenvolvimento)
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Sender entrega uma mensagem.
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, m Message) error
+}
+
+// format: mensagem RFC 5322 simples (texto UTF-8).
+func format(from string, m Message, now time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Text, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// validAddr: sem quebras de linha (evita injeção de cabeçalhos).
+func validAddr(s string) error {
+	if s == "" || strings.ContainsAny(s, "\r\n") {
+		return fmt.Errorf("mail: invalid address %q", s)
+	}
+	return nil
+}
+
+// ===== SMTP
+
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+func (s *SMTPSender) Name() string { return "smtp" }
+
+func (s *SMTPSender) Send(ctx context.Context, m Message) error {
+	if err := validAddr(m.To); err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, s.From, []string{m.To}, format(s.From, m, time.Now()))
+	}()
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return fmt.Errorf("mail: smtp timeout after %s", timeout)
+	}
+}
+
+// ===== outbox em arquivo
+
+type FileOutbox struct {
+	Dir  string
+	From string
+	seq  atomic.Int64
+}
+
+func (f *FileOutbox) Name() string { return "file" }
+
+func (f *FileOutbox) Send(_ context.Context, m Message) error {
+	if err := validAddr(m.To); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%03d.eml", now.UTC().Format("20060102T150405.000"), f.seq.Add(1)%1000)
+	path := filepath.Join(f.Dir, name)
+	if err := os.WriteFile(path, format(f.From, m, now), 0o600); err != nil {
+		return err
+	}
+	log.Printf("[mail] %q -> %s (%s)", m.Subject, m.To, path)
+	return nil
+}
+
+// ===== log
+
+type LogSender struct{}
+
+func (LogSender) Name() string { return "log" }
+
+func (LogSender) Send(_ context.Context, m Message) error {
+	if err := validAddr(m.To); err != nil {
+		return err
+	}
+	log.Printf("[mail] to=%s subject=%q\n%s", m.To, m.Subject, m.Text)
+	return nil
+}
+
+// FromEnv escolhe o Sender pelo MAIL_DRIVER.
+func FromEnv() Sender {
+	from := envOr("MAIL_FROM", "Anima <no-reply@anima.local>")
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("MAIL_DRIVER"))) {
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil || port <= 0 {
+			port = 587
+		}
+		return &SMTPSender{
+			Host:     envOr("SMTP_HOST", "localhost"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASS"),
+			From:     from,
+		}
+	case "file":
+		return &FileOutbox{Dir: envOr("MAIL_OUTBOX_DIR", "tmp/outbox"), From: from}
+	}
+	return LogSender{}
+}
+
+func envOr(k, def string) string {
+	if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+		return v
+	}
+	return def
+}
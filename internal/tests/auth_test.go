@@ -5,8 +5,8 @@ import (
     "encoding/json"
     "net/http"
     "net/http/httptest"
-    "os"
     "testing"
+    "time"
 
     "anima/internal/handlers"
 )
@@ -27,59 +27,110 @@ func TestProtectedRouteRequiresAuth(t *testing.T) {
     }
 }
 
-func TestRefreshGeneratesNewToken(t *testing.T) {
+func TestRefreshRequiresRefreshToken(t *testing.T) {
     t.Setenv("JWT_SECRET", "testsecret")
-    t.Setenv("JWT_EXP_HOURS", "1")
 
-    // Issue a valid token
-    token, _, err := handlers.IssueJWTHS256("user-123", 1, os.Getenv("JWT_SECRET"))
+    // Access token não serve mais para renovar (só o refresh token opaco)
+    token, _, err := handlers.IssueAccessToken("user-123", "", time.Minute)
     if err != nil {
-        t.Fatalf("IssueJWTHS256 error: %v", err)
+        t.Fatalf("IssueAccessToken error: %v", err)
     }
-
-    // Call refresh endpoint
-    h := handlers.AuthRefresh()
     body, _ := json.Marshal(map[string]string{"token": token})
 
     req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", bytes.NewReader(body))
     req.Header.Set("Content-Type", "application/json")
     rr := httptest.NewRecorder()
-    h.ServeHTTP(rr, req)
+    handlers.AuthRefresh(nil).ServeHTTP(rr, req)
 
-    if rr.Code != http.StatusOK {
-        t.Fatalf("expected 200 from refresh, got %d: %s", rr.Code, rr.Body.String())
+    if rr.Code != http.StatusBadRequest {
+        t.Fatalf("expected 400 without refresh_token, got %d: %s", rr.Code, rr.Body.String())
     }
+}
+
+func TestAccessTokenCarriesLoginSession(t *testing.T) {
+    t.Setenv("JWT_SECRET", "testsecret")
 
-    var resp struct{
-        AccessToken string `json:"access_token"`
-        ExpiresIn   int64  `json:"expires_in"`
-        TokenType   string `json:"token_type"`
+    token, expIn, err := handlers.IssueAccessToken("user-123", "sess-1", 15*time.Minute)
+    if err != nil {
+        t.Fatalf("IssueAccessToken error: %v", err)
     }
-    if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
-        t.Fatalf("invalid json: %v", err)
+    if expIn != 900 {
+        t.Fatalf("expected expires_in 900, got %d", expIn)
     }
-    if resp.AccessToken == "" {
-        t.Fatalf("empty access_token")
+
+    protected := handlers.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+        _, _ = w.Write([]byte(handlers.GetUserID(r) + "|" + handlers.GetLoginSessionID(r)))
+    }))
+
+    req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    rr := httptest.NewRecorder()
+    protected.ServeHTTP(rr, req)
+
+    if rr.Code != http.StatusOK {
+        t.Fatalf("expected 200 with access token, got %d", rr.Code)
     }
-    if resp.AccessToken == token {
-        t.Fatalf("expected a new token different from the old one")
+    if rr.Body.String() != "user-123|sess-1" {
+        t.Fatalf("expected 'user-123|sess-1', got '%s'", rr.Body.String())
     }
+}
 
-    // Use the new token on a protected handler and assert user_id is preserved
-    protected := handlers.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+func TestRequireRoleChecksTokenRoles(t *testing.T) {
+    t.Setenv("JWT_SECRET", "testsecret")
+
+    h := handlers.RequireRole(nil, handlers.RoleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         w.WriteHeader(http.StatusOK)
-        _, _ = w.Write([]byte(handlers.GetUserID(r)))
     }))
 
-    req2 := httptest.NewRequest(http.MethodGet, "/protected", nil)
-    req2.Header.Set("Authorization", "Bearer "+resp.AccessToken)
-    rr2 := httptest.NewRecorder()
-    protected.ServeHTTP(rr2, req2)
+    rr := httptest.NewRecorder()
+    h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/admin/rules", nil))
+    if rr.Code != http.StatusUnauthorized {
+        t.Fatalf("expected 401 without token, got %d", rr.Code)
+    }
+
+    // token de atleta: nem chega a consultar o banco
+    token, _, err := handlers.IssueAccessToken("user-123", "", time.Minute, handlers.RoleAthlete, handlers.RoleCoach)
+    if err != nil {
+        t.Fatalf("IssueAccessToken error: %v", err)
+    }
+    req := httptest.NewRequest(http.MethodGet, "/api/admin/rules", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    rr = httptest.NewRecorder()
+    h.ServeHTTP(rr, req)
+    if rr.Code != http.StatusForbidden {
+        t.Fatalf("expected 403 without admin role, got %d", rr.Code)
+    }
+}
 
-    if rr2.Code != http.StatusOK {
-        t.Fatalf("expected 200 with new token, got %d", rr2.Code)
+func TestPersonalAccessTokenBearer(t *testing.T) {
+    t.Setenv("JWT_SECRET", "testsecret")
+
+    ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+        _, _ = w.Write([]byte(handlers.AuthKind(r)))
+    })
+    chain := handlers.OptionalAuth(handlers.EnforceScopes(handlers.RequireAuth(ok)))
+
+    // PAT desconhecido (sem banco para resolver) não autentica
+    req := httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+    req.Header.Set("Authorization", "Bearer anima_pat_naoexiste")
+    rr := httptest.NewRecorder()
+    chain.ServeHTTP(rr, req)
+    if rr.Code != http.StatusUnauthorized {
+        t.Fatalf("expected 401 for unknown PAT, got %d", rr.Code)
+    }
+
+    // JWT não passa pelo filtro de escopos
+    token, _, err := handlers.IssueAccessToken("user-123", "", time.Minute)
+    if err != nil {
+        t.Fatalf("IssueAccessToken error: %v", err)
     }
-    if rr2.Body.String() != "user-123" {
-        t.Fatalf("expected user_id 'user-123', got '%s'", rr2.Body.String())
+    req = httptest.NewRequest(http.MethodPost, "/api/me/tokens", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    rr = httptest.NewRecorder()
+    chain.ServeHTTP(rr, req)
+    if rr.Code != http.StatusOK || rr.Body.String() != "jwt" {
+        t.Fatalf("expected 200 jwt, got %d %q", rr.Code, rr.Body.String())
     }
 }
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"anima/internal/ai"
+)
+
+func TestChatProviderUsesCompletion(t *testing.T) {
+	var got struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" Treine pesado. "}}]}`))
+	}))
+	defer srv.Close()
+
+	p := &ai.ChatProvider{BaseURL: srv.URL, APIKey: "k", Model: "stub-1", Timeout: time.Second, Fallback: ai.Heuristic{}}
+	res, err := p.CoachNotes(context.Background(), ai.CoachInput{Goal: "hipertrofia", Exercises: []string{"Supino"}})
+	if err != nil {
+		t.Fatalf("CoachNotes error: %v", err)
+	}
+	if res.Notes != "Treine pesado." || res.Fallback || res.Model != "stub-1" || res.PromptVersion != ai.PromptVersion {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if got.Model != "stub-1" || len(got.Messages) != 2 || !strings.Contains(got.Messages[1].Content, "Supino") {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+}
+
+func TestChatProviderFallsBackOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	p := &ai.ChatProvider{BaseURL: srv.URL, Model: "stub-1", Timeout: 50 * time.Millisecond, Fallback: ai.Heuristic{}}
+	res, err := p.CoachNotes(context.Background(), ai.CoachInput{Goal: "hipertrofia"})
+	if err != nil {
+		t.Fatalf("CoachNotes error: %v", err)
+	}
+	if !res.Fallback || res.Provider != "heuristic" || res.Error == "" || !strings.Contains(res.Notes, "hipertrofia") {
+		t.Fatalf("expected heuristic fallback, got %+v", res)
+	}
+}
+
+func TestChatProviderReplySendsContextAndHistory(t *testing.T) {
+	var got struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Suba 2,5 kg."}}],"usage":{"prompt_tokens":120,"completion_tokens":6}}`))
+	}))
+	defer srv.Close()
+
+	p := &ai.ChatProvider{BaseURL: srv.URL, Model: "stub-1", Timeout: time.Second, Fallback: ai.Heuristic{}}
+	res, err := p.Reply(context.Background(), ai.ChatInput{
+		Context: ai.ChatContext{
+			Profile:    ai.CoachInput{Goal: "forca"},
+			WeeklySets: map[string]int{"Peito": 12},
+		},
+		History: []ai.Message{{Role: "user", Content: "oi"}, {Role: "assistant", Content: "olá"}},
+		Message: "posso aumentar a carga?",
+	})
+	if err != nil {
+		t.Fatalf("Reply error: %v", err)
+	}
+	if res.Content != "Suba 2,5 kg." || res.Fallback || res.TokensIn != 120 || res.TokensOut != 6 {
+		t.Fatalf("unexpected reply: %+v", res)
+	}
+	if len(got.Messages) != 4 || got.Messages[0].Role != "system" || !strings.Contains(got.Messages[0].Content, "Peito=12") ||
+		got.Messages[3].Content != "posso aumentar a carga?" {
+		t.Fatalf("unexpected request: %+v", got.Messages)
+	}
+}
+
+func TestHeuristicReplyWithoutProvider(t *testing.T) {
+	res, err := ai.Heuristic{}.Reply(context.Background(), ai.ChatInput{
+		Context: ai.ChatContext{WeeklySets: map[string]int{"Costas": 6}},
+		Message: "Quantas séries estou fazendo?",
+	})
+	if err != nil {
+		t.Fatalf("Reply error: %v", err)
+	}
+	if res.Provider != "heuristic" || !strings.Contains(res.Content, "Costas 6") || res.TokensOut == 0 {
+		t.Fatalf("unexpected reply: %+v", res)
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"anima/internal/handlers"
+)
+
+// ledger em memória no lugar de coach_messages; mu faz o papel do advisory lock
+type coachLedger struct {
+	mu       sync.Mutex
+	messages int
+	tokens   int
+	limits   handlers.CoachUsage
+}
+
+func (l *coachLedger) admit(tokens int) (string, error) {
+	_, msg, err := handlers.AdmitCoachTurn(
+		func() (func(bool) error, error) {
+			l.mu.Lock()
+			return func(bool) error { l.mu.Unlock(); return nil }, nil
+		},
+		func() (handlers.CoachUsage, error) {
+			u := l.limits
+			u.MessagesToday, u.TokensToday = l.messages, l.tokens
+			return u, nil
+		},
+		func() error {
+			l.messages++
+			l.tokens += tokens
+			return nil
+		},
+	)
+	return msg, err
+}
+
+func runParallel(l *coachLedger, n, tokens int) (admitted, rejected int) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg, err := l.admit(tokens)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+			case msg == "":
+				admitted++
+			default:
+				rejected++
+			}
+		}()
+	}
+	wg.Wait()
+	return admitted, rejected
+}
+
+func TestCoachQuotaParallelMessages(t *testing.T) {
+	l := &coachLedger{limits: handlers.CoachUsage{MessagesLimit: 5}}
+	admitted, rejected := runParallel(l, 40, 100)
+	if admitted != 5 || rejected != 35 || l.messages != 5 {
+		t.Errorf("admitted %d rejected %d stored %d, want 5 35 5", admitted, rejected, l.messages)
+	}
+}
+
+func TestCoachQuotaParallelTokens(t *testing.T) {
+	// cada pergunta reserva 600 tokens: a 4ª já encontra 1800 ≥ 1500
+	l := &coachLedger{limits: handlers.CoachUsage{TokensLimit: 1500}}
+	admitted, _ := runParallel(l, 20, 600)
+	if admitted != 3 || l.tokens != 1800 {
+		t.Errorf("admitted %d tokens %d, want 3 1800", admitted, l.tokens)
+	}
+}
+
+func TestCoachQuotaReserveError(t *testing.T) {
+	released := []bool{}
+	_, msg, err := handlers.AdmitCoachTurn(
+		func() (func(bool) error, error) {
+			return func(commit bool) error { released = append(released, commit); return nil }, nil
+		},
+		func() (handlers.CoachUsage, error) { return handlers.CoachUsage{MessagesLimit: 5}, nil },
+		func() error { return errors.New("insert failed") },
+	)
+	if err == nil || msg != "" || len(released) != 1 || released[0] {
+		t.Errorf("err %v msg %q released %v, want error and rollback", err, msg, released)
+	}
+}
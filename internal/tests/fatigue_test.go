@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"anima/internal/handlers"
+	"anima/internal/i18n"
+)
+
+func TestTrailingDrops(t *testing.T) {
+	cases := []struct {
+		vals []float64
+		want int
+	}{
+		{nil, 0},
+		{[]float64{100}, 0},
+		{[]float64{100, 98, 96}, 2},
+		{[]float64{100, 99.5, 99}, 0}, // quedas < 1%
+		{[]float64{100, 105, 103, 101}, 2},
+		{[]float64{90, 100, 95}, 1},
+		{[]float64{100, 95, 90, 92}, 0}, // a última sessão subiu
+	}
+	for _, c := range cases {
+		if got := handlers.TrailingDrops(c.vals, 0.01); got != c.want {
+			t.Errorf("TrailingDrops(%v) = %d, want %d", c.vals, got, c.want)
+		}
+	}
+}
+
+func TestEvalFatigue(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	flat := []float64{1000, 1000, 1000, 1000, 1000, 1000}
+	cases := []struct {
+		name    string
+		data    handlers.FatigueData
+		score   int
+		deload  bool
+		kinds   []string
+		summary string
+	}{
+		{"sem dados", handlers.FatigueData{}, 0, false, []string{},
+			"sem sinais de fadiga relevantes"},
+		{"e1RM caindo em um exercício",
+			handlers.FatigueData{E1RM: map[int64][]float64{7: {110, 112, 108, 104}}, Order: []int64{7}},
+			1, false, []string{"e1rm_decline"},
+			"sinal isolado de fadiga: mantenha a carga e monitore sono/recuperação"},
+		{"e1RM caindo em dois exercícios",
+			handlers.FatigueData{E1RM: map[int64][]float64{7: {112, 108, 104}, 9: {80, 78, 75}, 11: {60, 61, 62}}, Order: []int64{7, 9, 11}},
+			1, true, []string{"e1rm_decline", "e1rm_decline"},
+			"fadiga acumulada: faça uma semana de deload (~85% da carga, metade das séries)"},
+		{"RIR desabando",
+			handlers.FatigueData{RecentRIR: f(0.5), RecentN: 8, BaseRIR: f(2), BaseN: 10},
+			1, false, []string{"rir_collapse"},
+			"sinal isolado de fadiga: mantenha a carga e monitore sono/recuperação"},
+		{"RIR baixo com poucas séries",
+			handlers.FatigueData{RecentRIR: f(0.5), RecentN: 5, BaseRIR: f(2), BaseN: 10},
+			0, false, []string{}, "sem sinais de fadiga relevantes"},
+		{"RIR já era baixo",
+			handlers.FatigueData{RecentRIR: f(0.5), RecentN: 8, BaseRIR: f(1.2), BaseN: 10},
+			0, false, []string{}, "sem sinais de fadiga relevantes"},
+		{"RPE subindo com tonelagem igual",
+			handlers.FatigueData{RPE: []float64{6, 6, 7, 8, 8, 8.5}, Tonnage: flat},
+			1, false, []string{"rpe_rise"},
+			"sinal isolado de fadiga: mantenha a carga e monitore sono/recuperação"},
+		{"RPE subindo com mais tonelagem",
+			handlers.FatigueData{RPE: []float64{6, 6, 7, 8, 8, 8.5}, Tonnage: []float64{1000, 1000, 1000, 1200, 1200, 1200}},
+			0, false, []string{}, "sem sinais de fadiga relevantes"},
+		{"RIR e RPE juntos",
+			handlers.FatigueData{RecentRIR: f(0.5), RecentN: 8, BaseRIR: f(2), BaseN: 10,
+				RPE: []float64{6, 6, 6, 8, 8, 8}, Tonnage: flat},
+			2, true, []string{"rir_collapse", "rpe_rise"},
+			"fadiga acumulada: faça uma semana de deload (~85% da carga, metade das séries)"},
+	}
+	for _, c := range cases {
+		rep := handlers.EvalFatigue(i18n.PT, c.data)
+		kinds := []string{}
+		for _, s := range rep.Signals {
+			kinds = append(kinds, s.Kind)
+		}
+		if rep.Score != c.score || rep.RecommendDeload != c.deload || !reflect.DeepEqual(kinds, c.kinds) {
+			t.Errorf("%s: score %d deload %v signals %v; want %d %v %v", c.name,
+				rep.Score, rep.RecommendDeload, kinds, c.score, c.deload, c.kinds)
+		}
+		if rep.Recommendation != c.summary {
+			t.Errorf("%s: recommendation %q, want %q", c.name, rep.Recommendation, c.summary)
+		}
+	}
+}
+
+func TestEvalFatigueSignalDetail(t *testing.T) {
+	rep := handlers.EvalFatigue(i18n.EN, handlers.FatigueData{
+		E1RM:  map[int64][]float64{7: {110, 112, 108, 104}},
+		Order: []int64{7},
+	})
+	if len(rep.Signals) != 1 {
+		t.Fatalf("signals %v", rep.Signals)
+	}
+	s := rep.Signals[0]
+	if s.ExercicioID == nil || *s.ExercicioID != 7 || !reflect.DeepEqual(s.Values, []float64{112, 108, 104}) {
+		t.Errorf("signal %+v", s)
+	}
+	if s.Detail != "e1RM dropped for 2 sessions in a row" {
+		t.Errorf("detail %q", s.Detail)
+	}
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"anima/internal/handlers"
+)
+
+func TestForecastGoal(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 0, 0, 0, time.UTC)
+	s := func(v string) *string { return &v }
+	weekly := []string{"2026-09-21", "2026-09-28", "2026-10-05", "2026-10-12", "2026-10-19"}
+	cases := []struct {
+		name     string
+		kind     string
+		target   float64
+		deadline *string
+		days     []string
+		vals     []float64
+
+		status   string
+		rate     float64 // só conferido com ≥3 pontos
+		forecast string
+		current  float64
+		progress float64
+		required float64 // 0 = ausente
+	}{
+		{"força no ritmo, sem prazo", "strength", 120, nil, weekly, []float64{100, 102, 104, 106, 108},
+			"on_track", 2, "2026-11-30", 108, 40, 0},
+		{"força com prazo curto", "strength", 120, s("2026-11-15"), weekly, []float64{100, 102, 104, 106, 108},
+			"off_track", 2, "2026-11-30", 108, 40, 3.11},
+		{"força com prazo folgado", "strength", 120, s("2026-12-31"), weekly, []float64{100, 102, 104, 106, 108},
+			"on_track", 2, "2026-11-30", 108, 40, 1.15},
+		{"força já atingida", "strength", 105, nil, weekly, []float64{100, 102, 104, 106, 108},
+			"achieved", 0, "", 108, 100, 0},
+		{"peso caindo até o alvo", "bodyweight", 75, nil, weekly[1:], []float64{80, 79.5, 79, 78.5},
+			"on_track", -0.5, "2026-12-07", 78.5, 30, 0},
+		{"peso na direção errada", "bodyweight", 75, nil, weekly[2:], []float64{80, 80.5, 81},
+			"off_track", 0.5, "", 81, 0, 0},
+		{"poucos pontos", "strength", 120, nil, weekly[3:], []float64{100, 102},
+			"insufficient_data", 0, "", 102, 10, 0},
+		{"pontos em menos de 7 dias", "bodyweight", 75, nil, []string{"2026-10-15", "2026-10-17", "2026-10-19"}, []float64{80, 79.8, 79.6},
+			"insufficient_data", 0, "", 79.6, 8, 0},
+	}
+	for _, c := range cases {
+		g := &handlers.UserGoal{Kind: c.kind, TargetValue: c.target, Deadline: c.deadline}
+		handlers.ForecastGoal(g, c.days, c.vals, now)
+		f := g.Forecast
+		if f.Status != c.status || f.Points != len(c.days) {
+			t.Errorf("%s: status %s (%d points), want %s", c.name, f.Status, f.Points, c.status)
+		}
+		if g.CurrentValue == nil || *g.CurrentValue != c.current {
+			t.Errorf("%s: current %v, want %.1f", c.name, g.CurrentValue, c.current)
+		}
+		if g.ProgressPct == nil || *g.ProgressPct != c.progress {
+			t.Errorf("%s: progress %v, want %.1f", c.name, g.ProgressPct, c.progress)
+		}
+		if c.rate != 0 && (f.RatePerWeek == nil || *f.RatePerWeek != c.rate) {
+			t.Errorf("%s: rate %v, want %.2f", c.name, f.RatePerWeek, c.rate)
+		}
+		got := ""
+		if f.ForecastDate != nil {
+			got = *f.ForecastDate
+		}
+		if got != c.forecast {
+			t.Errorf("%s: forecast date %q, want %q", c.name, got, c.forecast)
+		}
+		req := 0.0
+		if f.RequiredPerWk != nil {
+			req = *f.RequiredPerWk
+		}
+		if req != c.required {
+			t.Errorf("%s: required/week %.2f, want %.2f", c.name, req, c.required)
+		}
+	}
+}
+
+func TestForecastGoalStrengthUsesRecentBest(t *testing.T) {
+	// e1RM antigo (fora das últimas 4 semanas) não conta como valor atual
+	g := &handlers.UserGoal{Kind: "strength", TargetValue: 140}
+	handlers.ForecastGoal(g,
+		[]string{"2026-09-01", "2026-09-28", "2026-10-12", "2026-10-19"},
+		[]float64{130, 100, 104, 102},
+		time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC))
+	if g.CurrentValue == nil || *g.CurrentValue != 104 {
+		t.Errorf("current %v, want 104", g.CurrentValue)
+	}
+	if g.StartValue == nil || *g.StartValue != 130 {
+		t.Errorf("start %v, want 130", g.StartValue)
+	}
+	if g.Forecast.Status != "off_track" {
+		t.Errorf("status %s, want off_track", g.Forecast.Status)
+	}
+}
@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"testing"
+
+	"anima/internal/i18n"
+)
+
+func TestFromAcceptLanguage(t *testing.T) {
+	cases := map[string]i18n.Lang{
+		"en-US,en;q=0.9":          i18n.EN,
+		"pt-BR,pt;q=0.9,en;q=0.8": i18n.PT,
+		"fr-FR,en;q=0.5,pt;q=0.7": i18n.PT,
+		"de, en-GB;q=0.3":         i18n.EN,
+	}
+	for h, want := range cases {
+		if got, ok := i18n.FromAcceptLanguage(h); !ok || got != want {
+			t.Errorf("FromAcceptLanguage(%q) = %q, %v; want %q", h, got, ok, want)
+		}
+	}
+	if _, ok := i18n.FromAcceptLanguage("fr, de;q=0.5"); ok {
+		t.Errorf("expected no supported language")
+	}
+}
+
+func TestTranslateKnownMessages(t *testing.T) {
+	cases := []struct {
+		lang     i18n.Lang
+		in, want string
+	}{
+		{i18n.EN, "json inválido", "invalid JSON"},
+		{i18n.PT, "method not allowed", "método não permitido"},
+		{i18n.EN, "falha ao montar plano: timeout", "failed to build plan: timeout"},
+		{i18n.PT, "pain must be between 0 and 10", "pain deve estar entre 0 e 10"},
+		{i18n.PT, "waist_cm must be between 0 and 300", "waist_cm deve estar entre 0 e 300"},
+		{i18n.EN, "measured_at inválido (YYYY-MM-DD)", "invalid measured_at (YYYY-MM-DD)"},
+		{i18n.PT, "something unexpected", "something unexpected"},
+	}
+	for _, c := range cases {
+		if got := i18n.Translate(c.lang, c.in); got != c.want {
+			t.Errorf("Translate(%s, %q) = %q; want %q", c.lang, c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"anima/internal/handlers"
+	"anima/internal/jwtkeys"
+)
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// keyDir: 2026-01 (RSA, aposentada: só pública) e 2026-02 (Ed25519, assina).
+func keyDir(t *testing.T) (string, *rsa.PrivateKey) {
+	dir := t.TempDir()
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, _ := x509.MarshalPKIXPublicKey(&rk.PublicKey)
+	writePEM(t, filepath.Join(dir, "2026-01.pem"), "PUBLIC KEY", pub)
+	_, ek, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(ek)
+	writePEM(t, filepath.Join(dir, "2026-02.pem"), "PRIVATE KEY", der)
+	return dir, rk
+}
+
+func authStatus(token string) int {
+	h := handlers.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestAsymmetricJWTAndJWKS(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	dir, _ := keyDir(t)
+	ks, err := jwtkeys.LoadDir(dir, "")
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	handlers.SetJWTKeys(ks)
+	defer handlers.SetJWTKeys(nil)
+
+	token, _, err := handlers.IssueAccessToken("user-1", "", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+	hb, _ := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	var header map[string]string
+	_ = json.Unmarshal(hb, &header)
+	if header["alg"] != "EdDSA" || header["kid"] != "2026-02" {
+		t.Fatalf("unexpected header %v", header)
+	}
+	if code := authStatus(token); code != http.StatusOK {
+		t.Fatalf("EdDSA token: expected 200, got %d", code)
+	}
+
+	// HS256 só com a flag de migração
+	legacy, _, _ := handlers.IssueJWTHS256("user-1", 1, "testsecret")
+	if code := authStatus(legacy); code != http.StatusUnauthorized {
+		t.Fatalf("HS256 without flag: expected 401, got %d", code)
+	}
+	t.Setenv("JWT_ACCEPT_HS256", "true")
+	if code := authStatus(legacy); code != http.StatusOK {
+		t.Fatalf("HS256 with flag: expected 200, got %d", code)
+	}
+
+	// aud de outro serviço
+	t.Setenv("JWT_AUDIENCE", "other-api")
+	if code := authStatus(token); code != http.StatusUnauthorized {
+		t.Fatalf("wrong aud: expected 401, got %d", code)
+	}
+
+	rr := httptest.NewRecorder()
+	handlers.JWKS().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	var doc struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("jwks json: %v", err)
+	}
+	if len(doc.Keys) != 2 || doc.Keys[0]["kid"] != "2026-01" || doc.Keys[0]["kty"] != "RSA" ||
+		doc.Keys[1]["kty"] != "OKP" || doc.Keys[1]["crv"] != "Ed25519" {
+		t.Fatalf("unexpected jwks: %s", rr.Body.String())
+	}
+	for _, k := range doc.Keys {
+		if k["d"] != "" {
+			t.Fatalf("jwks leaks private material: %v", k)
+		}
+	}
+}
+
+func TestRetiredKeyStillVerifies(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	dir, rk := keyDir(t)
+	// emite com a chave antiga (RSA) como se ainda fosse a de assinatura
+	der := x509.MarshalPKCS1PrivateKey(rk)
+	old := t.TempDir()
+	writePEM(t, filepath.Join(old, "2026-01.pem"), "RSA PRIVATE KEY", der)
+	oldSet, err := jwtkeys.LoadDir(old, "")
+	if err != nil {
+		t.Fatalf("LoadDir old: %v", err)
+	}
+	handlers.SetJWTKeys(oldSet)
+	token, _, err := handlers.IssueAccessToken("user-2", "", time.Minute)
+	if err != nil {
+		t.Fatalf("IssueAccessToken RS256: %v", err)
+	}
+
+	// rotação: agora assina 2026-02, mas 2026-01 (pública) segue válida
+	ks, err := jwtkeys.LoadDir(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers.SetJWTKeys(ks)
+	defer handlers.SetJWTKeys(nil)
+	if code := authStatus(token); code != http.StatusOK {
+		t.Fatalf("RS256 token after rotation: expected 200, got %d", code)
+	}
+}
+
+// hs256Token assina claims arbitrárias (para montar tokens que o emissor não gera).
+func hs256Token(secret string, claims map[string]any) string {
+	enc := base64.RawURLEncoding
+	hb, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	pb, _ := json.Marshal(claims)
+	seg := enc.EncodeToString(hb) + "." + enc.EncodeToString(pb)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(seg))
+	return seg + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWTRequiresExp(t *testing.T) {
+	t.Setenv("JWT_SECRET", "testsecret")
+	now := time.Now().Unix()
+	base := func() map[string]any {
+		return map[string]any{"sub": "user-1", "iss": "anima", "aud": "anima-api", "iat": now, "nbf": now}
+	}
+
+	withExp := base()
+	withExp["exp"] = now + 60
+	if code := authStatus(hs256Token("testsecret", withExp)); code != http.StatusOK {
+		t.Fatalf("token with exp: expected 200, got %d", code)
+	}
+
+	if code := authStatus(hs256Token("testsecret", base())); code != http.StatusUnauthorized {
+		t.Fatalf("token without exp: expected 401, got %d", code)
+	}
+
+	expired := base()
+	expired["exp"] = now - 60
+	if code := authStatus(hs256Token("testsecret", expired)); code != http.StatusUnauthorized {
+		t.Fatalf("expired token: expected 401, got %d", code)
+	}
+}
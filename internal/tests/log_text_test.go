@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"anima/internal/handlers"
+	"anima/internal/i18n"
+)
+
+func TestParseSetLine(t *testing.T) {
+	cases := []struct {
+		text     string
+		query    string
+		weight   float64 // 0 = sem carga
+		reps     []int
+		rir      int // -1 = sem RIR
+		warnings int
+	}{
+		{"supino reto 3x10 60kg rir 2", "supino reto", 60, []int{10, 10, 10}, 2, 0},
+		{"agachamento 100kg x 5, 5, 4", "agachamento", 100, []int{5, 5, 4}, -1, 0},
+		{"bench press 3 sets of 8 @ 135 lbs rpe 8", "bench press", 61.25, []int{8, 8, 8}, 2, 0},
+		{"Remada Curvada 4 séries de 12 com 50 kg RIR: 1", "remada curvada", 50, []int{12, 12, 12, 12}, 1, 0},
+		{"rosca direta 20 lb x 12/10", "rosca direta", 9, []int{12, 10}, -1, 0},
+		{"desenvolvimento 3 x 8 rpe 9.5", "desenvolvimento", 0, []int{8, 8, 8}, 1, 0}, // RIR 0.5 arredonda p/ 1
+		{"terra 5x5 @ 140", "terra", 140, []int{5, 5, 5, 5, 5}, -1, 0},
+		{"leg press 12 x 10 200kg", "leg press", 200, repeat(10, 12), -1, 0},
+		// ambíguos: sem unidade, número alto é carga (com aviso)
+		{"leg press 12 x 10", "leg press", 12, []int{10}, -1, 1},
+		{"agachamento 100 x 5, 5, 4", "agachamento", 100, []int{5, 5, 4}, -1, 1},
+		{"leg press 4 x 10", "leg press", 0, []int{10, 10, 10, 10}, -1, 0},
+	}
+	for _, c := range cases {
+		p := handlers.ParseSetLine(i18n.PT, c.text)
+		if p.Status != "parsed" || p.Invalid != "" {
+			t.Errorf("%q: status %s invalid %q", c.text, p.Status, p.Invalid)
+			continue
+		}
+		if p.Query != c.query {
+			t.Errorf("%q: query %q, want %q", c.text, p.Query, c.query)
+		}
+		var w float64
+		if p.WeightKg != nil {
+			w = *p.WeightKg
+		}
+		if w != c.weight {
+			t.Errorf("%q: weight %.2f, want %.2f", c.text, w, c.weight)
+		}
+		if !reflect.DeepEqual(p.Reps, c.reps) {
+			t.Errorf("%q: reps %v, want %v", c.text, p.Reps, c.reps)
+		}
+		rir := -1
+		if p.RIR != nil {
+			rir = *p.RIR
+		}
+		if rir != c.rir {
+			t.Errorf("%q: rir %d, want %d", c.text, rir, c.rir)
+		}
+		if len(p.Warnings) != c.warnings {
+			t.Errorf("%q: warnings %v, want %d", c.text, p.Warnings, c.warnings)
+		}
+	}
+}
+
+func TestParseSetLineRejects(t *testing.T) {
+	cases := []struct {
+		text    string
+		status  string
+		invalid string
+	}{
+		{"supino reto 3x10 60kg rir 99", "parsed", "rir must be between 0 and 10"},
+		{"supino reto 3x10 60kg rpe 12", "parsed", "rpe must be between 1 and 10"},
+		{"supino reto 3x10 60kg rir 10", "parsed", ""},
+		{"supino reto 60kg", "unparsed", ""},
+		{"3x10 60kg", "unparsed", ""},
+		{"supino 13x10 60kg", "unparsed", ""}, // acima do máximo de séries
+		{"supino 3x10, 8 60kg", "parsed", ""},
+	}
+	for _, c := range cases {
+		p := handlers.ParseSetLine(i18n.PT, c.text)
+		if p.Status != c.status || p.Invalid != c.invalid {
+			t.Errorf("%q: status %s invalid %q, want %s %q", c.text, p.Status, p.Invalid, c.status, c.invalid)
+		}
+	}
+
+	p := handlers.ParseSetLine(i18n.EN, "leg press 12 x 10")
+	if want := []string{"load without unit: 12.0 taken as kg"}; !reflect.DeepEqual(p.Warnings, want) {
+		t.Errorf("warnings %v, want %v", p.Warnings, want)
+	}
+}
+
+func TestResolveExercise(t *testing.T) {
+	items := []handlers.ExerciseItem{
+		{ID: 1, Nome: "Supino reto com barra", Grupo: "peito"},
+		{ID: 2, Nome: "Supino inclinado com halteres", Grupo: "peito"},
+		{ID: 3, Nome: "Agachamento livre", Grupo: "pernas"},
+		{ID: 4, Nome: "Leg press 45", Grupo: "pernas"},
+		{ID: 5, Nome: "Rosca direta", Grupo: "biceps"},
+		{ID: 6, Nome: "Rosca martelo", Grupo: "biceps"},
+	}
+	aliases := map[int64][]string{
+		1: {"bench press"},
+		3: {"back squat"},
+	}
+	cases := []struct {
+		query string
+		want  int64   // 0 = não resolvido
+		cands []int64 // candidatos quando não resolvido
+	}{
+		{"Agachamento Livre", 3, nil},
+		{"bench press", 1, nil},
+		{"Back-Squat", 3, nil},
+		{"supino reto", 1, nil},
+		{"supino halter", 2, nil},
+		{"agachamento", 3, nil},
+		{"leg press", 4, nil},
+		{"rosca", 0, []int64{5, 6}},
+		{"supino", 0, []int64{1, 2}},
+		{"remada curvada", 0, []int64{}},
+	}
+	for _, c := range cases {
+		it, cands := handlers.ResolveExercise(items, aliases, c.query)
+		if c.want != 0 {
+			if it == nil || it.ID != c.want {
+				t.Errorf("%q: got %v (cands %v), want %d", c.query, it, cands, c.want)
+			}
+			continue
+		}
+		if it != nil {
+			t.Errorf("%q: resolved to %d, want ambiguous", c.query, it.ID)
+			continue
+		}
+		ids := []int64{}
+		for _, x := range cands {
+			ids = append(ids, x.ID)
+		}
+		if !reflect.DeepEqual(ids, c.cands) {
+			t.Errorf("%q: candidates %v, want %v", c.query, ids, c.cands)
+		}
+	}
+}
+
+func repeat(v, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"anima/internal/handlers"
+)
+
+var throttleCfg = handlers.LoginThrottle{
+	BackoffAfter: 3,
+	BackoffBase:  time.Second,
+	BackoffMax:   5 * time.Minute,
+	LockAt:       10,
+	LockFor:      30 * time.Minute,
+	Window:       15 * time.Minute,
+}
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{11, 256 * time.Second},
+		{12, 5 * time.Minute}, // 512s limitado ao máximo
+		{100, 5 * time.Minute},
+	}
+	for _, c := range cases {
+		if got := throttleCfg.Backoff(c.failures); got != c.want {
+			t.Errorf("Backoff(%d) = %v, want %v", c.failures, got, c.want)
+		}
+	}
+}
+
+func TestLoginThrottleRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		failures int
+		last     time.Time
+		locked   time.Time
+		want     time.Duration
+	}{
+		{"sem falhas", 0, time.Unix(0, 0), time.Time{}, 0},
+		{"abaixo do backoff", 2, now, time.Time{}, 0},
+		{"backoff em curso", 4, now.Add(-500 * time.Millisecond), time.Time{}, 1500 * time.Millisecond},
+		{"backoff cumprido", 4, now.Add(-3 * time.Second), time.Time{}, 0},
+		{"fora da janela", 9, now.Add(-16 * time.Minute), time.Time{}, 0},
+		{"bloqueada", 10, now.Add(-time.Minute), now.Add(29 * time.Minute), 29 * time.Minute},
+		{"bloqueio vencido", 10, now.Add(-31 * time.Minute), now.Add(-time.Minute), 0},
+	}
+	for _, c := range cases {
+		if got := throttleCfg.RetryAfter(c.failures, c.last, c.locked, now); got != c.want {
+			t.Errorf("%s: RetryAfter = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestLoginThrottleStep(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name       string
+		failures   int
+		last       time.Time
+		locked     time.Time
+		want       int
+		wantLocked bool
+	}{
+		{"primeira", 0, time.Unix(0, 0), time.Time{}, 1, false},
+		{"soma na janela", 4, now.Add(-time.Minute), time.Time{}, 5, false},
+		{"recomeça fora da janela", 8, now.Add(-20 * time.Minute), time.Time{}, 1, false},
+		{"atinge o limite", 9, now.Add(-time.Minute), time.Time{}, 10, true},
+		{"bloqueio vigente não zera", 10, now.Add(-20 * time.Minute), now.Add(time.Minute), 11, true},
+	}
+	for _, c := range cases {
+		n, until := throttleCfg.Step(c.failures, c.last, c.locked, now)
+		if n != c.want || !until.IsZero() != c.wantLocked {
+			t.Errorf("%s: Step = (%d, %v), want (%d, locked=%v)", c.name, n, until, c.want, c.wantLocked)
+		}
+		if c.wantLocked && !until.Equal(now.Add(throttleCfg.LockFor)) {
+			t.Errorf("%s: locked until %v, want %v", c.name, until, now.Add(throttleCfg.LockFor))
+		}
+	}
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	cases := []struct {
+		name    string
+		trusted string
+		remote  string
+		xff     string
+		want    string
+	}{
+		{"sem proxy confiável ignora XFF", "", "203.0.113.7:5000", "1.2.3.4", "203.0.113.7"},
+		{"peer fora da lista ignora XFF", "10.0.0.0/8", "203.0.113.7:5000", "1.2.3.4", "203.0.113.7"},
+		{"proxy confiável", "10.0.0.0/8", "10.0.0.2:5000", "198.51.100.9", "198.51.100.9"},
+		{"forjado à esquerda", "10.0.0.0/8", "10.0.0.2:5000", "1.2.3.4, 198.51.100.9", "198.51.100.9"},
+		{"cadeia de proxies", "10.0.0.0/8,192.0.2.1", "10.0.0.2:5000", "198.51.100.9, 192.0.2.1", "198.51.100.9"},
+		{"XFF inválido", "10.0.0.2", "10.0.0.2:5000", "lixo", "10.0.0.2"},
+	}
+	for _, c := range cases {
+		t.Setenv("TRUSTED_PROXIES", c.trusted)
+		req := httptest.NewRequest("POST", "/api/auth/login", nil)
+		req.RemoteAddr = c.remote
+		if c.xff != "" {
+			req.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if got := handlers.ClientIP(req); got != c.want {
+			t.Errorf("%s: ClientIP = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
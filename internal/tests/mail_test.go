@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"anima/internal/mail"
+)
+
+func TestFileOutboxWritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	s := &mail.FileOutbox{Dir: dir, From: "Anima <no-reply@anima.local>"}
+	err := s.Send(context.Background(), mail.Message{To: "ana@example.com", Subject: "Confirme seu e-mail", Text: "link:\nhttp://x/verify-email?token=abc"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("want 1 .eml, got %d", len(files))
+	}
+	b, _ := os.ReadFile(files[0])
+	for _, want := range []string{"To: ana@example.com\r\n", "Subject: Confirme seu e-mail\r\n", "token=abc"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("missing %q in:\n%s", want, b)
+		}
+	}
+
+	if err := s.Send(context.Background(), mail.Message{To: "a@b.com\r\nBcc: x@y.com", Subject: "x"}); err == nil {
+		t.Fatal("header injection in To should be rejected")
+	}
+}
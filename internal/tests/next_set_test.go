@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"testing"
+
+	"anima/internal/handlers"
+	"anima/internal/i18n"
+)
+
+func fp(v float64) *float64 { return &v }
+func ip(v int) *int         { return &v }
+
+func TestNextSetRIRDeviation(t *testing.T) {
+	plan := handlers.SetPlan{Series: 3, RepsLo: 8, RepsHi: 12, TargetRIR: 2}
+	cases := []struct {
+		name       string
+		pos        int
+		weight     float64
+		reps, rir  int
+		action     string
+		wantWeight float64
+		wantReps   int
+		rationale  string
+	}{
+		{"no alvo", 1, 100, 10, 2, "same", 100, 10, "no alvo de RIR: repetir a série"},
+		{"perto da falha", 1, 100, 10, 0, "down", 92, 10, "RIR 0 vs alvo 2: reduzir 8% da carga"},
+		{"abaixo da faixa", 1, 100, 6, 2, "down", 96, 8, "RIR 2 vs alvo 2: reduzir 4% da carga"},
+		{"corte limitado a 15%", 1, 100, 10, -4, "down", 85, 10, "RIR -4 vs alvo 2: reduzir 15% da carga"},
+		{"sobrou margem", 1, 100, 10, 5, "up", 107.5, 10, "RIR 5 vs alvo 2: subir 7.5% da carga"},
+		{"subida limitada a 10%", 2, 100, 10, 8, "up", 110, 10, "RIR 8 vs alvo 2: subir 10.0% da carga"},
+		{"subida mínima de 0.5kg", 1, 4, 10, 4, "up", 4.5, 10, "RIR 4 vs alvo 2: subir 5.0% da carga"},
+		{"+1 rep", 1, 100, 10, 3, "same", 100, 11, "RIR 3 vs alvo 2: manter carga, buscar +1 rep"},
+		{"+1 rep no topo da faixa", 1, 100, 12, 3, "same", 100, 12, "RIR 3 vs alvo 2: manter carga, buscar +1 rep"},
+		{"-1 rep", 2, 100, 10, 1, "same", 100, 9, "RIR 1 vs alvo 2: manter carga, 1 rep a menos"},
+		{"última série", 3, 100, 10, 2, "done", 0, 0, "3/3 séries concluídas"},
+	}
+	for _, c := range cases {
+		ns := handlers.ComputeNextSet(i18n.PT, plan, c.pos, c.weight, c.reps, c.rir)
+		if ns.Action != c.action || ns.WeightKg != c.wantWeight || ns.Reps != c.wantReps {
+			t.Errorf("%s: got %s %.1fkg x%d, want %s %.1fkg x%d", c.name, ns.Action, ns.WeightKg, ns.Reps, c.action, c.wantWeight, c.wantReps)
+		}
+		if ns.SetNumber != c.pos+1 || ns.TargetRIR != 2 {
+			t.Errorf("%s: set_number=%d target_rir=%d", c.name, ns.SetNumber, ns.TargetRIR)
+		}
+		if ns.Rationale != c.rationale {
+			t.Errorf("%s: rationale %q, want %q", c.name, ns.Rationale, c.rationale)
+		}
+	}
+}
+
+func TestNextSetBackoff(t *testing.T) {
+	plan := handlers.SetPlan{Series: 3, RepsLo: 3, RepsHi: 5, TargetRIR: 2,
+		TopSetRPE: fp(8), BackoffPct: fp(10), BackoffSets: ip(3)}
+	cases := []struct {
+		rir        int
+		wantWeight float64
+		rationale  string
+	}{
+		{2, 90, "top set a RPE 8 (alvo 8.0); back-off -10%"},
+		{1, 88, "top set a RPE 9 (alvo 8.0); back-off -10%, ajuste -2.5% pelo RPE"},
+		{0, 85.5, "top set a RPE 10 (alvo 8.0); back-off -10%, ajuste -5.0% pelo RPE"},
+		{4, 94.5, "top set a RPE 6 (alvo 8.0); back-off -10%, ajuste +5.0% pelo RPE"},
+	}
+	for _, c := range cases {
+		ns := handlers.ComputeNextSet(i18n.PT, plan, 1, 100, 5, c.rir)
+		if ns.Action != "backoff" || ns.WeightKg != c.wantWeight || ns.Reps != 5 {
+			t.Errorf("rir %d: got %s %.1fkg x%d, want backoff %.1fkg x5", c.rir, ns.Action, ns.WeightKg, ns.Reps, c.wantWeight)
+		}
+		if ns.Rationale != c.rationale {
+			t.Errorf("rir %d: rationale %q, want %q", c.rir, ns.Rationale, c.rationale)
+		}
+	}
+
+	// top set + 3 back-offs = 4 séries
+	if ns := handlers.ComputeNextSet(i18n.PT, plan, 4, 90, 5, 2); ns.Action != "done" {
+		t.Errorf("pos 4: action %s, want done", ns.Action)
+	}
+	if ns := handlers.ComputeNextSet(i18n.PT, plan, 3, 90, 5, 2); ns.Action != "same" || ns.WeightKg != 90 {
+		t.Errorf("back-off no alvo: got %s %.1fkg", ns.Action, ns.WeightKg)
+	}
+}
+
+func TestNextSetRationaleEnglish(t *testing.T) {
+	plan := handlers.SetPlan{Series: 3, RepsLo: 8, RepsHi: 12, TargetRIR: 2}
+	ns := handlers.ComputeNextSet(i18n.EN, plan, 1, 100, 10, 0)
+	if want := "RIR 0 vs target 2: reduce the load by 8%"; ns.Rationale != want {
+		t.Errorf("rationale %q, want %q", ns.Rationale, want)
+	}
+}
+
+func TestValidateAutoreg(t *testing.T) {
+	cases := []struct {
+		name string
+		it   handlers.SaveTreinoItemReq
+		want string
+	}{
+		{"sem autorregulação", handlers.SaveTreinoItemReq{}, ""},
+		{"rir ok", handlers.SaveTreinoItemReq{TargetRIR: ip(3)}, ""},
+		{"rir fora", handlers.SaveTreinoItemReq{TargetRIR: ip(6)}, "target_rir must be between 0 and 5"},
+		{"top set sem back-off", handlers.SaveTreinoItemReq{TopSetRPE: fp(8)}, "top_set_rpe and backoff_pct must be set together"},
+		{"back-off sem top set", handlers.SaveTreinoItemReq{BackoffPct: fp(10)}, "top_set_rpe and backoff_pct must be set together"},
+		{"rpe fora", handlers.SaveTreinoItemReq{TopSetRPE: fp(5), BackoffPct: fp(10)}, "top_set_rpe must be between 6 and 10"},
+		{"pct fora", handlers.SaveTreinoItemReq{TopSetRPE: fp(8), BackoffPct: fp(40)}, "backoff_pct must be between 1 and 30"},
+		{"sets fora", handlers.SaveTreinoItemReq{TopSetRPE: fp(8), BackoffPct: fp(10), BackoffSets: ip(7)}, "backoff_sets must be between 1 and 6"},
+		{"top set completo", handlers.SaveTreinoItemReq{TopSetRPE: fp(8), BackoffPct: fp(10), BackoffSets: ip(3)}, ""},
+	}
+	for _, c := range cases {
+		if got := handlers.ValidateAutoreg(c.it); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	// mensagens saem em pt pelo middleware de idioma
+	if got := i18n.Translate(i18n.PT, "top_set_rpe and backoff_pct must be set together"); got != "top_set_rpe e backoff_pct devem ser informados juntos" {
+		t.Errorf("Translate = %q", got)
+	}
+	if got := i18n.Translate(i18n.PT, "backoff_pct must be between 1 and 30"); got != "backoff_pct deve estar entre 1 e 30" {
+		t.Errorf("Translate = %q", got)
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"anima/internal/handlers"
+	"anima/internal/i18n"
+)
+
+func TestComputeNutrition(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      handlers.NutritionIn
+		formula string
+		bmr     int
+		tdee    int
+		goal    string
+		kcal    int
+		macros  [3]int // proteína, gordura, carbo
+		notes   int
+	}{
+		{"homem, Mifflin-St Jeor",
+			handlers.NutritionIn{WeightKG: fp(80), HeightCM: fp(180), Age: ip(30), Gender: "male", Activity: "moderado", Goal: "hipertrofia"},
+			"mifflin_st_jeor", 1780, 2759, "hipertrofia", 3030, [3]int{160, 84, 409}, 0},
+		{"mulher em déficit: piso no BMR",
+			handlers.NutritionIn{WeightKG: fp(60), HeightCM: fp(165), Age: ip(25), Gender: "F", Activity: "sedentary", Goal: "emagrecimento"},
+			"mifflin_st_jeor", 1345, 1614, "emagrecimento", 1350, [3]int{132, 37, 120}, 0},
+		{"% gordura: Katch-McArdle, sem altura/idade",
+			handlers.NutritionIn{WeightKG: fp(90), BodyfatPct: fp(20), Activity: "ativo", Goal: "Força"},
+			"katch_mcardle", 1925, 3321, "forca", 3490, [3]int{162, 97, 492}, 0},
+		{"sem sexo nem atividade, objetivo desconhecido",
+			handlers.NutritionIn{WeightKG: fp(70), HeightCM: fp(170), Age: ip(40), Goal: "bulking"},
+			"mifflin_st_jeor", 1485, 2042, "manutencao", 2040, [3]int{112, 57, 271}, 2},
+	}
+	for _, c := range cases {
+		out, missing := handlers.ComputeNutrition(i18n.PT, c.in)
+		if out == nil {
+			t.Errorf("%s: missing %v", c.name, missing)
+			continue
+		}
+		got := [3]int{out.ProteinG, out.FatG, out.CarbsG}
+		if out.BMRFormula != c.formula || out.BMR != c.bmr || out.TDEE != c.tdee || out.Goal != c.goal ||
+			out.Calories != c.kcal || got != c.macros || len(out.Notes) != c.notes {
+			t.Errorf("%s: got %s bmr=%d tdee=%d %s kcal=%d macros=%v notes=%v; want %s %d %d %s %d %v %d",
+				c.name, out.BMRFormula, out.BMR, out.TDEE, out.Goal, out.Calories, got, out.Notes,
+				c.formula, c.bmr, c.tdee, c.goal, c.kcal, c.macros, c.notes)
+		}
+		if out.WeightSource != "profile" || out.Adjustment != nil {
+			t.Errorf("%s: source %s adjustment %+v", c.name, out.WeightSource, out.Adjustment)
+		}
+	}
+}
+
+func TestComputeNutritionMissing(t *testing.T) {
+	cases := []struct {
+		in   handlers.NutritionIn
+		want []string
+	}{
+		{handlers.NutritionIn{}, []string{"weight_kg", "height_cm", "birth_date"}},
+		{handlers.NutritionIn{WeightKG: fp(80), Age: ip(30)}, []string{"height_cm"}},
+		{handlers.NutritionIn{WeightKG: fp(80), HeightCM: fp(180)}, []string{"birth_date"}},
+		{handlers.NutritionIn{BodyfatPct: fp(15)}, []string{"weight_kg"}},
+	}
+	for _, c := range cases {
+		out, missing := handlers.ComputeNutrition(i18n.PT, c.in)
+		if out != nil || !reflect.DeepEqual(missing, c.want) {
+			t.Errorf("%+v: missing %v, want %v", c.in, missing, c.want)
+		}
+	}
+}
+
+func TestComputeNutritionTrendAdjust(t *testing.T) {
+	// hipertrofia a 80 kg: esperado +0.20 kg/semana
+	cases := []struct {
+		name  string
+		rate  float64
+		delta int // 0 = sem ajuste
+		kcal  int
+	}{
+		{"peso parado", 0, 220, 3250},
+		{"perto do esperado", 0.19, 0, 3030},
+		{"perdendo peso: ajuste limitado", -0.6, 500, 3530},
+	}
+	for _, c := range cases {
+		rate := c.rate
+		out, _ := handlers.ComputeNutrition(i18n.PT, handlers.NutritionIn{
+			WeightKG: fp(80), HeightCM: fp(180), Age: ip(30), Gender: "male", Activity: "moderate",
+			Goal: "hipertrofia", Trend: &handlers.WeightTrend{WeeklyRateKG: &rate},
+		})
+		delta := 0
+		if out.Adjustment != nil {
+			delta = out.Adjustment.DeltaKcal
+		}
+		if delta != c.delta || out.Calories != c.kcal || out.WeightSource != "trend" {
+			t.Errorf("%s: delta %d kcal %d source %s, want %d %d", c.name, delta, out.Calories, out.WeightSource, c.delta, c.kcal)
+		}
+	}
+
+	rate := 0.0
+	out, _ := handlers.ComputeNutrition(i18n.EN, handlers.NutritionIn{
+		WeightKG: fp(80), HeightCM: fp(180), Age: ip(30), Goal: "hipertrofia",
+		Trend: &handlers.WeightTrend{WeeklyRateKG: &rate},
+	})
+	if want := "trend weight changing +0.00 kg/week vs +0.20 expected"; out.Adjustment == nil || out.Adjustment.Reason != want {
+		t.Errorf("reason %+v, want %q", out.Adjustment, want)
+	}
+	want := []string{"sex not set: BMR from the average of the formulas", "activity_level missing/unknown: factor 1.375 (light)"}
+	if !reflect.DeepEqual(out.Notes, want) {
+		t.Errorf("notes %v, want %v", out.Notes, want)
+	}
+}
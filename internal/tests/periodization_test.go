@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"anima/internal/handlers"
+)
+
+func TestPeriodizeLinearWeeks(t *testing.T) {
+	weeks, err := handlers.Periodize("linear", 7, 3, 4, "hipertrofia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		phase     string
+		intensity float64
+		volume    float64
+		reps      string
+	}{
+		{"linear", 0.65, 1.10, "10-12"},
+		{"linear", 0.68, 1.04, "10-12"},
+		{"linear", 0.72, 0.98, "8-10"},
+		{"deload", 0.65, 0.50, "8-10"}, // 90% da semana 3, reps mantidas
+		{"linear", 0.75, 0.92, "6-8"},
+		{"linear", 0.79, 0.86, "6-8"},
+		{"linear", 0.82, 0.80, "4-6"},
+	}
+	if len(weeks) != len(want) {
+		t.Fatalf("got %d weeks, want %d", len(weeks), len(want))
+	}
+	for i, w := range want {
+		if len(weeks[i]) != 3 {
+			t.Fatalf("week %d: %d sessions, want 3", i+1, len(weeks[i]))
+		}
+		for _, p := range weeks[i] {
+			if p.Week != i+1 || p.Phase != w.phase || p.IntensityPct != w.intensity ||
+				p.VolumeFactor != w.volume || p.RepTarget != w.reps || p.Deload != (w.phase == "deload") {
+				t.Errorf("week %d day %d: got %+v, want %+v", i+1, p.Day, p, w)
+			}
+		}
+	}
+}
+
+func TestPeriodizeDUPRotation(t *testing.T) {
+	weeks, err := handlers.Periodize("dup", 7, 3, 4, "hipertrofia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases := []string{"heavy", "moderate", "light"}
+	for _, w := range weeks {
+		for i, p := range w {
+			if p.Deload {
+				continue
+			}
+			if p.Phase != phases[i] {
+				t.Errorf("week %d day %d: phase %s, want %s", p.Week, p.Day, p.Phase, phases[i])
+			}
+			if p.IntensityPct > 0.87 { // teto: topo da faixa + 5 p.p.
+				t.Errorf("week %d day %d: intensity %.2f above cap", p.Week, p.Day, p.IntensityPct)
+			}
+		}
+		if !w[0].Deload && !(w[0].IntensityPct > w[1].IntensityPct && w[1].IntensityPct > w[2].IntensityPct) {
+			t.Errorf("week %d: heavy/moderate/light not ordered: %.2f %.2f %.2f", w[0].Week,
+				w[0].IntensityPct, w[1].IntensityPct, w[2].IntensityPct)
+		}
+	}
+	if got := weeks[6][0].IntensityPct; got != 0.87 {
+		t.Errorf("week 7 heavy: %.2f, want capped 0.87", got)
+	}
+	// deload mantém a faixa de reps de cada dia da semana anterior
+	for d := range weeks[3] {
+		if weeks[3][d].RepTarget != weeks[2][d].RepTarget || weeks[3][d].VolumeFactor != 0.5 {
+			t.Errorf("deload day %d: %+v vs %+v", d+1, weeks[3][d], weeks[2][d])
+		}
+	}
+}
+
+func TestPeriodizeBlockPhases(t *testing.T) {
+	weeks, err := handlers.Periodize("block", 7, 2, 4, "forca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"accumulation", "accumulation", "intensification", "deload",
+		"intensification", "intensification", "realization"}
+	for i, w := range weeks {
+		if w[0].Phase != want[i] {
+			t.Errorf("week %d: phase %s, want %s", i+1, w[0].Phase, want[i])
+		}
+	}
+	if first, last := weeks[0][0].IntensityPct, weeks[6][0].IntensityPct; first != 0.75 || last != 0.92 {
+		t.Errorf("força: intensity %.2f..%.2f, want 0.75..0.92", first, last)
+	}
+}
+
+func TestPeriodizeErrors(t *testing.T) {
+	cases := []struct {
+		weeks, days, every int
+	}{
+		{0, 3, 4},
+		{4, 0, 4},
+		{4, 3, 1}, // toda semana seria deload
+	}
+	for _, c := range cases {
+		if _, err := handlers.Periodize("linear", c.weeks, c.days, c.every, "hipertrofia"); err == nil {
+			t.Errorf("Periodize(%d weeks, %d days, deload %d): expected error", c.weeks, c.days, c.every)
+		}
+	}
+	// sem deload: só semanas de carga
+	weeks, err := handlers.Periodize("linear", 4, 2, 0, "hipertrofia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range weeks {
+		if w[0].Deload {
+			t.Errorf("week %d: unexpected deload", w[0].Week)
+		}
+	}
+}
+
+func TestDayOffsets(t *testing.T) {
+	cases := map[int][]int{
+		1: {0},
+		2: {0, 3},
+		3: {0, 2, 4},
+		4: {0, 1, 3, 4},
+		6: {0, 1, 2, 3, 4, 5},
+		9: {0, 1, 2, 3, 4, 5, 6},
+	}
+	for days, want := range cases {
+		if got := handlers.DayOffsets(days); !reflect.DeepEqual(got, want) {
+			t.Errorf("DayOffsets(%d) = %v, want %v", days, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"anima/internal/handlers"
+	"anima/internal/i18n"
+)
+
+// semanas (segundas) terminando em 2026-10-19
+func trendPoints(e1rm []float64, reps, sets int) []handlers.TrendPoint {
+	last := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	out := make([]handlers.TrendPoint, len(e1rm))
+	for i, v := range e1rm {
+		wk := last.AddDate(0, 0, -7*(len(e1rm)-1-i))
+		out[i] = handlers.TrendPoint{Week: wk.Format("2006-01-02"), E1RMKg: v, BestReps: reps, Sets: sets}
+	}
+	return out
+}
+
+func TestClassifyTrend(t *testing.T) {
+	now := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name      string
+		e1rm      []float64
+		status    string
+		slope     float64
+		best      float64
+		sincePR   int
+		avgSets   float64
+		setsPerWk int
+	}{
+		{"sem pontos", nil, "insufficient_data", 0, 0, 0, 0, 0},
+		{"duas semanas", []float64{100, 102}, "insufficient_data", 0, 102, 0, 12, 12},
+		{"progredindo", []float64{100, 101, 102, 103}, "progressing", 0.99, 103, 0, 12, 12},
+		{"inclinação fraca", []float64{100, 100.2, 100, 100.2}, "stalled", 0.04, 100.2, 2, 12, 12}, // PR = primeira ocorrência do máximo
+		{"sem PR há 4 semanas", []float64{100, 115, 105, 107, 109, 111}, "stalled", 1.03, 115, 4, 12, 12},
+		{"regredindo", []float64{110, 108, 106, 104}, "regressing", -1.87, 110, 3, 12, 12},
+	}
+	for _, c := range cases {
+		tr := &handlers.ExerciseTrend{Points: trendPoints(c.e1rm, 8, c.setsPerWk)}
+		handlers.ClassifyTrend(tr, now)
+		if tr.Status != c.status || tr.SlopePctPerWeek != c.slope || tr.BestE1RMKg != c.best ||
+			tr.WeeksSincePR != c.sincePR || tr.AvgWeeklySets != c.avgSets {
+			t.Errorf("%s: got status=%s slope=%.2f best=%.1f sincePR=%d sets=%.1f; want %s %.2f %.1f %d %.1f",
+				c.name, tr.Status, tr.SlopePctPerWeek, tr.BestE1RMKg, tr.WeeksSincePR, tr.AvgWeeklySets,
+				c.status, c.slope, c.best, c.sincePR, c.avgSets)
+		}
+	}
+}
+
+func TestTrendRuleSuggestions(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    string
+		reps      int
+		sets      int
+		repRange  string
+		setsDelta int // 0 = sem sugestão de volume
+	}{
+		{"reps baixas, volume ok", "stalled", 5, 12, "8-12", 0},
+		{"reps altas, pouco volume", "stalled", 12, 8, "4-6", 2},
+		{"reps médias, volume alto", "stalled", 8, 20, "3-5", -6},
+		{"regredindo com volume moderado", "regressing", 8, 12, "3-5", -4},
+		{"regredindo com pouco volume", "regressing", 8, 5, "3-5", -2},
+	}
+	for _, c := range cases {
+		tr := &handlers.ExerciseTrend{Status: c.status, Points: trendPoints([]float64{100, 100, 100}, c.reps, c.sets)}
+		tr.AvgWeeklySets = float64(c.sets)
+		got := handlers.TrendRuleSuggestions(i18n.PT, tr)
+		if got[0].Kind != "rep_range" || got[0].RepRange != c.repRange {
+			t.Errorf("%s: rep suggestion %+v, want %s", c.name, got[0], c.repRange)
+		}
+		delta := 0
+		if len(got) > 1 {
+			delta = got[1].SetsDelta
+		}
+		if delta != c.setsDelta {
+			t.Errorf("%s: sets delta %d, want %d", c.name, delta, c.setsDelta)
+		}
+	}
+
+	tr := &handlers.ExerciseTrend{Status: "stalled", AvgWeeklySets: 8, Points: trendPoints([]float64{100, 100, 100}, 12, 8)}
+	got := handlers.TrendRuleSuggestions(i18n.EN, tr)
+	if want := "best sets at ~12 reps: 4–6 week block at 4-6 with more load"; got[0].Detail != want {
+		t.Errorf("detail %q, want %q", got[0].Detail, want)
+	}
+	if want := "8.0 sets/week: add 2 weekly sets"; got[1].Detail != want {
+		t.Errorf("detail %q, want %q", got[1].Detail, want)
+	}
+}
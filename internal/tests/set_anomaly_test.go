@@ -0,0 +1,44 @@
+package tests
+
+import (
+	"testing"
+
+	"anima/internal/handlers"
+)
+
+func TestSetFlagStateConfirmFlow(t *testing.T) {
+	steps := []struct {
+		name      string
+		suspect   bool
+		confirm   bool
+		changed   bool
+		flagged   bool
+		confirmed bool
+	}{
+		{"600 kg gravado", true, false, true, true, false},
+		{"usuário confirma", true, true, false, false, true},
+		{"edita só as notas", true, false, false, false, true},
+		{"edita dor/descanso de novo", true, false, false, false, true},
+		{"muda a carga", true, false, true, true, false},
+		{"corrige p/ valor normal", false, false, true, false, false},
+	}
+	confirmed := false
+	for _, s := range steps {
+		var flagged bool
+		flagged, confirmed = handlers.SetFlagState(s.suspect, s.confirm, confirmed, s.changed)
+		if flagged != s.flagged || confirmed != s.confirmed {
+			t.Fatalf("%s: flagged %v confirmed %v, want %v %v", s.name, flagged, confirmed, s.flagged, s.confirmed)
+		}
+	}
+}
+
+func TestSetFlagStateConfirmWithEdit(t *testing.T) {
+	// confirm no mesmo PATCH que muda a carga confirma os valores novos
+	if flagged, confirmed := handlers.SetFlagState(true, true, true, true); flagged || !confirmed {
+		t.Errorf("flagged %v confirmed %v, want false true", flagged, confirmed)
+	}
+	// série normal nunca fica marcada
+	if flagged, _ := handlers.SetFlagState(false, false, false, true); flagged {
+		t.Error("clean set flagged")
+	}
+}
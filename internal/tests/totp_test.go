@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"anima/internal/totp"
+)
+
+// Vetores da RFC 6238 (SHA1, segredo "12345678901234567890"), 6 últimos dígitos.
+func TestTOTPRFCVectors(t *testing.T) {
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, want := range cases {
+		got, err := totp.Code(secret, time.Unix(ts, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("t=%d: got %s want %s", ts, got, want)
+		}
+	}
+}
+
+func TestTOTPValidateSkew(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_800_000_000, 0)
+	prev, _ := totp.Code(secret, now.Add(-totp.Period*time.Second))
+	step, ok := totp.Validate(secret, prev, now, 1)
+	if !ok || step != totp.Step(now)-1 {
+		t.Fatalf("previous step should validate with skew 1 (ok=%v step=%d)", ok, step)
+	}
+	if _, ok := totp.Validate(secret, prev, now, 0); ok {
+		t.Fatal("previous step must not validate with skew 0")
+	}
+	if _, ok := totp.Validate(secret, "12345", now, 1); ok {
+		t.Fatal("short code must not validate")
+	}
+
+	uri := totp.URI("Anima", "ana@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Anima:ana@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected uri %q", uri)
+	}
+}
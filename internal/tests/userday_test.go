@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"anima/internal/handlers"
+)
+
+func TestLocalDay(t *testing.T) {
+	cases := []struct {
+		at   string
+		tz   string
+		want string
+	}{
+		{"2026-10-20T00:30:00Z", "America/Sao_Paulo", "2026-10-19"}, // 21:30 em São Paulo
+		{"2026-10-20T03:00:00Z", "America/Sao_Paulo", "2026-10-20"},
+		{"2026-10-19T20:00:00Z", "Asia/Tokyo", "2026-10-20"},
+		{"2026-10-20T00:30:00Z", "", "2026-10-20"},
+		{"2026-10-20T00:30:00Z", "Local", "2026-10-20"},
+		{"2026-10-20T00:30:00Z", "Mars/Olympus", "2026-10-20"},
+	}
+	for _, c := range cases {
+		at, err := time.Parse(time.RFC3339, c.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := handlers.LocalDay(at, c.tz); got != c.want {
+			t.Errorf("LocalDay(%s, %q) = %s, want %s", c.at, c.tz, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package totp: códigos de uso único por tempo (RFC 6238, HMAC-SHA1, 6
+// dígitos, passo de 30s), compatíveis com Google Authenticator, 1Password etc.
+//
+// O segredo circula em base32 sem padding, como nos URIs otpauth://.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 // segundos
+)
+
+var ErrSecret = errors.New("totp: invalid secret")
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret: 160 bits aleatórios em base32.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(secret), " ", ""))
+	key, err := b32.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrSecret
+	}
+	return key, nil
+}
+
+// Step: contador de passos (Unix/Period) do instante t.
+func Step(t time.Time) int64 { return t.Unix() / Period }
+
+// Code: código do instante t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return codeAt(key, Step(t)), nil
+}
+
+// codeAt: HOTP (RFC 4226) do contador step.
+func codeAt(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, v%1_000_000)
+}
+
+// Validate confere code em t com tolerância de skew passos para cada lado.
+// Devolve o passo que casou, para o chamador recusar reuso (passo <= último aceito).
+func Validate(secret, code string, t time.Time, skew int) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != Digits {
+		return 0, false
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	now := Step(t)
+	for d := -int64(skew); d <= int64(skew); d++ {
+		if subtle.ConstantTimeCompare([]byte(codeAt(key, now+d)), []byte(code)) == 1 {
+			return now + d, true
+		}
+	}
+	return 0, false
+}
+
+// URI: otpauth://totp/<issuer>:<account>?secret=...&issuer=... (QR code dos apps).
+func URI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(Period))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
@@ -12,7 +12,10 @@ import (
 	"syscall"
 	"time"
 
+	"anima/internal/ai"
 	"anima/internal/handlers"
+	"anima/internal/jwtkeys"
+	"anima/internal/mail"
 
 	_ "github.com/lib/pq"
 )
@@ -45,7 +48,7 @@ func docsHandler(w http.ResponseWriter, r *http.Request) {
 func withCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-User-ID, X-Admin-Token, X-Request-ID")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-User-ID, X-Request-ID, X-Timezone")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, PUT, OPTIONS")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -72,6 +75,35 @@ func main() {
 
 	// Injeta DB para handlers compatíveis que usam variável interna
 	handlers.SetSessionsDB(db)
+	// Resolve tokens de acesso pessoal nos middlewares de auth
+	handlers.SetAuthDB(db)
+
+	// Notas do coach: heurística ou endpoint de chat (COACH_PROVIDER=chat)
+	coach := ai.ProviderFromEnv()
+	handlers.SetCoachProvider(coach)
+	log.Printf("[anima] coach provider: %s", coach.Name())
+
+	// Primeiro admin (depois, papéis via /api/admin/users/{id}/roles)
+	if email := os.Getenv("BOOTSTRAP_ADMIN_EMAIL"); email != "" {
+		if err := handlers.BootstrapAdmin(context.Background(), db, email); err != nil {
+			log.Fatalf("erro no bootstrap de admin: %v", err)
+		}
+	}
+
+	// E-mails de conta: log, outbox em arquivo ou SMTP (MAIL_DRIVER)
+	mailer := mail.FromEnv()
+	handlers.SetMailer(mailer)
+	log.Printf("[anima] mail driver: %s", mailer.Name())
+
+	// JWT assimétrico: PEMs em JWT_KEYS_DIR (sem ele, HS256 com JWT_SECRET)
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		ks, err := jwtkeys.LoadDir(dir, os.Getenv("JWT_SIGNING_KID"))
+		if err != nil {
+			log.Fatalf("erro carregando chaves JWT: %v", err)
+		}
+		handlers.SetJWTKeys(ks)
+		log.Printf("[anima] jwt: %s kid=%s", ks.SigningKey().Alg, ks.SigningKey().ID)
+	}
 
 	// ===== Mux =====
 	mux := http.NewServeMux()
@@ -80,6 +112,9 @@ func main() {
 	mux.Handle("/openapi.yaml", http.StripPrefix("/", http.FileServer(http.Dir("./docs"))))
 	mux.HandleFunc("/docs", docsHandler)
 
+	// Chaves públicas para validar os access tokens
+	mux.Handle("/.well-known/jwks.json", handlers.JWKS())
+
 	// Health
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -106,22 +141,51 @@ func main() {
 		}
 		handlers.ListExercises(db).ServeHTTP(w, r)
 	})
+	// GET /api/exercises/{id}/substitutes (respeita limitações/dor do usuário)
+	mux.Handle("/api/exercises/", handlers.ExerciseSubstitutes(db))
+
+	// ===== Exercícios personalizados =====
+	// GET/POST /api/me/exercises | PATCH/DELETE /api/me/exercises/{id} | GET /api/me/exercises/{id}/trend
+	mux.Handle("/api/me/exercises", handlers.RequireAuth(handlers.MeExercises(db)))
+	mux.Handle("/api/me/exercises/", handlers.RequireAuth(handlers.MeExercises(db)))
+	mux.Handle("/api/me/plateaus", handlers.RequireAuth(handlers.MePlateaus(db))) // GET
 
 	// ===== Auth =====
-	// POST /api/auth/login
-	mux.HandleFunc("/api/auth/login", handlers.AuthLogin(db))
-	// POST /api/auth/refresh
-	mux.HandleFunc("/api/auth/refresh", handlers.AuthRefresh())
+	// POST /api/auth/login (backoff/bloqueio por conta e IP em login_security.go)
+	mux.Handle("/api/auth/login",
+		handlers.RateLimit(handlers.AtoiEnvInt("RATE_LIMIT_LOGIN", 30))(handlers.AuthLogin(db)))
+	// POST /api/auth/refresh (rotação do refresh token)
+	mux.HandleFunc("/api/auth/refresh", handlers.AuthRefresh(db))
+	// POST /api/auth/logout
+	mux.HandleFunc("/api/auth/logout", handlers.AuthLogout(db))
+	// GET/DELETE /api/me/sessions[/{id}]: dispositivos logados
+	mux.Handle("/api/me/sessions", handlers.RequireAuth(handlers.MeSessions(db)))
+	mux.Handle("/api/me/sessions/", handlers.RequireAuth(handlers.MeSessions(db)))
+	// POST /api/auth/mfa: segunda etapa do login com 2FA (TOTP ou código de recuperação)
+	mux.Handle("/api/auth/mfa",
+		handlers.RateLimit(handlers.AtoiEnvInt("RATE_LIMIT_LOGIN", 30))(handlers.AuthMFA(db)))
+	// GET/DELETE /api/me/mfa | POST /api/me/mfa/{enroll,confirm,recovery-codes}
+	mux.Handle("/api/me/mfa", handlers.RequireAuth(handlers.MeMFA(db)))
+	mux.Handle("/api/me/mfa/", handlers.RequireAuth(handlers.MeMFA(db)))
+	// GET/POST /api/me/tokens | DELETE /api/me/tokens/{id}: tokens de acesso pessoal
+	mux.Handle("/api/me/tokens", handlers.RequireAuth(handlers.MeTokens(db)))
+	mux.Handle("/api/me/tokens/", handlers.RequireAuth(handlers.MeTokens(db)))
+	// Conta: cadastro, verificação de e-mail e reset de senha
+	mux.HandleFunc("/api/auth/register", handlers.AuthRegister(db))
+	mux.HandleFunc("/api/auth/verify-email", handlers.AuthVerifyEmail(db))
+	mux.HandleFunc("/api/auth/verify-email/resend", handlers.AuthResendVerification(db))
+	mux.HandleFunc("/api/auth/password/forgot", handlers.AuthForgotPassword(db))
+	mux.HandleFunc("/api/auth/password/reset", handlers.AuthResetPassword(db))
 
 	// ===== Treinos (coleção) =====
 	// GET /api/treinos (listagem)
-	// POST /api/treinos (por enquanto não implementado nesta branch)
+	// POST /api/treinos (treino manual)
 	mux.Handle("/api/treinos", handlers.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
 			handlers.TreinosCollection(db).ServeHTTP(w, r)
 		case http.MethodPost:
-			http.Error(w, "not implemented (POST /api/treinos)", http.StatusNotImplemented)
+			handlers.SaveTreino(db).ServeHTTP(w, r)
 		default:
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		}
@@ -167,6 +231,11 @@ func main() {
 		handlers.GenerateTreino(db).ServeHTTP(w, r)
 	})))
 
+	// ===== Programas periodizados =====
+	// GET/POST /api/programs | GET/DELETE /api/programs/{id}
+	mux.Handle("/api/programs", handlers.RequireAuth(handlers.Programs(db)))
+	mux.Handle("/api/programs/", handlers.RequireAuth(handlers.Programs(db)))
+
 	// ===== Overload (compat legacy) =====
 	// GET /api/suggestions/next-load
 	mux.HandleFunc("/api/suggestions/next-load", func(w http.ResponseWriter, r *http.Request) {
@@ -184,20 +253,20 @@ func main() {
 	// ===== Planner semanal =====
 	// GET /api/plan/weekly
 	// POST /api/plan/weekly/save
-	mux.HandleFunc("/api/plan/weekly", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/plan/weekly", handlers.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		handlers.PlanWeekly(db).ServeHTTP(w, r)
-	})
-	mux.HandleFunc("/api/plan/weekly/save", func(w http.ResponseWriter, r *http.Request) {
+	})))
+	mux.Handle("/api/plan/weekly/save", handlers.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		handlers.PlanWeeklySave(db).ServeHTTP(w, r)
-	})
+	})))
 
 	// ===== Sets (item) =====
 	// /api/sets/{id}  (PATCH/DELETE)
@@ -229,6 +298,18 @@ func main() {
 	mux.HandleFunc("/api/sessions/", func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 
+		// /api/sessions/prefill (GET) — séries/reps/carga-alvo do dia
+		if path == "/api/sessions/prefill" {
+			handlers.SessionsPrefill(db).ServeHTTP(w, r)
+			return
+		}
+
+		// /api/sessions/{id}/log-text (POST) — séries a partir de texto livre
+		if strings.HasSuffix(path, "/log-text") {
+			handlers.SessionLogText(db).ServeHTTP(w, r)
+			return
+		}
+
 		// /api/sessions/{id}/sets  (GET/POST)
 		if strings.Contains(path, "/sets") {
 			switch r.Method {
@@ -264,17 +345,41 @@ func main() {
 		}
 	})
 
+	// ===== Admin (papel admin no JWT, confirmado no banco) =====
+	admin := func(h http.Handler) http.Handler { return handlers.RequireRole(db, handlers.RoleAdmin, h) }
+	// qualquer /api/admin/* não mapeado também exige admin (404 só depois)
+	mux.Handle("/api/admin/", admin(http.NotFoundHandler()))
+
 	// ===== Admin: Overload =====
 	// Refresh MV
-	mux.HandleFunc("/api/admin/overload/refresh", func(w http.ResponseWriter, r *http.Request) {
-		handlers.OverloadRefreshMV(db).ServeHTTP(w, r)
-	})
+	mux.Handle("/api/admin/overload/refresh", admin(handlers.OverloadRefreshMV(db)))
 	// Logs (list)
-	mux.Handle("/api/admin/overload/logs", handlers.AdminOverloadLogs(db))
+	mux.Handle("/api/admin/overload/logs", admin(handlers.AdminOverloadLogs(db)))
 	// Stats agregadas
-	mux.Handle("/api/admin/overload/stats", handlers.AdminOverloadStats(db))
+	mux.Handle("/api/admin/overload/stats", admin(handlers.AdminOverloadStats(db)))
 	// Export CSV
-	mux.Handle("/api/admin/overload/export.csv", handlers.AdminOverloadExportCSV(db))
+	mux.Handle("/api/admin/overload/export.csv", admin(handlers.AdminOverloadExportCSV(db)))
+
+	// ===== Admin: Catálogo =====
+	// GET /api/admin/exercises/custom (privados) | POST /api/admin/exercises/{id}/promote
+	mux.Handle("/api/admin/exercises/custom", admin(handlers.AdminCustomExercises(db)))
+	mux.Handle("/api/admin/exercises/", admin(handlers.AdminExercisePromote(db)))
+
+	// ===== Admin: Regras do gerador =====
+	// GET /api/admin/rules[?version=N] | POST /api/admin/rules (publica nova versão)
+	mux.Handle("/api/admin/rules", admin(handlers.AdminRules(db)))
+
+	// ===== Admin: Papéis =====
+	// GET/POST /api/admin/users/{id}/roles | DELETE /api/admin/users/{id}/roles/{role}
+	mux.Handle("/api/admin/users/", admin(handlers.AdminUserRoles(db)))
+	// GET /api/admin/roles/audit[?user_id=&limit=]
+	mux.Handle("/api/admin/roles/audit", admin(handlers.AdminRoleAudit(db)))
+
+	// ===== Admin: Segurança de login =====
+	// GET /api/admin/security/events[?event=&user_id=&email=&since=&limit=]
+	mux.Handle("/api/admin/security/events", admin(handlers.AdminSecurityEvents(db)))
+	// POST /api/admin/security/unlock {"email"} | {"ip"}
+	mux.Handle("/api/admin/security/unlock", admin(handlers.AdminUnlockLogin(db)))
 
 	// PATCH /api/sets/batch  (atualização em lote)
 	mux.HandleFunc("/api/sets/batch", handlers.SetsBatch)
@@ -284,15 +389,50 @@ func main() {
 	mux.Handle("/api/me/metrics", handlers.RequireAuth(handlers.MeMetrics(db)))        // GET
 	mux.Handle("/api/me/summary", handlers.RequireAuth(handlers.MeSummaryHandler(db))) // GET
 
+	// ===== Nutrição =====
+	mux.Handle("/api/me/nutrition-targets", handlers.RequireAuth(handlers.MeNutritionTargets(db))) // GET
+
+	// ===== Coach (chat) =====
+	// POST /api/coach/messages | GET /api/coach/threads | GET/DELETE /api/coach/threads/{id}
+	mux.Handle("/api/coach/messages", handlers.RequireAuth(handlers.CoachMessages(db)))
+	mux.Handle("/api/coach/threads", handlers.RequireAuth(handlers.CoachThreads(db)))
+	mux.Handle("/api/coach/threads/", handlers.RequireAuth(handlers.CoachThreads(db)))
+
+	// ===== Medidas corporais =====
+	// GET/POST /api/me/body-metrics | GET /api/me/body-metrics/trend | PATCH/DELETE /api/me/body-metrics/{YYYY-MM-DD}
+	mux.Handle("/api/me/body-metrics", handlers.RequireAuth(handlers.UserMetrics(db)))
+	mux.Handle("/api/me/body-metrics/", handlers.RequireAuth(handlers.UserMetrics(db)))
+
+	// ===== Fadiga & deload =====
+	mux.Handle("/api/me/fatigue", handlers.RequireAuth(handlers.MeFatigue(db))) // GET
+	mux.Handle("/api/me/deload", handlers.RequireAuth(handlers.MeDeload(db)))   // GET/POST/DELETE
+
+	// ===== Prontidão diária =====
+	mux.Handle("/api/me/readiness", handlers.RequireAuth(handlers.MeReadiness(db))) // GET/POST
+
+	// ===== Metas =====
+	// GET/POST /api/me/goals | PATCH/DELETE /api/me/goals/{id}
+	mux.Handle("/api/me/goals", handlers.RequireAuth(handlers.MeGoals(db)))
+	mux.Handle("/api/me/goals/", handlers.RequireAuth(handlers.MeGoals(db)))
+
+	// ===== Lesões & limitações =====
+	// GET/POST /api/me/limitations | PATCH/DELETE /api/me/limitations/{id}
+	mux.Handle("/api/me/limitations", handlers.RequireAuth(handlers.MeLimitations(db)))
+	mux.Handle("/api/me/limitations/", handlers.RequireAuth(handlers.MeLimitations(db)))
+
 	// ===== Server =====
 	srv := &http.Server{
 		Addr: ":" + port,
 		Handler: withCORS(
 			handlers.RequestID(
-				handlers.OptionalAuth( // captura user_id de JWT se presente
-					handlers.JSONSafe( // valida Content-Type e limita body
-						handlers.WrapLogging(
-							handlers.Recover(mux),
+				handlers.OptionalAuth( // captura user_id de JWT ou PAT se presente
+					handlers.EnforceScopes( // PAT: só rotas cobertas pelos escopos
+						handlers.JSONSafe( // valida Content-Type e limita body
+							handlers.WrapLogging(
+								handlers.Recover(
+									handlers.Localize(db, mux), // idioma (?lang, perfil, Accept-Language) + erros traduzidos
+								),
+							),
 						),
 					),
 				),
@@ -14,6 +14,13 @@ import (
 
 func b64(v []byte) string { return base64.RawURLEncoding.EncodeToString(v) }
 
+func envOr(k, def string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return def
+}
+
 func main() {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
@@ -28,9 +35,13 @@ func main() {
 	flag.Parse()
 
 	header := map[string]any{"alg": "HS256", "typ": "JWT"}
+	// iss/aud precisam bater com JWT_ISSUER/JWT_AUDIENCE da API
 	payload := map[string]any{
 		"sub": sub,
+		"iss": envOr("JWT_ISSUER", "anima"),
+		"aud": envOr("JWT_AUDIENCE", "anima-api"),
 		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
 		"exp": time.Now().Add(time.Duration(ttl) * time.Second).Unix(),
 	}
 